@@ -1,44 +1,22 @@
 import type { Printer } from '$lib/Printer';
+import { _apiUrl } from '$lib/Utils';
 
+/**
+ * Progress of the current print returned by /v1/printer/{id}/job
+ * Times are in seconds. file is empty when nothing is printing
+ */
 export interface JobInformation {
-	job: {
-		averagePrintTime: number;
-		estimatedPrintTime: number;
-		filament: {
-			tool0: {
-				length: number;
-				volume: number;
-			};
-		};
-		file: {
-			date: number;
-			display: string;
-			name: string;
-			origin: string;
-			path: string;
-			size: number;
-		};
-		lastPrintTime: number;
-		user: string;
-	};
-	progress: {
-		completion: number;
-		filepos: number;
-		printTime: number;
-		printTimeLeft: number;
-		printTimeLeftOrigin: string;
-	};
-	state: string;
+	file: string;
+	completion: number;
+	printTime: number;
+	printTimeLeft: number;
+	estimatedPrintTime: number;
 }
 
 export const GetPrinterJob = async (printer: Printer) => {
 	let jobInfo: JobInformation;
 	try {
-		const res: Response = await fetch(`${printer.url}/api/job`, {
-			headers: {
-				'X-Api-Key': printer.apiKey
-			}
-		});
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/job`));
 		if (!res.ok) {
 			console.log(`error: ${res}`);
 		}
@@ -48,3 +26,42 @@ export const GetPrinterJob = async (printer: Printer) => {
 	}
 	return jobInfo;
 };
+
+/**
+ * A print job Ymir sent to a printer, from /v1/job, /v1/model/{id}/jobs and /v1/printer/{id}/jobs
+ * state is one of printing, paused, completed, cancelled, failed or unknown
+ */
+export type JobRecord = {
+	_id: string;
+	modelId?: string;
+	modelName?: string;
+	file: string;
+	printerId: string;
+	printerName?: string;
+	user?: string;
+	startTime: string;
+	endTime?: string;
+	state: string;
+	completion: number;
+	plannedFilamentG?: number;
+	filamentUsedG: number;
+};
+
+const getJobs = async (path: string): Promise<JobRecord[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl(path));
+		if (!res.ok) {
+			console.log(`error: ${res.status}`);
+			return [];
+		}
+		const jobs: { [id: string]: JobRecord } = await res.json();
+		return Object.values(jobs).sort((a, b) => b.startTime.localeCompare(a.startTime));
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+export const GetJobs = async () => getJobs('/v1/job');
+export const GetModelJobs = async (modelId: string) => getJobs(`/v1/model/${modelId}/jobs`);
+export const GetPrinterJobs = async (printer: Printer) => getJobs(`/v1/printer/${printer._id}/jobs`);
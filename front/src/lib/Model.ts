@@ -39,6 +39,55 @@ export interface GCodeMetaData {
 	filamentUsedM: string;
 	printerType: string;
 	thumbnail: string;
+	/** the G-code dialect Cura sliced for, e.g. Marlin or Griffin */
+	flavor?: string;
+	/** the print's extents in mm as the slicer reported them */
+	boundingBox?: { minX: number; maxX: number; minY: number; maxY: number; minZ: number; maxZ: number };
+	stats?: GCodeStats;
+	cost?: CostEstimate;
 }
 
+/**
+ * What following the moves of a G-code file tells about it, whatever sliced it. Extents are in mm.
+ */
+export interface GCodeStats {
+	layers: number;
+	layerHeights?: number[];
+	extents: { minX: number; maxX: number; minY: number; maxY: number; minZ: number; maxZ: number; moves: number };
+	extrusionMm: number;
+	filamentG: number;
+	estimatedSeconds: number;
+}
+
+/**
+ * What a print costs, estimated from the slicer metadata with the [cost] rates in the server config
+ */
+export interface CostEstimate {
+	currency: string;
+	filamentG: number;
+	hours: number;
+	filament: number;
+	electricity: number;
+	wear: number;
+	total: number;
+}
+
+/**
+ * /v1/model/{id}/cost: an estimate per print file and the cost of the jobs that printed the model
+ */
+export interface CostSummary {
+	modelId: string;
+	files: { path: string; estimate: CostEstimate }[];
+	jobs: number;
+	printed: CostEstimate;
+}
+
+export const formatCost = (amount: number, currency: string): string => {
+	try {
+		return new Intl.NumberFormat(undefined, { style: 'currency', currency: currency }).format(amount);
+	} catch {
+		return `${amount.toFixed(2)} ${currency}`;
+	}
+};
+
 export default {};
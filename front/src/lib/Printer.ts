@@ -1,5 +1,6 @@
 import { writable } from 'svelte/store';
 import { _apiUrl } from '$lib/Utils';
+import type { JobInformation } from '$lib/Job';
 
 export type Printer = {
 	_id: string;
@@ -7,16 +8,57 @@ export type Printer = {
 	printerName: string;
 	url: string;
 	apiType: string;
+	/** masked when read from the server, leave it masked or blank to keep the stored key */
 	apiKey: string;
+	username?: string;
+	password?: string;
+	hasKey?: boolean;
+	hasPassword?: boolean;
+	devicePath?: string;
+	baudRate?: number;
 	location: PrinterLocation;
 	type: PrinterType;
 	dateAdded: string;
 	tags: string[];
 	autoConnect: boolean;
+	profile?: PrinterProfile;
+	/** a still image url, e.g. mjpg-streamer's ?action=snapshot, fetched through /v1/printer/{id}/webcam/snapshot */
+	snapshotUrl?: string;
+	streamUrl?: string;
+	/** the filament spool loaded in the printer, see $lib/Spool */
+	spoolId?: string;
 };
 
+/**
+ * What a printer can do, seeded from the catalog at /v1/printer/profiles
+ * buildVolume is in mm and temperatures in °C
+ */
+export type PrinterProfile = {
+	_id: string;
+	make: string;
+	model: string;
+	buildVolume: { x: number; y: number; z: number };
+	nozzles: number[];
+	materials: string[];
+	maxHotendTemp: number;
+	maxBedTemp: number;
+	gcodeFlavor: string;
+	binaryGCode: boolean;
+	slicerModels?: string[];
+	aliases?: string[];
+};
+
+/**
+ * A place printers are kept in, nested through parentId, e.g. a site, a room or a shelf.
+ * path is the names from the top location down, e.g. "Building A / Room 2 / Shelf 1"
+ * Printers added before locations existed only have a name.
+ */
 export type PrinterLocation = {
+	_id?: string;
 	name: string;
+	parentId?: string;
+	kind?: string;
+	path?: string;
 };
 
 export type PrinterType = {
@@ -25,69 +67,90 @@ export type PrinterType = {
 	Version: string;
 };
 
+export type Temperature = {
+	actual: number;
+	target: number;
+};
+
+/**
+ * Backend independent printer status returned by /v1/printer/{id}/state
+ * state is one of offline, idle, printing, paused, busy or error
+ */
 export type PrinterStatus = {
-	sd?: {
-		ready?: boolean;
-	};
-	state?: {
-		error?: string;
-		flags?: {
-			cancelling?: boolean;
-			closedOrError?: boolean;
-			error?: boolean;
-			finishing?: boolean;
-			operational?: boolean;
-			paused?: boolean;
-			pausing?: boolean;
-			printing?: boolean;
-			ready?: boolean;
-			resuming?: boolean;
-			sdReady?: boolean;
-		};
-		text?: string;
-	};
-	temperature?: {
-		A?: {
-			actual?: number;
-			offset?: number;
-			target?: number;
-		};
-		P?: {
-			actual?: number;
-			offset?: number;
-			target?: number;
-		};
-		bed?: {
-			actual?: number;
-			offset?: number;
-			target?: number;
-		};
-		tool0?: {
-			actual?: number;
-			offset?: number;
-			target?: number;
-		};
-	};
+	state: string;
+	text: string;
+	temperatures: { [heater: string]: Temperature };
+	job?: JobInformation;
+};
+
+/**
+ * Status of a printer as last seen by the server side poller, returned by /v1/printer/status
+ */
+export type CachedPrinterStatus = PrinterStatus & {
+	id: string;
+	printerName: string;
+	lastSeen: string;
+	updated: string;
+	error?: string;
 };
 
 export const SelectedPrinter = writable<Printer>();
 
+export const GetPrinterStatuses = async (): Promise<{ [id: string]: CachedPrinterStatus }> => {
+	try {
+		const res: Response = await fetch(_apiUrl('/v1/printer/status'));
+		if (!res.ok) {
+			console.log(`error: ${res.status}`);
+			return {};
+		}
+		return await res.json();
+	} catch (error) {
+		console.log(error);
+		return {};
+	}
+};
+
+/**
+ * Event pushed by the server on /v1/events
+ * type is one of printer.state, job.progress, job.started, job.finished, upload.complete or queue.changed
+ */
+export type PrinterEvent = {
+	type: string;
+	printerId: string;
+	time: string;
+	data: any;
+};
+
+/**
+ * Subscribes to server sent printer events, optionally only for the given types.
+ * Returns a function that closes the stream.
+ */
+export const SubscribeEvents = (
+	onEvent: (event: PrinterEvent) => void,
+	types: string[] = []
+): (() => void) => {
+	const query = types.length > 0 ? `?type=${types.join(',')}` : '';
+	const source = new EventSource(_apiUrl(`/v1/events${query}`));
+	const listener = (e: MessageEvent) => onEvent(JSON.parse(e.data));
+	for (const type of [
+		'printer.state',
+		'job.progress',
+		'job.started',
+		'job.finished',
+		'upload.complete',
+		'queue.changed'
+	]) {
+		source.addEventListener(type, listener);
+	}
+	return () => source.close();
+};
+
 export const Connect = async (printer: Printer): Promise<boolean> => {
 	try {
-		const res: Response = await fetch(`${printer.url}/api/printer`, {
-			method: 'POST',
-			body: "{'command': 'connect', 'autoconnect': true}",
-			headers: {
-				'X-Api-Key': printer.apiKey,
-				'content-type': 'application/json'
-			}
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/connect`), {
+			method: 'POST'
 		});
-		if (res.status == 400) {
-			console.log(`400: You sent a request that this server could not understand.`);
-			return false;
-		} else if (res.status == 204) {
-			return true;
-		}
+		return res.status == 204;
 	} catch (error) {
 		console.log(error);
 		return false;
@@ -101,36 +164,28 @@ export const CheckPrinterStatus = async function (
 	let online: string;
 	let err: Error;
 	try {
-		const res: Response = await fetch(`${printer.url}/api/printer`, {
-			headers: {
-				'X-Api-Key': printer.apiKey
-			}
-		});
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/state`));
 		if (!res.ok) {
 			if (res.status == 403) {
 				online = 'FORBIDDEN';
 			} else {
 				online = 'OFFLINE';
 			}
-			if (res.status == 409 && printer.autoConnect) {
-				let connected: boolean;
-				let connectTimeout: number;
-				while (!connected) {
-					console.log('attempting to reconnect');
-					connectTimeout = setTimeout(Connect, 5000);
-				}
-				clearTimeout(connectTimeout);
-			}
 		} else {
 			online = 'ONLINE';
 			printerStatus = await res.json();
+			if (printerStatus.state == 'offline' && printer.autoConnect) {
+				console.log('attempting to reconnect');
+				await Connect(printer);
+			}
 		}
 	} catch (error) {
 		if (error.message === 'Failed to fetch') {
 			online = 'OFFLINE';
 			printerStatus = {
-				state: { text: 'Unknown' },
-				temperature: { bed: { actual: 0 }, tool0: { actual: 1.0 }, A: { actual: 0 } }
+				state: 'offline',
+				text: 'Unknown',
+				temperatures: {}
 			};
 			err = error;
 		}
@@ -138,38 +193,196 @@ export const CheckPrinterStatus = async function (
 	return { online, printerStatus, err };
 };
 
-export const GetPrinterFiles = async (printer: Printer) => {
-	let printerFiles;
+/**
+ * A print file on the printer's storage, from /v1/printer/{id}/files
+ * location is the storage it is on, e.g. local or sdcard
+ */
+export type PrinterFile = {
+	name: string;
+	path: string;
+	location?: string;
+	size: number;
+	date: string;
+};
+
+export const GetPrinterFiles = async (
+	printer: Printer
+): Promise<{ printerFiles: PrinterFile[]; error?: Error }> => {
 	try {
-		const res: Response = await fetch(`${printer.url}/api/files/local/ymir`, {
-			headers: {
-				'X-Api-Key': printer.apiKey
-			}
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/files`));
+		if (!res.ok) {
+			return { printerFiles: [], error: new Error(`could not list files: ${await res.text()}`) };
+		}
+		return { printerFiles: (await res.json()) ?? [] };
+	} catch (err) {
+		return { printerFiles: [], error: new Error(`could not list files: ${err}`) };
+	}
+};
+
+const fileRequest = async (
+	printer: Printer,
+	file: PrinterFile,
+	method: string,
+	action: string = ''
+): Promise<{ error?: Error }> => {
+	const path = encodeURIComponent(file.path);
+	const location = encodeURIComponent(file.location ?? '');
+	try {
+		const res: Response = await fetch(
+			_apiUrl(`/v1/printer/${printer._id}/files/${path}${action}?location=${location}`),
+			{ method: method }
+		);
+		if (!res.ok) {
+			return { error: new Error(await res.text()) };
+		}
+		return {};
+	} catch (err) {
+		return { error: new Error(`${err}`) };
+	}
+};
+
+export const PrintPrinterFile = async (printer: Printer, file: PrinterFile) =>
+	fileRequest(printer, file, 'POST', '/print');
+export const DeletePrinterFile = async (printer: Printer, file: PrinterFile) =>
+	fileRequest(printer, file, 'DELETE');
+
+/**
+ * Print files waiting for a printer, from /v1/printer/{id}/queue
+ * The next item starts once the printer is idle and bedClear has been confirmed
+ */
+export type QueueItem = {
+	_id: string;
+	file: string;
+	user?: string;
+	dateAdded: string;
+};
+
+export type PrinterQueue = {
+	printerId: string;
+	items: QueueItem[];
+	bedClear: boolean;
+	lastError?: string;
+};
+
+const queueRequest = async (
+	printer: Printer,
+	path: string,
+	method: string,
+	body?: object
+): Promise<{ queue?: PrinterQueue; error?: Error }> => {
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/queue${path}`), {
+			method: method,
+			body: body ? JSON.stringify(body) : undefined,
+			headers: { 'content-type': 'application/json' }
 		});
 		if (!res.ok) {
-			console.log(`error: ${res}`);
+			return { error: new Error(`queue request failed: ${await res.text()}`) };
 		}
-		printerFiles = await res.json();
-		//console.log(printerFiles)
+		return { queue: await res.json() };
+	} catch (err) {
+		return { error: new Error(`queue request failed: ${err}`) };
+	}
+};
+
+export const GetQueue = async (printer: Printer) => queueRequest(printer, '', 'GET');
+export const AddToQueue = async (printer: Printer, filePath: string, override: boolean = false) =>
+	queueRequest(printer, '', 'POST', { file: filePath, override: override });
+export const ReorderQueue = async (printer: Printer, itemIds: string[]) =>
+	queueRequest(printer, '', 'PUT', { items: itemIds });
+export const RemoveFromQueue = async (printer: Printer, itemId: string) =>
+	queueRequest(printer, `/${itemId}`, 'DELETE');
+export const ConfirmBedClear = async (printer: Printer) =>
+	queueRequest(printer, '/bedclear', 'POST');
+
+/**
+ * What POST /v1/printer/test learned about an unsaved printer
+ */
+export type PrinterTestResult = {
+	server?: string;
+	serverVersion?: string;
+	firmware?: string;
+	firmwareVersion?: string;
+	make?: string;
+	model?: string;
+	type: PrinterType;
+};
+
+export const TestPrinter = async (
+	printer: Partial<Printer>
+): Promise<{ result?: PrinterTestResult; error?: Error }> => {
+	try {
+		const res: Response = await fetch(_apiUrl('/v1/printer/test'), {
+			method: 'POST',
+			body: JSON.stringify(printer),
+			headers: { 'content-type': 'application/json' }
+		});
+		if (!res.ok) {
+			return { error: new Error(`${res.status}: ${await res.text()}`) };
+		}
+		return { result: await res.json() };
+	} catch (err) {
+		return { error: new Error(`printer test failed: ${err}`) };
+	}
+};
+
+export const GetProfiles = async (): Promise<PrinterProfile[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl('/v1/printer/profiles'));
+		if (!res.ok) {
+			return [];
+		}
+		return await res.json();
 	} catch (err) {
 		console.log(err);
+		return [];
 	}
-	return { printerFiles };
 };
 
-export const UploadAndPrintFile = async (filePath: string, printer: Printer, print: boolean) => {
-	let resBody;
+/**
+ * Result of checking a print file against the printer's profile before it is sent
+ * Issues with severity error stop the upload unless it is retried with override
+ */
+export type PreflightIssue = {
+	check: string;
+	severity: 'warning' | 'error';
+	message: string;
+};
+
+export type PreflightReport = {
+	file: string;
+	issues: PreflightIssue[];
+	overridden?: boolean;
+};
+
+export const UploadAndPrintFile = async (
+	filePath: string,
+	printer: Printer,
+	print: boolean,
+	override: boolean = false
+) => {
+	let resBody: PreflightReport | undefined;
+	let preflight: PreflightReport | undefined;
 	let error: Error;
 	try {
 		const res: Response = await fetch(
-			_apiUrl('/v1/model/file/printer?file=').concat(filePath, '&print=', print.toString()),
+			_apiUrl('/v1/model/file/printer?file=').concat(
+				filePath,
+				'&print=',
+				print.toString(),
+				'&override=',
+				override.toString()
+			),
 			{
 				method: 'POST',
 				body: JSON.stringify(printer),
 				headers: { 'content-type': 'application/json' }
 			}
 		);
-		if (!res.ok) {
+		if (res.status == 409) {
+			preflight = await res.json();
+			error = new Error('the file does not look compatible with this printer');
+		} else if (!res.ok) {
 			error = await res.json();
 			error = new Error(`error printing file. Response is: ${error}`);
 		} else {
@@ -178,5 +391,202 @@ export const UploadAndPrintFile = async (filePath: string, printer: Printer, pri
 	} catch (err) {
 		error = new Error(`error printing file.  response is: ${err}`);
 	}
-	return { resBody, error };
+	return { resBody, preflight, error };
+};
+
+/**
+ * Body of POST /v1/printer/{id}/control. Only the fields the command uses are read,
+ * see ControlCommand in pkg/api/printer/types/control.go
+ */
+export type ControlCommand = {
+	command: 'pause' | 'resume' | 'cancel' | 'temperature' | 'home' | 'jog' | 'fan' | 'gcode';
+	heater?: string;
+	target?: number;
+	axes?: string[];
+	x?: number;
+	y?: number;
+	z?: number;
+	feedrate?: number;
+	speed?: number;
+	commands?: string[];
+};
+
+/**
+ * A control command as recorded in the printer's audit trail. result is 'ok' or the error
+ */
+export type AuditEntry = {
+	_id: string;
+	printerId: string;
+	printerName?: string;
+	time: string;
+	user?: string;
+	command: ControlCommand;
+	gcode?: string[];
+	result: string;
+};
+
+export const SendControl = async (
+	printer: Printer,
+	command: ControlCommand
+): Promise<{ entry?: AuditEntry; error?: Error }> => {
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/control`), {
+			method: 'POST',
+			body: JSON.stringify(command),
+			headers: { 'content-type': 'application/json' }
+		});
+		if (!res.ok) {
+			return { error: new Error(await res.text()) };
+		}
+		return { entry: await res.json() };
+	} catch (err) {
+		return { error: new Error(`control command failed: ${err}`) };
+	}
+};
+
+export const GetAudit = async (printer: Printer): Promise<AuditEntry[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/control/audit`));
+		if (!res.ok) {
+			console.log(`error: ${await res.text()}`);
+			return [];
+		}
+		return await res.json();
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+/**
+ * What a printer has printed, from /v1/printer/{id}/usage
+ */
+export type PrinterUsage = {
+	printHours: number;
+	filamentM: number;
+	jobs: number;
+};
+
+/**
+ * A maintenance task with how far the printer has come since it was last done, from /v1/printer/{id}/maintenance.
+ * Intervals left out don't apply
+ */
+export type MaintenanceTask = {
+	_id?: string;
+	printerId?: string;
+	name: string;
+	description?: string;
+	intervalHours?: number;
+	intervalFilamentM?: number;
+	intervalJobs?: number;
+	intervalDays?: number;
+	intervalMonths?: number;
+	history?: { time: string; usage: PrinterUsage; user?: string; notes?: string }[];
+	since?: PrinterUsage;
+	dueDate?: string;
+	overdue?: boolean;
+	reasons?: string[];
+};
+
+export const GetUsage = async (printer: Printer): Promise<PrinterUsage | undefined> => {
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/usage`));
+		if (res.ok) {
+			return await res.json();
+		}
+		console.log(`error: ${await res.text()}`);
+	} catch (err) {
+		console.log(err);
+	}
+};
+
+export const GetMaintenance = async (printer: Printer): Promise<MaintenanceTask[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer/${printer._id}/maintenance`));
+		if (!res.ok) {
+			console.log(`error: ${await res.text()}`);
+			return [];
+		}
+		return await res.json();
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+const maintenanceRequest = async (path: string, method: string, body?: object): Promise<Error | undefined> => {
+	try {
+		const res: Response = await fetch(_apiUrl(path), {
+			method: method,
+			body: body ? JSON.stringify(body) : undefined,
+			headers: { 'content-type': 'application/json' }
+		});
+		if (!res.ok) {
+			return new Error(await res.text());
+		}
+	} catch (err) {
+		return new Error(`maintenance request failed: ${err}`);
+	}
 };
+
+export const CreateMaintenanceTask = async (printer: Printer, task: MaintenanceTask) =>
+	maintenanceRequest(`/v1/printer/${printer._id}/maintenance`, 'POST', task);
+export const DeleteMaintenanceTask = async (printer: Printer, task: MaintenanceTask) =>
+	maintenanceRequest(`/v1/printer/${printer._id}/maintenance/${task._id}`, 'DELETE');
+export const CompleteMaintenanceTask = async (printer: Printer, task: MaintenanceTask, notes: string) =>
+	maintenanceRequest(`/v1/printer/${printer._id}/maintenance/${task._id}/done`, 'POST', { notes: notes });
+
+/**
+ * GetPrinters lists the printers in a location, including the locations inside it, and with a tag.
+ * location is a location id or name, empty values match every printer.
+ */
+export const GetPrinters = async (location: string = '', tag: string = ''): Promise<Printer[]> => {
+	const query = new URLSearchParams({ location: location, tag: tag });
+	try {
+		const res: Response = await fetch(_apiUrl(`/v1/printer?${query}`));
+		if (!res.ok) {
+			console.log(`error: ${await res.text()}`);
+			return [];
+		}
+		return Object.values(await res.json());
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+export const GetLocations = async (): Promise<PrinterLocation[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl('/v1/printer/locations'));
+		if (!res.ok) {
+			console.log(`error: ${await res.text()}`);
+			return [];
+		}
+		return await res.json();
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+const locationRequest = async (path: string, method: string, body?: object): Promise<Error | undefined> => {
+	try {
+		const res: Response = await fetch(_apiUrl(path), {
+			method: method,
+			body: body ? JSON.stringify(body) : undefined,
+			headers: { 'content-type': 'application/json' }
+		});
+		if (!res.ok) {
+			return new Error(await res.text());
+		}
+	} catch (err) {
+		return new Error(`location request failed: ${err}`);
+	}
+};
+
+export const CreateLocation = async (location: PrinterLocation) => locationRequest('/v1/printer/locations', 'POST', location);
+export const DeleteLocation = async (location: PrinterLocation) =>
+	locationRequest(`/v1/printer/locations/${location._id}`, 'DELETE');
+/** PlacePrinter puts the printer in the location with locationId, an empty id takes it out of its location */
+export const PlacePrinter = async (printer: Printer, locationId: string) =>
+	locationRequest(`/v1/printer/${printer._id}/location`, 'PUT', { locationId: locationId });
@@ -0,0 +1,58 @@
+import type { Printer } from '$lib/Printer';
+import { _apiUrl } from '$lib/Utils';
+
+/**
+ * A filament spool from /v1/spool. remainingWeightG goes down as jobs finish on the printer it is loaded in
+ */
+export type Spool = {
+	_id?: string;
+	material: string;
+	color?: string;
+	brand?: string;
+	diameter: number;
+	initialWeightG: number;
+	remainingWeightG: number;
+	cost?: number;
+	location?: string;
+	dateAdded?: string;
+};
+
+export const GetSpools = async (): Promise<Spool[]> => {
+	try {
+		const res: Response = await fetch(_apiUrl('/v1/spool'));
+		if (!res.ok) {
+			console.log(`error: ${res.status}`);
+			return [];
+		}
+		const spools: { [id: string]: Spool } = await res.json();
+		return Object.values(spools).sort((a, b) => a.material.localeCompare(b.material));
+	} catch (err) {
+		console.log(err);
+		return [];
+	}
+};
+
+const spoolRequest = async (path: string, method: string, body?: object): Promise<Error | undefined> => {
+	try {
+		const res: Response = await fetch(_apiUrl(path), {
+			method: method,
+			body: body ? JSON.stringify(body) : undefined,
+			headers: { 'content-type': 'application/json' }
+		});
+		if (!res.ok) {
+			return new Error(await res.text());
+		}
+	} catch (err) {
+		return new Error(`spool request failed: ${err}`);
+	}
+};
+
+export const CreateSpool = async (spool: Spool) => spoolRequest('/v1/spool', 'POST', spool);
+export const UpdateSpool = async (spool: Spool) => spoolRequest(`/v1/spool/${spool._id}`, 'PUT', spool);
+export const DeleteSpool = async (spool: Spool) => spoolRequest(`/v1/spool/${spool._id}`, 'DELETE');
+
+/**
+ * Loads the spool into the printer, an empty spoolId unloads it
+ */
+export const AssignSpool = async (printer: Printer, spoolId: string) =>
+	spoolRequest(`/v1/printer/${printer._id}/spool`, 'PUT', { spoolId: spoolId });
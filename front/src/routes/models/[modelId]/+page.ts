@@ -1,6 +1,6 @@
 /** @type {import('./$types').PageLoad} */
 import { _apiUrl } from '$lib/Utils';
-import type { Model, GCodeMetaData, ModelFileType } from '$lib/Model';
+import type { Model, GCodeMetaData, ModelFileType, CostSummary } from '$lib/Model';
 
 export const load = async ({ fetch, params }) => {
 	/**
@@ -26,6 +26,15 @@ export const load = async ({ fetch, params }) => {
 		metaData = await res.json();
 	}
 
+	/**
+	 * Fetch what the print files cost and what printing the model has cost so far
+	 */
+	let cost: CostSummary;
+	res = await fetch(_apiUrl(`/v1/model/${params.modelId}/cost`));
+	if (res.ok) {
+		cost = await res.json();
+	}
+
 	/**
 	 * Fetch STL thumbnails as Base64 strings and attach to modelFile
 	 */
@@ -39,7 +48,7 @@ export const load = async ({ fetch, params }) => {
 		}
 	}
 	//console.log(metaData);
-	return { model, metaData };
+	return { model, metaData, cost };
 };
 
 export const _getSTLThumbnail = async (
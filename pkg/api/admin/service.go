@@ -42,8 +42,15 @@ func (as AdminService) ListModels() (models map[string]model.Model, err error) {
 	return as.modelStore.List()
 }
 
+/*
+ListPrinters returns every printer with its secrets redacted
+*/
 func (as AdminService) ListPrinters() (printers map[string]printer.Printer, err error) {
-	return as.printerStore.List()
+	printers, err = as.printerStore.List()
+	for id, p := range printers {
+		printers[id] = p.Redacted()
+	}
+	return
 }
 
 func (as AdminService) TruncateModels() error {
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api"
+	bus "ymir/pkg/events"
+)
+
+const (
+	_PRINTER = "printer"
+	_TYPE    = "type"
+
+	// _HEARTBEAT keeps proxies from closing idle streams
+	_HEARTBEAT = 15 * time.Second
+	// _RETRY tells the browser how long to wait before reconnecting, in milliseconds
+	_RETRY = 5000
+)
+
+type EventsHandler struct {
+	api.Handler
+	closing chan struct{}
+	once    *sync.Once
+}
+
+func NewEventsHandler() api.HandlerIFace {
+	eh := EventsHandler{
+		Handler: api.Handler{
+			Prefix:  "/events",
+			Service: NewEventsService(),
+		},
+		closing: make(chan struct{}),
+		once:    &sync.Once{},
+	}
+
+	eh.Routes = []api.Route{
+		{
+			"streamEvents",
+			http.MethodGet,
+			"/",
+			false,
+			eh.stream,
+		},
+	}
+	return eh
+}
+
+func (eh EventsHandler) GetRoutes() []api.Route {
+	return eh.Routes
+}
+
+func (eh EventsHandler) GetService() api.Service {
+	return eh.Service
+}
+
+func (eh EventsHandler) GetPrefix() string {
+	return eh.Prefix
+}
+
+/*
+Close ends the open streams, they would keep the server from shutting down. Streams opened later end straight away.
+*/
+func (eh EventsHandler) Close() {
+	eh.once.Do(func() {
+		close(eh.closing)
+	})
+}
+
+/*
+filter holds the optional ?printer= and ?type= query params. Both may be repeated or comma separated.
+*/
+type filter struct {
+	printers map[string]bool
+	types    map[string]bool
+}
+
+func newFilter(r *http.Request) filter {
+	f := filter{printers: map[string]bool{}, types: map[string]bool{}}
+	q := r.URL.Query()
+	for _, v := range q[_PRINTER] {
+		for _, id := range strings.Split(v, ",") {
+			if id != "" {
+				f.printers[id] = true
+			}
+		}
+	}
+	for _, v := range q[_TYPE] {
+		for _, t := range strings.Split(v, ",") {
+			if t != "" {
+				f.types[t] = true
+			}
+		}
+	}
+	return f
+}
+
+func (f filter) match(e bus.Event) bool {
+	if len(f.printers) > 0 && !f.printers[e.PrinterId] {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[e.Type] {
+		return false
+	}
+	return true
+}
+
+/*
+GET /events?printer={id}&type={type} (200, 500) -- streams printer state changes, job progress, jobs starting and finishing, upload completion and queue changes as Server-Sent Events
+*/
+func (eh EventsHandler) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	f := newFilter(r)
+	events, unsubscribe := eh.Service.(EventsServiceIface).Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// tell nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", _RETRY)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(_HEARTBEAT)
+	defer heartbeat.Stop()
+	id := 0
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-eh.closing:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, open := <-events:
+			if !open {
+				return
+			}
+			if !f.match(e) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				log.Errorf("could not marshal %v event: %v", e.Type, err)
+				continue
+			}
+			id++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api"
+	bus "ymir/pkg/events"
+)
+
+/*
+MockEventsService subscribes to its own bus so tests don't see each other's events
+*/
+type MockEventsService struct {
+	EventsService
+	bus *bus.Bus
+}
+
+func (m *MockEventsService) Subscribe() (<-chan bus.Event, func()) {
+	return m.bus.Subscribe(bus.DEFAULT_BUFFER)
+}
+
+type EventsHandlerTestSuite struct {
+	suite.Suite
+	bus     *bus.Bus
+	handler *EventsHandler
+	server  *httptest.Server
+}
+
+func (suite *EventsHandlerTestSuite) SetupTest() {
+	suite.bus = bus.NewBus()
+	suite.handler = &EventsHandler{
+		Handler: api.Handler{
+			Service: &MockEventsService{bus: suite.bus},
+		},
+		closing: make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.handler.stream))
+}
+
+func (suite *EventsHandlerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+/*
+open connects to the stream and returns a reader positioned after the retry preamble
+*/
+func (suite *EventsHandlerTestSuite) open(query string) (*bufio.Reader, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, suite.server.URL+query, nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	assert.Equal(suite.T(), "retry: 5000", suite.readMessage(r)[0])
+	return r, cancel
+}
+
+func (suite *EventsHandlerTestSuite) readMessage(r *bufio.Reader) []string {
+	lines := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.NoError(suite.T(), err) {
+			return lines
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (suite *EventsHandlerTestSuite) publish(events ...bus.Event) {
+	// wait for the handler to subscribe
+	time.Sleep(50 * time.Millisecond)
+	for _, e := range events {
+		suite.bus.Publish(e)
+	}
+}
+
+func (suite *EventsHandlerTestSuite) TestStream() {
+	r, cancel := suite.open("/")
+	defer cancel()
+	suite.publish(bus.Event{
+		Type:      bus.PRINTER_STATE,
+		PrinterId: "p1",
+		Data:      bus.StateChange{PrinterName: "mk3s", Previous: "idle", State: "printing", Text: "Printing"},
+	})
+
+	msg := suite.readMessage(r)
+	assert.Equal(suite.T(), []string{"id: 1", "event: printer.state"}, msg[:2])
+	e := map[string]interface{}{}
+	assert.NoError(suite.T(), json.Unmarshal([]byte(strings.TrimPrefix(msg[2], "data: ")), &e))
+	assert.Equal(suite.T(), "p1", e["printerId"])
+	assert.Equal(suite.T(), "printing", e["data"].(map[string]interface{})["state"])
+}
+
+func (suite *EventsHandlerTestSuite) TestStream_Filter() {
+	r, cancel := suite.open("/?printer=p2&type=job.progress,upload.complete")
+	defer cancel()
+	suite.publish(
+		bus.Event{Type: bus.JOB_PROGRESS, PrinterId: "p1"},
+		bus.Event{Type: bus.PRINTER_STATE, PrinterId: "p2"},
+		bus.Event{Type: bus.UPLOAD_COMPLETE, PrinterId: "p2", Data: bus.Upload{File: "cube.gcode"}},
+	)
+
+	msg := suite.readMessage(r)
+	assert.Equal(suite.T(), "event: upload.complete", msg[1])
+	assert.Contains(suite.T(), msg[2], "cube.gcode")
+}
+
+func (suite *EventsHandlerTestSuite) TestStream_Shutdown() {
+	r, cancel := suite.open("/")
+	defer cancel()
+	suite.server.Config.RegisterOnShutdown(suite.handler.Close)
+
+	ctx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+	assert.NoError(suite.T(), suite.server.Config.Shutdown(ctx), "open streams don't hold up the shutdown")
+	_, err := r.ReadString('\n')
+	assert.ErrorIs(suite.T(), err, io.EOF)
+}
+
+func TestEventsHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(EventsHandlerTestSuite))
+}
@@ -0,0 +1,33 @@
+package events
+
+import (
+	"ymir/pkg/api"
+	bus "ymir/pkg/events"
+)
+
+type EventsServiceIface interface {
+	api.Service
+	Subscribe() (events <-chan bus.Event, unsubscribe func())
+}
+
+type EventsService struct {
+	EventsServiceIface
+	name string
+}
+
+func NewEventsService() api.Service {
+	return EventsService{
+		name: "Events",
+	}
+}
+
+func (es EventsService) GetName() (name string) {
+	return es.name
+}
+
+/*
+Subscribe subscribes to printer, job, upload and queue events until unsubscribe is called
+*/
+func (es EventsService) Subscribe() (<-chan bus.Event, func()) {
+	return bus.Subscribe(bus.DEFAULT_BUFFER)
+}
@@ -0,0 +1,98 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api"
+	"ymir/pkg/api/job/store"
+)
+
+type JobHandler struct {
+	api.Handler
+}
+
+func NewJobHandler() api.HandlerIFace {
+	jh := JobHandler{
+		Handler: api.Handler{
+			Prefix:  "/job",
+			Service: NewJobService(),
+		},
+	}
+
+	jh.Routes = []api.Route{
+		{
+			"listAllJobs",
+			http.MethodGet,
+			"/",
+			false,
+			jh.listAll,
+		},
+		{
+			"inspectJob",
+			http.MethodGet,
+			"/{id}",
+			false,
+			jh.inspect,
+		},
+	}
+	return jh
+}
+
+func (jh JobHandler) GetRoutes() []api.Route {
+	return jh.Routes
+}
+
+func (jh JobHandler) GetService() api.Service {
+	return jh.Service
+}
+
+func (jh JobHandler) GetPrefix() string {
+	return jh.Prefix
+}
+
+/*
+GET /job (200, 500) -- lists the history of print jobs
+*/
+func (jh JobHandler) listAll(w http.ResponseWriter, r *http.Request) {
+	jobs, err := jh.Service.(JobServiceIface).ListJobs()
+	if err != nil {
+		log.Errorf("list jobs service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+/*
+GET /job/{id} (200, 404, 500) -- gets the job with {id}
+*/
+func (jh JobHandler) inspect(w http.ResponseWriter, r *http.Request) {
+	job, err := jh.Service.(JobServiceIface).GetJob(chi.URLParam(r, "id"))
+	if errors.Is(err, store.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Errorf("inspect job service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(js)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package job
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api/job/store"
+	"ymir/pkg/api/job/types"
+	bus "ymir/pkg/events"
+	driver "ymir/pkg/printer"
+)
+
+const (
+	// COMPLETED_AT is the completion in percent above which a job that ends idle counts as completed rather than cancelled.
+	// Backends stop reporting progress at different points near the end of a print.
+	COMPLETED_AT = 99.0
+)
+
+/*
+Recorder follows the printer events from the poller and keeps the progress and final state of recorded jobs up to date
+*/
+type Recorder struct {
+	store       store.JobStoreIFace
+	lock        sync.Mutex
+	unsubscribe func()
+	done        chan struct{}
+}
+
+func NewRecorder(s store.JobStoreIFace) *Recorder {
+	return &Recorder{store: s}
+}
+
+/*
+RecordStart stores j as printing from now on and publishes JOB_STARTED, so the Recorder follows it from there.
+Jobs still open on the printer were never seen to finish and are closed as unknown.
+*/
+func RecordStart(s store.JobStoreIFace, j types.Job) (types.Job, error) {
+	now := time.Now()
+	open, err := s.ListByPrinter(j.PrinterId)
+	if err != nil {
+		return types.Job{}, err
+	}
+	for _, o := range open {
+		if o.Active() {
+			o.Finish(types.JOB_STATE_UNKNOWN, now)
+			if err := s.Update(o); err != nil {
+				return types.Job{}, err
+			}
+		}
+	}
+
+	j.Id = uuid.New().String()
+	j.StartTime = now
+	j.State = types.JOB_STATE_PRINTING
+	if err := s.Create(j); err != nil {
+		return types.Job{}, err
+	}
+	bus.Publish(bus.Event{Type: bus.JOB_STARTED, PrinterId: j.PrinterId, Time: now, Data: j})
+	return j, nil
+}
+
+/*
+Start subscribes to printer events until Stop is called
+*/
+func (r *Recorder) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.unsubscribe != nil {
+		return
+	}
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	r.unsubscribe = unsubscribe
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		for e := range events {
+			r.handle(e)
+		}
+	}()
+}
+
+func (r *Recorder) Stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.unsubscribe == nil {
+		return
+	}
+	r.unsubscribe()
+	<-r.done
+	r.unsubscribe = nil
+}
+
+func (r *Recorder) handle(e bus.Event) {
+	switch e.Type {
+	case bus.JOB_PROGRESS:
+		progress, ok := e.Data.(*driver.Job)
+		if !ok || progress == nil {
+			return
+		}
+		j, ok := r.activeJob(e.PrinterId)
+		if !ok || j.Completion == progress.Completion {
+			return
+		}
+		j.Completion = progress.Completion
+		r.update(j)
+	case bus.PRINTER_STATE:
+		change, ok := e.Data.(bus.StateChange)
+		if !ok {
+			return
+		}
+		j, ok := r.activeJob(e.PrinterId)
+		if !ok {
+			return
+		}
+		switch driver.State(change.State) {
+		case driver.STATE_PRINTING:
+			j.State = types.JOB_STATE_PRINTING
+		case driver.STATE_PAUSED:
+			j.State = types.JOB_STATE_PAUSED
+		case driver.STATE_IDLE:
+			if j.Completion >= COMPLETED_AT {
+				j.Finish(types.JOB_STATE_COMPLETED, e.Time)
+			} else {
+				j.Finish(types.JOB_STATE_CANCELLED, e.Time)
+			}
+		case driver.STATE_ERROR:
+			j.Finish(types.JOB_STATE_FAILED, e.Time)
+		default:
+			// offline or busy says nothing about the job, the printer may still be printing
+			return
+		}
+		r.update(j)
+		if !j.Active() {
+			bus.Publish(bus.Event{Type: bus.JOB_FINISHED, PrinterId: j.PrinterId, Time: e.Time, Data: j})
+		}
+	}
+}
+
+/*
+activeJob is the latest job on the printer that has not finished
+*/
+func (r *Recorder) activeJob(printerId string) (types.Job, bool) {
+	jobs, err := r.store.ListByPrinter(printerId)
+	if err != nil {
+		log.Errorf("could not list jobs of printer %v: %v", printerId, err)
+		return types.Job{}, false
+	}
+	var active types.Job
+	found := false
+	for _, j := range jobs {
+		if j.Active() && (!found || j.StartTime.After(active.StartTime)) {
+			active = j
+			found = true
+		}
+	}
+	return active, found
+}
+
+func (r *Recorder) update(j types.Job) {
+	if err := r.store.Update(j); err != nil {
+		log.Errorf("could not update job %v: %v", j.Id, err)
+	}
+}
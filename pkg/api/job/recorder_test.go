@@ -0,0 +1,116 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/job/store"
+	"ymir/pkg/api/job/types"
+	bus "ymir/pkg/events"
+	driver "ymir/pkg/printer"
+)
+
+/*
+memStore keeps jobs in memory so recorder tests don't need a db
+*/
+type memStore struct {
+	store.JobStore
+	jobs map[string]types.Job
+}
+
+func (m *memStore) Update(j types.Job) error {
+	m.jobs[j.Id] = j
+	return nil
+}
+
+func (m *memStore) ListByPrinter(printerId string) (map[string]types.Job, error) {
+	jobs := map[string]types.Job{}
+	for id, j := range m.jobs {
+		if j.PrinterId == printerId {
+			jobs[id] = j
+		}
+	}
+	return jobs, nil
+}
+
+type RecorderTestSuite struct {
+	suite.Suite
+	store    *memStore
+	recorder *Recorder
+	start    time.Time
+}
+
+func (suite *RecorderTestSuite) SetupTest() {
+	suite.start = time.Now().Add(-time.Hour)
+	suite.store = &memStore{jobs: map[string]types.Job{
+		"old": {Id: "old", PrinterId: "p1", StartTime: suite.start.Add(-time.Hour), State: types.JOB_STATE_COMPLETED, Completion: 100},
+		"j1":  {Id: "j1", PrinterId: "p1", StartTime: suite.start, State: types.JOB_STATE_PRINTING, PlannedFilamentG: 20, PlannedFilamentM: 6.4},
+	}}
+	suite.recorder = NewRecorder(suite.store)
+}
+
+func (suite *RecorderTestSuite) progress(completion float64) {
+	suite.recorder.handle(bus.Event{Type: bus.JOB_PROGRESS, PrinterId: "p1", Data: &driver.Job{Completion: completion}})
+}
+
+func (suite *RecorderTestSuite) state(state driver.State) {
+	suite.recorder.handle(bus.Event{
+		Type:      bus.PRINTER_STATE,
+		PrinterId: "p1",
+		Time:      time.Now(),
+		Data:      bus.StateChange{State: string(state)},
+	})
+}
+
+func (suite *RecorderTestSuite) TestCompleted() {
+	suite.progress(42)
+	assert.Equal(suite.T(), 42.0, suite.store.jobs["j1"].Completion)
+	suite.progress(99.5)
+	suite.state(driver.STATE_IDLE)
+
+	j := suite.store.jobs["j1"]
+	assert.Equal(suite.T(), types.JOB_STATE_COMPLETED, j.State)
+	assert.Equal(suite.T(), 100.0, j.Completion)
+	assert.Equal(suite.T(), 20.0, j.FilamentUsedG)
+	assert.NotNil(suite.T(), j.EndTime)
+	assert.Equal(suite.T(), types.JOB_STATE_COMPLETED, suite.store.jobs["old"].State, "finished jobs are left alone")
+}
+
+func (suite *RecorderTestSuite) TestCancelled() {
+	suite.progress(25)
+	suite.state(driver.STATE_PAUSED)
+	assert.Equal(suite.T(), types.JOB_STATE_PAUSED, suite.store.jobs["j1"].State)
+	suite.state(driver.STATE_IDLE)
+
+	j := suite.store.jobs["j1"]
+	assert.Equal(suite.T(), types.JOB_STATE_CANCELLED, j.State)
+	assert.Equal(suite.T(), 5.0, j.FilamentUsedG, "only the printed part of the filament is used")
+	assert.Equal(suite.T(), 1.6, j.FilamentUsedM)
+}
+
+func (suite *RecorderTestSuite) TestFailed() {
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	defer unsubscribe()
+	suite.state(driver.STATE_OFFLINE)
+	assert.Equal(suite.T(), types.JOB_STATE_PRINTING, suite.store.jobs["j1"].State, "offline does not end a job")
+	assert.Len(suite.T(), events, 0)
+	suite.state(driver.STATE_ERROR)
+	assert.Equal(suite.T(), types.JOB_STATE_FAILED, suite.store.jobs["j1"].State)
+
+	e := <-events
+	assert.Equal(suite.T(), bus.JOB_FINISHED, e.Type)
+	assert.Equal(suite.T(), types.JOB_STATE_FAILED, e.Data.(types.Job).State)
+}
+
+func (suite *RecorderTestSuite) TestStartStop() {
+	suite.recorder.Start()
+	bus.Publish(bus.Event{Type: bus.PRINTER_STATE, PrinterId: "p1", Data: bus.StateChange{State: string(driver.STATE_ERROR)}})
+	suite.recorder.Stop()
+	assert.Equal(suite.T(), types.JOB_STATE_FAILED, suite.store.jobs["j1"].State)
+}
+
+func TestRecorderTestSuite(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
@@ -0,0 +1,41 @@
+package job
+
+import (
+	"ymir/pkg/api"
+	"ymir/pkg/api/job/store"
+	"ymir/pkg/api/job/types"
+)
+
+type JobServiceIface interface {
+	api.Service
+	ListJobs() (map[string]types.Job, error)
+	GetJob(id string) (types.Job, error)
+}
+
+type JobService struct {
+	JobServiceIface
+	name     string
+	jobStore store.JobStoreIFace
+}
+
+func NewJobService() api.Service {
+	return JobService{
+		name:     "Jobs",
+		jobStore: store.NewJobDataStore(),
+	}
+}
+
+func (js JobService) GetName() (name string) {
+	return js.name
+}
+
+/*
+ListJobs lists every print job Ymir has sent to a printer
+*/
+func (js JobService) ListJobs() (map[string]types.Job, error) {
+	return js.jobStore.List()
+}
+
+func (js JobService) GetJob(id string) (types.Job, error) {
+	return js.jobStore.Inspect(id)
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/job/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	JOBS_BUCKET = "jobs"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+)
+
+type JobStoreIFace interface {
+	Create(job types.Job) (err error)
+	Update(job types.Job) (err error)
+	Delete(id string) (err error)
+	List() (jobs map[string]types.Job, err error)
+	ListByModel(modelId string) (jobs map[string]types.Job, err error)
+	ListByPrinter(printerId string) (jobs map[string]types.Job, err error)
+	Inspect(id string) (job types.Job, err error)
+	Truncate() (err error)
+}
+
+type JobStore struct {
+	JobStoreIFace
+	bucket string
+	ds     db.BoltDBDataStore
+}
+
+func NewJobDataStore() (store JobStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := JobStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := createBucketIfNotExists(&d.ds)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+func createBucketIfNotExists(ds *db.BoltDBDataStore) error {
+	return ds.CreateBucket(JOBS_BUCKET)
+}
+
+func (js JobStore) Create(job types.Job) (err error) {
+	return js.Update(job)
+}
+
+func (js JobStore) Update(job types.Job) (err error) {
+	return js.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(JOBS_BUCKET))
+		jJson, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(job.Id), jJson)
+	})
+}
+
+func (js JobStore) Delete(id string) (err error) {
+	return js.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(JOBS_BUCKET))
+		return b.Delete([]byte(id))
+	})
+}
+
+func (js JobStore) Truncate() (err error) {
+	err = js.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(JOBS_BUCKET))
+	})
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+	return createBucketIfNotExists(&js.ds)
+}
+
+func (js JobStore) List() (map[string]types.Job, error) {
+	return js.filter(func(types.Job) bool { return true })
+}
+
+func (js JobStore) ListByModel(modelId string) (map[string]types.Job, error) {
+	return js.filter(func(j types.Job) bool { return j.ModelId == modelId })
+}
+
+func (js JobStore) ListByPrinter(printerId string) (map[string]types.Job, error) {
+	return js.filter(func(j types.Job) bool { return j.PrinterId == printerId })
+}
+
+func (js JobStore) filter(match func(types.Job) bool) (map[string]types.Job, error) {
+	jobs := map[string]types.Job{}
+	err := js.ds.GetDB().View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(JOBS_BUCKET))
+		return b.ForEach(func(k, v []byte) error {
+			j := types.Job{}
+			err := json.Unmarshal(v, &j)
+			if err != nil {
+				log.Error("error unmarshalling job")
+				return err
+			}
+			if match(j) {
+				jobs[string(k)] = j
+			}
+			return nil
+		})
+	})
+	return jobs, err
+}
+
+func (js JobStore) Inspect(id string) (job types.Job, err error) {
+	err = js.ds.GetDB().View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(JOBS_BUCKET))
+		v := b.Get([]byte(id))
+		if v == nil {
+			return ErrJobNotFound
+		}
+		return json.Unmarshal(v, &job)
+	})
+	return job, err
+}
+
+func (js JobStore) NumJobs() int {
+	numJobs, _ := js.ds.GetNumKeys(JOBS_BUCKET)
+	return numJobs
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/job/types"
+)
+
+const (
+	TEST_DB = "test.db"
+)
+
+type JobStoreTestSuite struct {
+	suite.Suite
+	store JobStore
+	jobs  []types.Job
+}
+
+func (suite *JobStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewJobDataStore().(JobStore)
+	suite.jobs = []types.Job{
+		{Id: "j1", ModelId: "m1", PrinterId: "p1", File: "a.gcode", StartTime: time.Now(), State: types.JOB_STATE_PRINTING},
+		{Id: "j2", ModelId: "m1", PrinterId: "p2", File: "a.gcode", StartTime: time.Now(), State: types.JOB_STATE_COMPLETED},
+		{Id: "j3", ModelId: "m2", PrinterId: "p1", File: "b.gcode", StartTime: time.Now(), State: types.JOB_STATE_FAILED},
+	}
+}
+
+func (suite *JobStoreTestSuite) SetupTest() {
+	assert.NoError(suite.T(), suite.store.Truncate())
+	for _, j := range suite.jobs {
+		assert.NoError(suite.T(), suite.store.Create(j))
+	}
+}
+
+func (suite *JobStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *JobStoreTestSuite) TestList() {
+	jobs, err := suite.store.List()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), jobs, 3)
+	assert.Equal(suite.T(), 3, suite.store.NumJobs())
+}
+
+func (suite *JobStoreTestSuite) TestListByModel() {
+	jobs, err := suite.store.ListByModel("m1")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), jobs, 2)
+	assert.Contains(suite.T(), jobs, "j1")
+	assert.Contains(suite.T(), jobs, "j2")
+}
+
+func (suite *JobStoreTestSuite) TestListByPrinter() {
+	jobs, err := suite.store.ListByPrinter("p1")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), jobs, 2)
+	assert.Contains(suite.T(), jobs, "j3")
+}
+
+func (suite *JobStoreTestSuite) TestInspectAndUpdate() {
+	j, err := suite.store.Inspect("j1")
+	assert.NoError(suite.T(), err)
+	j.Finish(types.JOB_STATE_CANCELLED, time.Now())
+	assert.NoError(suite.T(), suite.store.Update(j))
+
+	j, err = suite.store.Inspect("j1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), types.JOB_STATE_CANCELLED, j.State)
+	assert.NotNil(suite.T(), j.EndTime)
+
+	_, err = suite.store.Inspect("nope")
+	assert.ErrorIs(suite.T(), err, ErrJobNotFound)
+}
+
+func (suite *JobStoreTestSuite) TestDelete() {
+	assert.NoError(suite.T(), suite.store.Delete("j2"))
+	assert.Equal(suite.T(), 2, suite.store.NumJobs())
+}
+
+func TestJobStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(JobStoreTestSuite))
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"time"
+)
+
+const (
+	JOB_STATE_PRINTING  = "printing"
+	JOB_STATE_PAUSED    = "paused"
+	JOB_STATE_COMPLETED = "completed"
+	JOB_STATE_CANCELLED = "cancelled"
+	JOB_STATE_FAILED    = "failed"
+	// JOB_STATE_UNKNOWN is used when a job was never seen finishing, e.g. the next job was sent before the poller noticed
+	JOB_STATE_UNKNOWN = "unknown"
+)
+
+/*
+Job is a print file that Ymir sent to a printer to be printed. The planned filament in grams and meters comes from
+the gcode metadata and the filament used is the part of it that was printed before the job ended.
+*/
+type Job struct {
+	Id               string     `json:"_id,omitempty"`
+	ModelId          string     `json:"modelId,omitempty"`
+	ModelName        string     `json:"modelName,omitempty"`
+	File             string     `json:"file"`
+	PrinterId        string     `json:"printerId"`
+	PrinterName      string     `json:"printerName,omitempty"`
+	User             string     `json:"user,omitempty"`
+	StartTime        time.Time  `json:"startTime"`
+	EndTime          *time.Time `json:"endTime,omitempty"`
+	State            string     `json:"state"`
+	Completion       float64    `json:"completion"`
+	PlannedFilamentG float64    `json:"plannedFilamentG,omitempty"`
+	FilamentUsedG    float64    `json:"filamentUsedG"`
+	PlannedFilamentM float64    `json:"plannedFilamentM,omitempty"`
+	FilamentUsedM    float64    `json:"filamentUsedM"`
+}
+
+/*
+Active is true until the job has completed, been cancelled or failed
+*/
+func (j Job) Active() bool {
+	return j.State == JOB_STATE_PRINTING || j.State == JOB_STATE_PAUSED
+}
+
+/*
+Finish ends the job in the given state and works out how much of the planned filament was used
+*/
+func (j *Job) Finish(state string, end time.Time) {
+	if state == JOB_STATE_COMPLETED {
+		j.Completion = 100
+	}
+	j.State = state
+	j.EndTime = &end
+	j.FilamentUsedG = j.PlannedFilamentG * j.Completion / 100
+	j.FilamentUsedM = j.PlannedFilamentM * j.Completion / 100
+}
+
+/*
+Duration is how long the job ran, 0 until it has ended
+*/
+func (j Job) Duration() time.Duration {
+	if j.EndTime == nil {
+		return 0
+	}
+	return j.EndTime.Sub(j.StartTime)
+}
@@ -1,14 +1,11 @@
 package model
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"time"
 	"unsafe"
@@ -18,6 +15,10 @@ import (
 	"ymir/pkg/api"
 	"ymir/pkg/api/model/types"
 	types2 "ymir/pkg/api/printer/types"
+	"ymir/pkg/cost"
+	"ymir/pkg/gcode"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/preflight"
 )
 
 type ModelHandler struct {
@@ -118,6 +119,13 @@ func (mh ModelHandler) addRoutes() []api.Route {
 			false,
 			mh.inspect,
 		},
+		{
+			"listModelJobs",
+			http.MethodGet,
+			"/{id}/jobs",
+			false,
+			mh.listJobs,
+		},
 		{
 			"uploadFile",
 			http.MethodPost,
@@ -146,6 +154,13 @@ func (mh ModelHandler) addRoutes() []api.Route {
 			false,
 			mh.addNote,
 		},
+		{
+			"getModelCost",
+			http.MethodGet,
+			"/{id}/cost",
+			false,
+			mh.getCost,
+		},
 		{
 			"parseGCode",
 			http.MethodGet,
@@ -396,6 +411,53 @@ func (mh ModelHandler) inspect(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+/*
+GET /model/{id}/cost (200, 400, 500) -- estimates what the print files of the model with {id} cost
+and adds up the cost of the jobs that printed them
+*/
+func (mh ModelHandler) getCost(w http.ResponseWriter, r *http.Request) {
+	modelId := chi.URLParam(r, "id")
+	if modelId == "" {
+		http.Error(w, "modelId is missing or bad", http.StatusBadRequest)
+		return
+	}
+	summary, err := mh.Service.(ModelServiceIface).GetModelCost(modelId)
+	if err != nil {
+		log.Errorf("model cost service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(summary)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
+
+/*
+GET /model/{id}/jobs (200, 400, 500) -- lists the print jobs of the model with {id}
+*/
+func (mh ModelHandler) listJobs(w http.ResponseWriter, r *http.Request) {
+	modelId := chi.URLParam(r, "id")
+	if modelId == "" {
+		http.Error(w, "modelId is missing or bad", http.StatusBadRequest)
+		return
+	}
+	jobs, err := mh.Service.(ModelServiceIface).ListModelJobs(modelId)
+	if err != nil {
+		log.Errorf("list model jobs service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(jobs)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
+
 /*
 GET /model/export?path (204, 400, 500) -- gets model with {id}
 */
@@ -471,9 +533,10 @@ func (mh ModelHandler) uploadHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 /*
-POST /model/file/printer?file:<string>&print:<bool> (201, 400, 401, 500) -- Uploads file to printer
+POST /model/file/printer?file:<string>&print:<bool>&override:<bool> (201, 400, 401, 409, 500) -- Uploads file to printer
 Body: {Printer}
-https://github.com/mcuadros/go-octoprint/blob/master/files.go#L106
+The file is checked against the printer's profile first. Both 201 and 409 return the preflight report, 409 when it has
+errors and override was not set.
 */
 func (mh ModelHandler) UploadFileToPrinter(w http.ResponseWriter, r *http.Request) {
 	//Get the file
@@ -488,6 +551,7 @@ func (mh ModelHandler) UploadFileToPrinter(w http.ResponseWriter, r *http.Reques
 		log.Errorf("malformed print parameter.  setting to false")
 		printFile = false
 	}
+	override, _ := strconv.ParseBool(r.URL.Query().Get("override"))
 	//Decode the printer
 	var p types2.Printer
 	err = json.NewDecoder(r.Body).Decode(&p)
@@ -497,115 +561,35 @@ func (mh ModelHandler) UploadFileToPrinter(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ur := UploadFileRequest{
-		Location: "local",
-		Select:   true,
-		Print:    printFile,
-	}
-	file, _ := os.Open(filePath)
-	defer file.Close()
-	err = ur.AddFile(filepath.Base(file.Name()), file)
-	if err != nil {
-		log.Errorf("error adding file: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	report, err := mh.Service.(ModelServiceIface).UploadFileToPrinter(filePath, p, printFile, override, api.RequestUser(r))
+	w.Header().Set("x-powered-by", "bacon")
+	w.Header().Set("Content-Type", "application/json")
+	var statusErr driver.StatusError
+	var preflightErr *preflight.Error
+	if errors.As(err, &preflightErr) {
+		// retry with ?override=true to print anyway
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(preflightErr.Report)
 		return
-	}
-	ur.writer().WriteField("path", "ymir")
-	ur.addSelectPrintAndClose()
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/files/%s", p.URL, ur.Location), ur.b)
-	if err != nil {
-		log.Errorf("request error: %v", err)
+	} else if errors.As(err, &statusErr) {
+		if statusErr.HTTPStatus() == http.StatusUnauthorized || statusErr.HTTPStatus() == http.StatusForbidden {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(`{"error": "Missing or invalid API key"}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(fmt.Sprintf("{error: %v, code: %v}", statusErr.Error(), statusErr.HTTPStatus()))
+		}
+		return
+	} else if errors.Is(err, os.ErrNotExist) || errors.Is(err, driver.ErrUnknownAPIType) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
-	}
-	req.Header.Add("Host", "localhost:5000")
-	req.Header.Add("Accept", "*/*")
-	req.Header.Add("Content-Type", ur.w.FormDataContentType())
-	req.Header.Add("X-Api-Key", p.APIKey)
-
-	c := &http.Client{
-		Transport: &http.Transport{
-			DisableKeepAlives: true,
-		},
-	}
-	resp, err := c.Do(req)
-	if err != nil {
-		log.Errorf("response error: %v", err)
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
-	w.Header().Set("x-powered-by", "bacon")
-	w.Header().Set("Content-Type", "application/json")
-
-	if resp.StatusCode == 401 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(`{"error": "Missing or invalid API key"}`)
-	} else if resp.StatusCode == 201 {
-		w.WriteHeader(http.StatusCreated)
-		log.Debug(resp.StatusCode)
-		body, _ := io.ReadAll(resp.Body)
-		if log.GetLevel() == log.DebugLevel {
-			fmt.Println(string(body))
-		}
-		json.NewEncoder(w).Encode(string(body))
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(fmt.Sprintf("{error: %v, code: %v, body: %v}", resp.Status, resp.StatusCode, resp.Body))
-	}
-}
-
-type UploadFileRequest struct {
-	// Location is the target location to which to upload the file. Currently
-	// only `local` and `sdcard` are supported here, with local referring to
-	// OctoPrint’s `uploads` folder and `sdcard` referring to the printer’s
-	// SD card. If an upload targets the SD card, it will also be stored
-	// locally first.
-	Location string
-	// Select whether to select the file directly after upload (true) or not
-	// (false). Optional, defaults to false. Ignored when creating a folder.
-	Select bool
-	//Print whether to start printing the file directly after upload (true) or
-	// not (false). If set, select is implicitely true as well. Optional,
-	// defaults to false. Ignored when creating a folder.
-	Print bool
-	b     *bytes.Buffer
-	w     *multipart.Writer
-}
-
-// AddFile adds a new file to be uploaded from a given reader.
-func (req *UploadFileRequest) AddFile(filename string, r io.Reader) error {
-	w, err := req.writer().CreateFormFile("file", filename)
-	if err != nil {
-		return err
-	}
-
-	_, err = io.Copy(w, r)
-	return err
-
-}
-
-func (req *UploadFileRequest) writer() *multipart.Writer {
-	if req.w == nil {
-		req.b = bytes.NewBuffer(nil)
-		req.w = multipart.NewWriter(req.b)
-	}
-
-	return req.w
-}
-
-func (req *UploadFileRequest) addSelectPrintAndClose() error {
-	err := req.writer().WriteField("select", fmt.Sprintf("%t", req.Select))
-	if err != nil {
-		return err
-	}
-
-	err = req.writer().WriteField("print", fmt.Sprintf("%t", req.Print))
-	if err != nil {
-		return err
-	}
 
-	return req.writer().Close()
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
 }
 
 /*
@@ -691,17 +675,25 @@ func (mh ModelHandler) addNote(w http.ResponseWriter, r *http.Request) {
 }
 
 /*
-GET /gcode (200, 500) -- Fetches Model STL Thumbnail
+GCodeInfo is a print file's slicer metadata with what the print is estimated to cost
+*/
+type GCodeInfo struct {
+	gcode.GCodeMetaData
+	Cost cost.Estimate `json:"cost"`
+}
+
+/*
+GET /gcode (200, 500) -- Fetches the slicer metadata and estimated cost of the print file at ?path=
 */
 func (mh ModelHandler) parseGCode(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Query().Get("path")
-	gcode, err := mh.Service.(ModelServiceIface).GetGCodeMetaData(path)
+	meta, err := mh.Service.(ModelServiceIface).GetGCodeMetaData(path)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	js, err := json.Marshal(gcode)
+	js, err := json.Marshal(GCodeInfo{GCodeMetaData: meta, Cost: mh.Service.(ModelServiceIface).EstimateCost(meta)})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -159,6 +159,20 @@ func (suite *ModelHandlerTestSuite) TestModelHandler_DeleteModel() {
 
 }
 
+// GET /model/gcode?path=
+func (suite *ModelHandlerTestSuite) TestModelHandler_ParseGCode() {
+	req := httptest.NewRequest(http.MethodGet, "/model/gcode?path=model1/benchy.gcode", nil)
+	rr := httptest.NewRecorder()
+	suite.handler.parseGCode(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var info GCodeInfo
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(suite.T(), "PLA", info.Material, "the metadata is still at the top level")
+	assert.Equal(suite.T(), 1.3, info.Cost.Total)
+	assert.Equal(suite.T(), "USD", info.Cost.Currency)
+}
+
 func TestModelHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(ModelHandlerTestSuite))
 }
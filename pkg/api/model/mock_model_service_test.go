@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"ymir/pkg/api/model/types"
+	"ymir/pkg/cost"
 	"ymir/pkg/gcode"
 )
 
@@ -82,7 +83,19 @@ func (m *MockModelService) AddNote(model types.Model) error {
 }
 
 func (m *MockModelService) GetGCodeMetaData(path string) (gcode.GCodeMetaData, error) {
-	return gcode.GCodeMetaData{}, nil
+	return gcode.GCodeMetaData{Material: "PLA", FilamentUsedG: "50", TotalTime: "2h 0m 0s"}, nil
+}
+
+func (m *MockModelService) EstimateCost(meta gcode.GCodeMetaData) cost.Estimate {
+	return testCostConfig().EstimateGCode(meta)
+}
+
+func (m *MockModelService) GetModelCost(id string) (cost.Summary, error) {
+	return cost.Summary{ModelId: id, Files: []cost.FileEstimate{}}, nil
+}
+
+func testCostConfig() *cost.CostConfig {
+	return &cost.CostConfig{Currency: "USD", DefaultPricePerKg: 20, PowerWatts: 200, ElectricityRate: 0.25, WearPerHour: 0.1}
 }
 
 func (m *MockModelService) GetName() string {
@@ -8,12 +8,24 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"ymir/pkg/api"
+	jobs "ymir/pkg/api/job"
+	jobstore "ymir/pkg/api/job/store"
+	jobtypes "ymir/pkg/api/job/types"
 	"ymir/pkg/api/model/store"
 	"ymir/pkg/api/model/types"
+	printerstore "ymir/pkg/api/printer/store"
+	printer "ymir/pkg/api/printer/types"
+	spoolstore "ymir/pkg/api/spool/store"
+	"ymir/pkg/cost"
+	"ymir/pkg/events"
 	"ymir/pkg/gcode"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/preflight"
 	"ymir/pkg/stl"
 	"ymir/pkg/utils"
 )
@@ -36,20 +48,33 @@ type ModelServiceIface interface {
 	//UploadFile(file multipart.File, filename string, basePath string, isExistingModel bool) (key string, err error)
 	UploadFilesExistingModel(file multipart.File, filename string, basePath string) (string, error)
 	UploadFilesNewModel(file multipart.File, filename string) (string, error)
+	UploadFileToPrinter(filePath string, p printer.Printer, print bool, override bool, user string) (preflight.Report, error)
+	PreflightCheck(filePath string, p printer.Printer) preflight.Report
+	ListModelJobs(id string) (map[string]jobtypes.Job, error)
+	EstimateCost(meta gcode.GCodeMetaData) cost.Estimate
+	GetModelCost(id string) (cost.Summary, error)
 }
 
 type ModelService struct {
 	ModelServiceIface
-	name       string
-	modelStore store.ModelStoreIFace
-	config     *ModelsConfig
+	name         string
+	modelStore   store.ModelStoreIFace
+	jobStore     jobstore.JobStoreIFace
+	printerStore printerstore.PrinterStoreIFace
+	spoolStore   spoolstore.SpoolStoreIFace
+	config       *ModelsConfig
+	cost         *cost.CostConfig
 }
 
 func NewModelService() (modelService api.Service) {
 	ms := ModelService{
-		name:       "Model",
-		config:     NewModelsConfig(),
-		modelStore: store.NewModelDataStore(),
+		name:         "Model",
+		config:       NewModelsConfig(),
+		cost:         cost.NewCostConfig(),
+		modelStore:   store.NewModelDataStore(),
+		jobStore:     jobstore.NewJobDataStore(),
+		printerStore: printerstore.NewPrinterDataStore(),
+		spoolStore:   spoolstore.NewSpoolDataStore(),
 	}
 
 	err := utils.MakeDirIfNotExists(ms.config.UploadsTempDir)
@@ -334,6 +359,227 @@ func (ms ModelService) UploadFilesNewModel(file multipart.File, filename string)
 	return path, nil
 }
 
+/*
+UploadFileToPrinter sends a print file to the printer through the driver for its APIType.
+When the file is printed a job is recorded for the printer on behalf of user.
+A saved printer is reached at its stored address with its stored secrets, whatever the client sent.
+The file is checked against the printer's profile first and a blocking report is returned as a *preflight.Error
+unless override is set.
+*/
+func (ms ModelService) UploadFileToPrinter(filePath string, p printer.Printer, print bool, override bool, user string) (preflight.Report, error) {
+	if p.Id != "" {
+		if stored, err := ms.printerStore.Inspect(p.Id); err == nil && stored.Id != "" {
+			p.URL, p.APIType, p.DevicePath, p.BaudRate, p.Username = stored.URL, stored.APIType, stored.DevicePath, stored.BaudRate, stored.Username
+			p.APIKey, p.Password = stored.APIKey, stored.Password
+			if p.Profile == nil {
+				p.Profile = stored.Profile
+			}
+			if p.SpoolId == "" {
+				p.SpoolId = stored.SpoolId
+			}
+		}
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Errorf("error opening file: %v", err)
+		return preflight.Report{}, err
+	}
+	defer file.Close()
+
+	report, meta := ms.preflightCheck(filePath, p)
+	if report.Blocking() {
+		if !override {
+			return report, &preflight.Error{Report: report}
+		}
+		report.Overridden = true
+		log.Warnf("sending %v to printer %v despite: %v", filePath, p.Id, (&preflight.Error{Report: report}).Error())
+	}
+	for _, i := range report.Issues {
+		if i.Check == preflight.CHECK_FILAMENT {
+			log.Warnf("sending %v to printer %v: %v", filePath, p.Id, i.Message)
+		}
+	}
+
+	d, err := driver.NewDriver(p)
+	if err != nil {
+		log.Error(err)
+		return report, err
+	}
+
+	err = d.Upload(filepath.Base(file.Name()), file, print)
+	if err != nil {
+		log.Errorf("error uploading %v to printer %v: %v", filePath, p.Id, err)
+		return report, err
+	}
+	log.Infof("uploaded %v to printer %v", filePath, p.Id)
+	if print {
+		if err := ms.recordJob(filePath, meta, p, user); err != nil {
+			// the print has started, so don't fail the upload over its history
+			log.Errorf("could not record job for %v on printer %v: %v", filePath, p.Id, err)
+		}
+	}
+	events.Publish(events.Event{
+		Type:      events.UPLOAD_COMPLETE,
+		PrinterId: p.Id,
+		Data: events.Upload{
+			PrinterName: p.PrinterName,
+			File:        filepath.Base(filePath),
+			Print:       print,
+		},
+	})
+	return report, nil
+}
+
+/*
+PreflightCheck compares a print file's slicer settings and extents with the printer's profile
+and the filament it needs with the printer's spool
+*/
+func (ms ModelService) PreflightCheck(filePath string, p printer.Printer) preflight.Report {
+	report, _ := ms.preflightCheck(filePath, p)
+	return report
+}
+
+/*
+preflightCheck also returns what was read from the file, so it doesn't have to be read again
+*/
+func (ms ModelService) preflightCheck(filePath string, p printer.Printer) (preflight.Report, gcode.GCodeMetaData) {
+	meta := gcode.GCodeMetaData{}
+	ext := gcode.Extents{}
+	// binary G-code can't be scanned, only its format checked
+	if !strings.EqualFold(filepath.Ext(filePath), ".bgcode") {
+		g := gcode.NewGCode(filePath)
+		if err := g.ParseGCode(false); err != nil {
+			log.Debugf("could not read the slicer settings of %v: %v", filePath, err)
+		}
+		// the extents are needed whatever the slicer wrote
+		if err := g.AnalyzeMoves(); err != nil {
+			log.Debugf("could not follow the moves of %v: %v", filePath, err)
+		}
+		meta = g.MetaData
+		if meta.Stats != nil {
+			ext = meta.Stats.Extents
+		}
+	}
+	report := preflight.Check(filePath, meta, ext, p.Profile)
+	if p.SpoolId != "" {
+		if spool, err := ms.spoolStore.Inspect(p.SpoolId); err == nil {
+			preflight.CheckSpool(&report, meta, spool)
+		} else {
+			log.Errorf("could not find spool %v of printer %v: %v", p.SpoolId, p.Id, err)
+		}
+	}
+	return report, meta
+}
+
+/*
+recordJob stores a printing job for the file, planning the filament meta says it needs
+*/
+func (ms ModelService) recordJob(filePath string, meta gcode.GCodeMetaData, p printer.Printer, user string) error {
+	job := jobtypes.Job{
+		File:        filePath,
+		PrinterId:   p.Id,
+		PrinterName: p.PrinterName,
+		User:        user,
+	}
+	models, err := ms.modelStore.List()
+	if err != nil {
+		return err
+	}
+	filePath = filepath.Clean(filePath)
+models:
+	for id, m := range models {
+		if m.BasePath == "" {
+			continue
+		}
+		for _, pf := range m.PrintFiles {
+			if filepath.Join(m.BasePath, pf.Path) == filePath {
+				job.ModelId = id
+				job.ModelName = m.DisplayName
+				break models
+			}
+		}
+	}
+	job.PlannedFilamentG = sumAmounts(meta.FilamentUsedG)
+	job.PlannedFilamentM = meta.FilamentMeters()
+	_, err = jobs.RecordStart(ms.jobStore, job)
+	return err
+}
+
+/*
+sumAmounts adds up slicer amounts like "12.3" or "1.5, 4.25" for multi extruder prints
+*/
+func sumAmounts(amounts string) float64 {
+	total := 0.0
+	for _, a := range strings.Split(amounts, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		if err == nil {
+			total += v
+		}
+	}
+	return total
+}
+
+/*
+ListModelJobs lists the jobs that printed the model's files
+*/
+func (ms ModelService) ListModelJobs(id string) (map[string]jobtypes.Job, error) {
+	return ms.jobStore.ListByModel(id)
+}
+
+/*
+EstimateCost prices a print file from its slicer metadata with the configured filament, electricity and wear rates
+*/
+func (ms ModelService) EstimateCost(meta gcode.GCodeMetaData) cost.Estimate {
+	return ms.cost.EstimateGCode(meta)
+}
+
+/*
+GetModelCost estimates each of the model's print files and adds up what the jobs that printed them cost,
+from the filament they used and how long they ran
+*/
+func (ms ModelService) GetModelCost(id string) (cost.Summary, error) {
+	model, err := ms.GetModel(id)
+	if err != nil {
+		return cost.Summary{}, err
+	}
+	summary := cost.Summary{ModelId: id, Files: []cost.FileEstimate{}, Printed: cost.Estimate{Currency: ms.cost.Currency}}
+	materials := map[string][]string{}
+	meta := func(file string) gcode.GCodeMetaData {
+		g := gcode.NewGCode(file)
+		if err := g.ParseGCode(false); err != nil {
+			log.Debugf("could not read the slicer settings of %v: %v", file, err)
+		}
+		// following the moves reads the whole file, only do it when the slicer didn't say
+		if g.MetaData.FilamentUsedG == "" || g.MetaData.TotalTime == "" {
+			if err := g.AnalyzeMoves(); err != nil {
+				log.Debugf("could not follow the moves of %v: %v", file, err)
+			}
+		}
+		materials[file] = strings.FieldsFunc(g.MetaData.Material, func(r rune) bool { return r == ';' || r == ',' })
+		return g.MetaData
+	}
+	for _, f := range model.PrintFiles {
+		file := filepath.Join(model.BasePath, f.Path)
+		summary.Files = append(summary.Files, cost.FileEstimate{Path: f.Path, Estimate: ms.cost.EstimateGCode(meta(file))})
+	}
+
+	jobs, err := ms.jobStore.ListByModel(id)
+	if err != nil {
+		return summary, err
+	}
+	for _, j := range jobs {
+		if j.Active() || j.EndTime == nil {
+			continue
+		}
+		if _, ok := materials[j.File]; !ok {
+			meta(j.File)
+		}
+		summary.Jobs++
+		summary.Printed = summary.Printed.Add(ms.cost.Calculate(materials[j.File], []float64{j.FilamentUsedG}, j.Duration()))
+	}
+	return summary, nil
+}
+
 func (ms ModelService) FetchModelImage(image string) (imageBytes []byte, err error) {
 	imageBytes, err = os.ReadFile(filepath.Join(ms.config.ModelsDir, image))
 	if err != nil {
@@ -390,6 +636,10 @@ func (ms ModelService) GetGCodeMetaData(path string) (gcode.GCodeMetaData, error
 		log.Error(err)
 		return gcode.GCodeMetaData{}, err
 	}
+	if err := g.AnalyzeMoves(); err != nil {
+		log.Error(err)
+		return gcode.GCodeMetaData{}, err
+	}
 
 	return g.MetaData, nil
 }
@@ -2,7 +2,10 @@ package model
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -12,9 +15,15 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
 	"ymir/pkg/api/model/store"
 	"ymir/pkg/api/model/types"
+	printertypes "ymir/pkg/api/printer/types"
+	"ymir/pkg/gcode"
 	"ymir/pkg/logger"
+	_ "ymir/pkg/printer/octoprint"
+	"ymir/pkg/printer/preflight"
+	"ymir/pkg/printer/profiles"
 )
 
 const (
@@ -39,6 +48,9 @@ logLevel="DEBUG"
 [datastore]
 dbFile = "test.db"
 
+[secrets]
+key = "test"
+
 [models]
 uploadsTempDir="TEST_DIR/tmp"
 modelsDir="TEST_DIR/models"
@@ -211,6 +223,124 @@ func (suite *ModelServiceTestSuite) TestGetGCodeMetaData() {
 	assert.Equal(suite.T(), "PRUSA", gcode.GCodeType)
 }
 
+func (suite *ModelServiceTestSuite) TestUploadFileToPrinter_Preflight() {
+	file := filepath.Join(suite.T().TempDir(), "benchy_mk4.gcode")
+	err := os.WriteFile(file, []byte(`; generated by PrusaSlicer 2.6.1+linux-x64-GTK3 on 2023-09-25 at 22:19:37 UTC
+G1 X10 Y10 Z0.2
+G1 X240 Y10 E10
+; nozzle_diameter = 0.4
+; printer_model = MK4IS
+`), 0644)
+	assert.NoError(suite.T(), err)
+	mk3, _ := profiles.Get("prusa-mk3s")
+	p := printertypes.Printer{APIType: printertypes.API_TYPE_OCTOPRINT, URL: "http://127.0.0.1:1", Profile: &mk3}
+
+	report, err := suite.service.UploadFileToPrinter(file, p, true, false, "bob")
+	var preflightErr *preflight.Error
+	assert.ErrorAs(suite.T(), err, &preflightErr)
+	assert.True(suite.T(), report.Blocking())
+	assert.Equal(suite.T(), preflight.CHECK_PRINTER_TYPE, report.Issues[0].Check)
+
+	// overriding gets as far as the unreachable printer
+	report, err = suite.service.UploadFileToPrinter(file, p, true, true, "bob")
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), errors.As(err, &preflightErr))
+	assert.True(suite.T(), report.Overridden)
+}
+
+func (suite *ModelServiceTestSuite) TestUploadFileToPrinter_StoredAddress() {
+	file := filepath.Join(suite.T().TempDir(), "cube.gcode")
+	assert.NoError(suite.T(), os.WriteFile(file, []byte("G1 X10 Y10 Z0.2\nG1 X20 E1\n"), 0644))
+	keys := make(chan string, 1)
+	printerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case keys <- r.Header.Get("X-Api-Key"):
+		default:
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"done": true}`))
+	}))
+	defer printerSrv.Close()
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.T().Errorf("the printer's key was sent to %v", r.Host)
+	}))
+	defer elsewhere.Close()
+
+	stored := printertypes.Printer{Id: "stored-printer", APIType: printertypes.API_TYPE_OCTOPRINT, URL: printerSrv.URL, APIKey: "ABC123"}
+	assert.NoError(suite.T(), suite.service.printerStore.Create(stored))
+	sent := stored.Redacted()
+	sent.URL = elsewhere.URL
+
+	_, err := suite.service.UploadFileToPrinter(file, sent, false, true, "bob")
+	assert.NoError(suite.T(), err)
+	select {
+	case key := <-keys:
+		assert.Equal(suite.T(), "ABC123", key)
+	case <-time.After(5 * time.Second):
+		suite.T().Error("the upload never reached the stored address")
+	}
+}
+
+func (suite *ModelServiceTestSuite) TestGetModelCost() {
+	dir := suite.T().TempDir()
+	err := os.WriteFile(filepath.Join(dir, "cube.gcode"), []byte(`; generated by PrusaSlicer 2.6.1+linux-x64-GTK3 on 2023-09-25 at 22:19:37 UTC
+; estimated printing time (normal mode) = 2h 0m 0s
+; total filament used [g] = 50
+; filament_type = PLA
+`), 0644)
+	assert.NoError(suite.T(), err)
+	id, err := suite.service.CreateModel(types.Model{})
+	assert.NoError(suite.T(), err)
+	m, err := suite.service.GetModel(id)
+	assert.NoError(suite.T(), err)
+	m.BasePath = dir
+	m.PrintFiles = []types.FileType{{Path: "cube.gcode"}}
+	assert.NoError(suite.T(), suite.service.UpdateModel(m))
+
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(time.Hour)
+	for _, j := range []jobtypes.Job{
+		{Id: "cost-1", ModelId: id, File: filepath.Join(dir, "cube.gcode"), StartTime: start, EndTime: &end,
+			State: jobtypes.JOB_STATE_CANCELLED, FilamentUsedG: 25},
+		{Id: "cost-2", ModelId: id, File: filepath.Join(dir, "cube.gcode"), StartTime: start, State: jobtypes.JOB_STATE_PRINTING},
+	} {
+		assert.NoError(suite.T(), suite.service.jobStore.Create(j))
+	}
+
+	summary, err := suite.service.GetModelCost(id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), summary.Files, 1)
+	assert.Equal(suite.T(), 50.0, summary.Files[0].Estimate.FilamentG)
+	assert.Equal(suite.T(), 2.0, summary.Files[0].Estimate.Hours)
+	assert.Equal(suite.T(), 1, summary.Jobs, "only finished jobs count")
+	assert.Equal(suite.T(), 25.0, summary.Printed.FilamentG)
+	assert.Equal(suite.T(), 1.0, summary.Printed.Hours)
+}
+
+func (suite *ModelServiceTestSuite) TestRecordJob_FindsModel() {
+	dir := suite.T().TempDir()
+	assert.NoError(suite.T(), os.MkdirAll(filepath.Join(dir, "files"), 0755))
+	file := filepath.Join(dir, "files", "cube.gcode")
+	assert.NoError(suite.T(), os.WriteFile(file, []byte("G1 X10 Y10 Z0.2\n"), 0644))
+	id, err := suite.service.CreateModel(types.Model{DisplayName: "Cube"})
+	assert.NoError(suite.T(), err)
+	m, err := suite.service.GetModel(id)
+	assert.NoError(suite.T(), err)
+	m.BasePath = dir
+	m.PrintFiles = []types.FileType{{Path: "files/cube.gcode"}}
+	assert.NoError(suite.T(), suite.service.UpdateModel(m))
+
+	p := printertypes.Printer{Id: "record-printer", PrinterName: "mk4"}
+	assert.NoError(suite.T(), suite.service.recordJob(file, gcode.GCodeMetaData{}, p, "bob"))
+	jobs, err := suite.service.jobStore.ListByPrinter(p.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), jobs, 1)
+	for _, j := range jobs {
+		assert.Equal(suite.T(), id, j.ModelId, "print files are found below the model's base path")
+		assert.Equal(suite.T(), "Cube", j.ModelName)
+	}
+}
+
 func TestModelServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(ModelServiceTestSuite))
 }
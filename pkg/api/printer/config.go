@@ -3,6 +3,7 @@ package printer
 import (
 	"bytes"
 	"encoding/json"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	log "github.com/sirupsen/logrus"
@@ -15,11 +16,17 @@ const (
 
 type PrintersConfig struct {
 	PrintersDir string `toml:"printersDir"`
+	// PollInterval is how often the background poller queries every printer, in seconds
+	PollInterval int `toml:"pollInterval"`
+	// TimelapseInterval is how often a printer with a snapshot url is photographed during a print, in seconds. 0 disables timelapses
+	TimelapseInterval int `toml:"timelapseInterval"`
 }
 
 func NewPrintersConfig() *PrintersConfig {
 	c := &PrintersConfig{
-		PrintersDir: "uploads/printers",
+		PrintersDir:       "uploads/printers",
+		PollInterval:      10,
+		TimelapseInterval: 30,
 	}
 
 	h := viper.Sub(_PRINTERS)
@@ -32,6 +39,20 @@ func NewPrintersConfig() *PrintersConfig {
 	return c
 }
 
+/*
+PollEvery returns PollInterval as a duration
+*/
+func (p *PrintersConfig) PollEvery() time.Duration {
+	return time.Duration(p.PollInterval) * time.Second
+}
+
+/*
+TimelapseEvery returns TimelapseInterval as a duration
+*/
+func (p *PrintersConfig) TimelapseEvery() time.Duration {
+	return time.Duration(p.TimelapseInterval) * time.Second
+}
+
 func (p *PrintersConfig) StringJSON() string {
 	b, _ := json.Marshal(p)
 	return string(b)
@@ -17,14 +17,18 @@ func TestNewPrintersConfig(t *testing.T) {
 			"No Config File",
 			"",
 			&PrintersConfig{
-				PrintersDir: "uploads/printers",
+				PrintersDir:       "uploads/printers",
+				PollInterval:      10,
+				TimelapseInterval: 30,
 			},
 		},
 		{
 			"With Good Config File",
 			"testdata/goodConfig.toml",
 			&PrintersConfig{
-				PrintersDir: "uploads/printers2",
+				PrintersDir:       "uploads/printers2",
+				PollInterval:      30,
+				TimelapseInterval: 30,
 			},
 		},
 	}
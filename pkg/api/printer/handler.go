@@ -2,16 +2,24 @@ package printer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	log "github.com/sirupsen/logrus"
 	"ymir/pkg/api"
+	"ymir/pkg/api/printer/store"
 	"ymir/pkg/api/printer/types"
+	spoolstore "ymir/pkg/api/spool/store"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/profiles"
+	"ymir/pkg/printer/webcam"
 )
 
 const (
@@ -19,11 +27,19 @@ const (
 	_URL           = "url"
 	_API_KEY       = "apiKey"
 	_API_TYPE      = "apiType"
+	_USERNAME      = "username"
+	_PASSWORD      = "password"
+	_DEVICE_PATH   = "devicePath"
+	_BAUD_RATE     = "baudRate"
 	_LOCATION      = "location"
+	_LOCATION_ID   = "locationId"
 	_PRINTER_MAKE  = "printerMake"
 	_PRINTER_MODEL = "printerModel"
 	_TAGS          = "tags"
 	_AUTO_CONNECT  = "autoConnect"
+	_PROFILE       = "profile"
+	_SNAPSHOT_URL  = "snapshotUrl"
+	_STREAM_URL    = "streamUrl"
 )
 
 type PrinterHandler struct {
@@ -74,6 +90,13 @@ func NewPrinterHandler() api.HandlerIFace {
 			false,
 			ph.listAll,
 		},
+		{
+			"listPrinterStatus",
+			http.MethodGet,
+			"/status",
+			false,
+			ph.listStatus,
+		},
 		{
 			"inspectPrinter",
 			http.MethodGet,
@@ -81,6 +104,230 @@ func NewPrinterHandler() api.HandlerIFace {
 			false,
 			ph.inspect,
 		},
+		{
+			"getPrinterJob",
+			http.MethodGet,
+			"/{id}/job",
+			false,
+			ph.getJob,
+		},
+		{
+			"sendJobCommand",
+			http.MethodPost,
+			"/{id}/job",
+			false,
+			ph.jobCommand,
+		},
+		{
+			"getPrinterState",
+			http.MethodGet,
+			"/{id}/state",
+			false,
+			ph.getState,
+		},
+		{
+			"connectPrinter",
+			http.MethodPost,
+			"/{id}/connect",
+			false,
+			ph.connect,
+		},
+		{
+			"sendPrinterCommand",
+			http.MethodPost,
+			"/{id}/command",
+			false,
+			ph.command,
+		},
+		{
+			"listPrinterProfiles",
+			http.MethodGet,
+			"/profiles",
+			false,
+			ph.listProfiles,
+		},
+		{
+			"listLocations",
+			http.MethodGet,
+			"/locations",
+			false,
+			ph.listLocations,
+		},
+		{
+			"createLocation",
+			http.MethodPost,
+			"/locations",
+			false,
+			ph.createLocation,
+		},
+		{
+			"updateLocation",
+			http.MethodPut,
+			"/locations/{locationId}",
+			false,
+			ph.updateLocation,
+		},
+		{
+			"deleteLocation",
+			http.MethodDelete,
+			"/locations/{locationId}",
+			false,
+			ph.deleteLocation,
+		},
+		{
+			"testPrinter",
+			http.MethodPost,
+			"/test",
+			false,
+			ph.test,
+		},
+		{
+			"listPrinterJobs",
+			http.MethodGet,
+			"/{id}/jobs",
+			false,
+			ph.listJobs,
+		},
+		{
+			"getPrinterQueue",
+			http.MethodGet,
+			"/{id}/queue",
+			false,
+			ph.getQueue,
+		},
+		{
+			"addToPrinterQueue",
+			http.MethodPost,
+			"/{id}/queue",
+			false,
+			ph.addToQueue,
+		},
+		{
+			"reorderPrinterQueue",
+			http.MethodPut,
+			"/{id}/queue",
+			false,
+			ph.reorderQueue,
+		},
+		{
+			"confirmBedClear",
+			http.MethodPost,
+			"/{id}/queue/bedclear",
+			false,
+			ph.confirmBedClear,
+		},
+		{
+			"removeFromPrinterQueue",
+			http.MethodDelete,
+			"/{id}/queue/{itemId}",
+			false,
+			ph.removeFromQueue,
+		},
+		{
+			"controlPrinter",
+			http.MethodPost,
+			"/{id}/control",
+			false,
+			ph.control,
+		},
+		{
+			"listPrinterAudit",
+			http.MethodGet,
+			"/{id}/control/audit",
+			false,
+			ph.listAudit,
+		},
+		{
+			"listPrinterFiles",
+			http.MethodGet,
+			"/{id}/files",
+			false,
+			ph.listFiles,
+		},
+		{
+			"deletePrinterFile",
+			http.MethodDelete,
+			"/{id}/files/{path}",
+			false,
+			ph.deleteFile,
+		},
+		{
+			"printPrinterFile",
+			http.MethodPost,
+			"/{id}/files/{path}/print",
+			false,
+			ph.printFile,
+		},
+		{
+			"assignPrinterSpool",
+			http.MethodPut,
+			"/{id}/spool",
+			false,
+			ph.assignSpool,
+		},
+		{
+			"placePrinter",
+			http.MethodPut,
+			"/{id}/location",
+			false,
+			ph.placePrinter,
+		},
+		{
+			"getPrinterUsage",
+			http.MethodGet,
+			"/{id}/usage",
+			false,
+			ph.getUsage,
+		},
+		{
+			"listPrinterMaintenance",
+			http.MethodGet,
+			"/{id}/maintenance",
+			false,
+			ph.listMaintenance,
+		},
+		{
+			"listOverdueMaintenance",
+			http.MethodGet,
+			"/{id}/maintenance/overdue",
+			false,
+			ph.listOverdueMaintenance,
+		},
+		{
+			"createMaintenanceTask",
+			http.MethodPost,
+			"/{id}/maintenance",
+			false,
+			ph.createMaintenanceTask,
+		},
+		{
+			"updateMaintenanceTask",
+			http.MethodPut,
+			"/{id}/maintenance/{taskId}",
+			false,
+			ph.updateMaintenanceTask,
+		},
+		{
+			"deleteMaintenanceTask",
+			http.MethodDelete,
+			"/{id}/maintenance/{taskId}",
+			false,
+			ph.deleteMaintenanceTask,
+		},
+		{
+			"completeMaintenanceTask",
+			http.MethodPost,
+			"/{id}/maintenance/{taskId}/done",
+			false,
+			ph.completeMaintenanceTask,
+		},
+		{
+			"getWebcamSnapshot",
+			http.MethodGet,
+			"/{id}/webcam/snapshot",
+			false,
+			ph.getWebcamSnapshot,
+		},
 	}
 
 	return ph
@@ -112,7 +359,7 @@ func (ph PrinterHandler) create(w http.ResponseWriter, r *http.Request) {
 	printer := types.Printer{
 		PrinterName: "",
 		URL:         "",
-		APIType:     _API_TYPE,
+		APIType:     types.API_TYPE_OCTOPRINT,
 		APIKey:      "",
 		Location: types.Location{
 			Name: "",
@@ -134,6 +381,10 @@ func (ph PrinterHandler) create(w http.ResponseWriter, r *http.Request) {
 		case _PRINTER_NAME:
 			printer.PrinterName = v[0]
 		case _URL:
+			if v[0] == "" {
+				// serial printers have a device path instead
+				continue
+			}
 			u, err := url.Parse(v[0])
 			if err != nil {
 				log.Error(err)
@@ -149,8 +400,30 @@ func (ph PrinterHandler) create(w http.ResponseWriter, r *http.Request) {
 			printer.URL = u.String()
 		case _API_KEY:
 			printer.APIKey = v[0]
+		case _API_TYPE:
+			if v[0] != "" {
+				printer.APIType = v[0]
+			}
+		case _USERNAME:
+			printer.Username = v[0]
+		case _PASSWORD:
+			printer.Password = v[0]
+		case _DEVICE_PATH:
+			printer.DevicePath = v[0]
+		case _BAUD_RATE:
+			if v[0] == "" {
+				continue
+			}
+			baud, err := strconv.Atoi(v[0])
+			if err != nil {
+				http.Error(w, "invalid baud rate: "+v[0], http.StatusBadRequest)
+				return
+			}
+			printer.BaudRate = baud
 		case _LOCATION:
 			printer.Location.Name = v[0]
+		case _LOCATION_ID:
+			printer.Location.Id = v[0]
 		case _PRINTER_MAKE:
 			printer.Type.Make = v[0]
 		case _PRINTER_MODEL:
@@ -164,13 +437,36 @@ func (ph PrinterHandler) create(w http.ResponseWriter, r *http.Request) {
 			} else {
 				printer.AutoConnect = ac
 			}
+		case _PROFILE:
+			if v[0] == "" {
+				continue
+			}
+			profile, ok := profiles.Get(v[0])
+			if !ok {
+				http.Error(w, "unknown printer profile: "+v[0], http.StatusBadRequest)
+				return
+			}
+			printer.Profile = &profile
+		case _SNAPSHOT_URL:
+			printer.SnapshotURL = v[0]
+		case _STREAM_URL:
+			printer.StreamURL = v[0]
+
+		}
+	}
 
+	if validate(r) {
+		result, err := ph.Service.(PrinterServiceIface).TestPrinter(printer)
+		if err != nil {
+			proxyError(w, err)
+			return
 		}
+		printer.Type = result.Type
 	}
 
 	_, err = ph.Service.(PrinterServiceIface).CreatePrinter(printer)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		proxyError(w, err)
 		return
 	}
 
@@ -192,12 +488,21 @@ func (ph PrinterHandler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if log.GetLevel() == log.DebugLevel {
-		fmt.Println(printer.Json())
+		fmt.Println(printer.Redacted().Json())
 	}
-	err = ph.Service.(PrinterService).UpdatePrinter(printer)
+	if validate(r) {
+		result, err := ph.Service.(PrinterServiceIface).TestPrinter(printer)
+		if err != nil {
+			proxyError(w, err)
+			return
+		}
+		printer.Type = result.Type
+	}
+	err = ph.Service.(PrinterServiceIface).UpdatePrinter(printer)
 	if err != nil {
 		log.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		proxyError(w, err)
+		return
 	}
 	w.Header().Set("x-powered-by", "bacon")
 	w.Header().Set("Content-Type", "application/json")
@@ -206,6 +511,51 @@ func (ph PrinterHandler) update(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(respBody)
 }
 
+/*
+POST /Printer/test [Printer{}] (200, 400, 401, 403, 502) -- contacts an unsaved printer to check its url and credentials
+and reports its firmware, server and type
+*/
+func (ph PrinterHandler) test(w http.ResponseWriter, r *http.Request) {
+	var printer types.Printer
+	err := json.NewDecoder(r.Body).Decode(&printer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if printer.URL != "" {
+		u, err := url.Parse(printer.URL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if u.Scheme == "" {
+			u.Scheme = "http"
+		}
+		printer.URL = u.String()
+	}
+	result, err := ph.Service.(PrinterServiceIface).TestPrinter(printer)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+/*
+GET /Printer/profiles (200) -- lists the catalog of printer capability profiles new printers are seeded from
+*/
+func (ph PrinterHandler) listProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ph.Service.(PrinterServiceIface).ListProfiles())
+}
+
+/*
+validate is true when create and update should test the printer first with ?validate=true
+*/
+func validate(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get("validate"))
+	return v
+}
+
 /*
 DELETE /Printer/{id} (200, 404, 500) -- deletes the Printer with {id}
 */
@@ -227,15 +577,23 @@ func (ph PrinterHandler) delete(w http.ResponseWriter, r *http.Request) {
 }
 
 /*
-GET /Printer (200, 500) -- get all Printers
+GET /Printer?location={location}&tag={tag} (200, 500) -- get all Printers, or the ones in a location, including the
+locations inside it, and with a tag. {location} is a location id or name.
 */
 func (ph PrinterHandler) listAll(w http.ResponseWriter, r *http.Request) {
-	printers, err := ph.Service.(PrinterServiceIface).ListPrinters()
+	filter := types.PrinterFilter{
+		Location: r.URL.Query().Get("location"),
+		Tag:      r.URL.Query().Get("tag"),
+	}
+	printers, err := ph.Service.(PrinterServiceIface).ListPrinters(filter)
 	if err != nil {
 		log.Errorf("list all models service error: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for id, printer := range printers {
+		printers[id] = printer.Redacted()
+	}
 	js, err := json.Marshal(printers)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -250,6 +608,18 @@ func (ph PrinterHandler) listAll(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+/*
+GET /Printer/status (200, 503) -- gets the cached state, temperatures, job progress and last seen time of all Printers
+*/
+func (ph PrinterHandler) listStatus(w http.ResponseWriter, r *http.Request) {
+	statuses, err := ph.Service.(PrinterServiceIface).ListPrinterStatus()
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
 /*
 GET /Printer/{id} (200, 401, 404, 500) -- gets Printer with {id}
 */
@@ -262,13 +632,13 @@ func (ph PrinterHandler) inspect(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	js, err := json.Marshal(printer)
+	js, err := json.Marshal(printer.Redacted())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	if log.GetLevel() == log.DebugLevel {
-		fmt.Println(printer.Json())
+		fmt.Println(printer.Redacted().Json())
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -278,6 +648,531 @@ func (ph PrinterHandler) inspect(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+/*
+GET /Printer/{id}/job (200, 403, 500) -- gets the current job from the printer with {id}
+*/
+func (ph PrinterHandler) getJob(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	status, err := ph.Service.(PrinterServiceIface).GetPrinterStatus(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	job := driver.Job{}
+	if status.Job != nil {
+		job = *status.Job
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+/*
+POST /Printer/{id}/job [JobCommand{}] (204, 400, 403, 404, 409, 500) -- starts, cancels, pauses or resumes the job on the printer
+with {id}. The command is added to the printer's audit trail like those sent to /Printer/{id}/control.
+*/
+func (ph PrinterHandler) jobCommand(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	var cmd types.JobCommand
+	err := json.NewDecoder(r.Body).Decode(&cmd)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = ph.Service.(PrinterServiceIface).Control(printerId, jobControl(cmd), api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GET /Printer/{id}/state (200, 403, 500) -- gets state, temperatures and job progress from the printer with {id}
+*/
+func (ph PrinterHandler) getState(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	status, err := ph.Service.(PrinterServiceIface).GetPrinterStatus(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+/*
+GET /Printer/{id}/files (200, 404, 502) -- lists the print files on the storage of the printer with {id}, including its SD card
+*/
+func (ph PrinterHandler) listFiles(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	files, err := ph.Service.(PrinterServiceIface).ListPrinterFiles(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+/*
+DELETE /Printer/{id}/files/{path}?location={location} (204, 400, 404, 502) -- deletes a file from the printer with {id}.
+{path} is URL encoded, e.g. ymir%2Fbenchy.gcode, and location is where it is stored, e.g. sdcard. It defaults to where Ymir uploads to.
+The deletion is added to the printer's audit trail like the commands sent to /Printer/{id}/control.
+*/
+func (ph PrinterHandler) deleteFile(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	err := ph.Service.(PrinterServiceIface).DeletePrinterFile(printerId, r.URL.Query().Get("location"), filePath(r), api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+POST /Printer/{id}/files/{path}/print?location={location} (204, 400, 404, 409, 502) -- starts printing a file already on the printer with {id}.
+The print is added to the printer's audit trail like the commands sent to /Printer/{id}/control.
+*/
+func (ph PrinterHandler) printFile(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	err := ph.Service.(PrinterServiceIface).PrintPrinterFile(printerId, r.URL.Query().Get("location"), filePath(r), api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+filePath is the {path} param. chi leaves it escaped when the request path had escaped slashes in it.
+*/
+func filePath(r *http.Request) string {
+	path := chi.URLParam(r, "path")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		return unescaped
+	}
+	return path
+}
+
+/*
+GET /Printer/{id}/webcam/snapshot (200, 404, 502) -- fetches a frame from the webcam of the printer with {id}
+*/
+func (ph PrinterHandler) getWebcamSnapshot(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	image, contentType, err := ph.Service.(PrinterServiceIface).GetWebcamSnapshot(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	_, err = w.Write(image)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
+
+/*
+POST /Printer/{id}/connect (204, 403, 500) -- connects the printer with {id} to its backend
+*/
+func (ph PrinterHandler) connect(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	err := ph.Service.(PrinterServiceIface).ConnectPrinter(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GET /Printer/{id}/jobs (200, 500) -- lists the print jobs Ymir sent to the printer with {id}
+*/
+func (ph PrinterHandler) listJobs(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	jobs, err := ph.Service.(PrinterServiceIface).ListPrinterJobs(printerId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+/*
+GET /Printer/{id}/queue (200, 404, 500) -- gets the print queue of the printer with {id}
+*/
+func (ph PrinterHandler) getQueue(w http.ResponseWriter, r *http.Request) {
+	q, err := ph.Service.(PrinterServiceIface).GetQueue(chi.URLParam(r, "id"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+/*
+POST /Printer/{id}/queue [QueueAddRequest{}] (201, 400, 404, 500) -- adds a print file to the end of the queue
+*/
+func (ph PrinterHandler) addToQueue(w http.ResponseWriter, r *http.Request) {
+	var req types.QueueAddRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.File == "" {
+		http.Error(w, "missing print file", http.StatusBadRequest)
+		return
+	}
+	q, err := ph.Service.(PrinterServiceIface).AddToQueue(chi.URLParam(r, "id"), req.File, req.Override, api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, q)
+}
+
+/*
+PUT /Printer/{id}/queue [QueueOrderRequest{}] (200, 400, 404, 500) -- reorders the queue
+*/
+func (ph PrinterHandler) reorderQueue(w http.ResponseWriter, r *http.Request) {
+	var req types.QueueOrderRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q, err := ph.Service.(PrinterServiceIface).ReorderQueue(chi.URLParam(r, "id"), req.Items)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+/*
+DELETE /Printer/{id}/queue/{itemId} (200, 404, 500) -- removes an item from the queue
+*/
+func (ph PrinterHandler) removeFromQueue(w http.ResponseWriter, r *http.Request) {
+	q, err := ph.Service.(PrinterServiceIface).RemoveFromQueue(chi.URLParam(r, "id"), chi.URLParam(r, "itemId"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+/*
+POST /Printer/{id}/queue/bedclear (200, 404, 500) -- confirms the bed is clear so the next queued file can start
+*/
+func (ph PrinterHandler) confirmBedClear(w http.ResponseWriter, r *http.Request) {
+	q, err := ph.Service.(PrinterServiceIface).ConfirmBedClear(chi.URLParam(r, "id"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+/*
+POST /Printer/{id}/control [ControlCommand{}] (200, 400, 404, 409, 502) -- starts, pauses, resumes or cancels the job,
+deletes a file, sets temperatures, homes, jogs, sets the fan or sends G-code on the printer with {id}. The command and its result are added
+to the printer's audit trail and returned.
+*/
+func (ph PrinterHandler) control(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	var cmd types.ControlCommand
+	err := json.NewDecoder(r.Body).Decode(&cmd)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, err := ph.Service.(PrinterServiceIface).Control(printerId, cmd, api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+/*
+PUT /Printer/{id}/spool [SpoolAssignment{}] (200, 400, 404, 500) -- loads a spool into the printer with {id}
+*/
+func (ph PrinterHandler) assignSpool(w http.ResponseWriter, r *http.Request) {
+	var assignment types.SpoolAssignment
+	err := json.NewDecoder(r.Body).Decode(&assignment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	printer, err := ph.Service.(PrinterServiceIface).AssignSpool(chi.URLParam(r, "id"), assignment.SpoolId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, printer)
+}
+
+/*
+PUT /Printer/{id}/location [LocationAssignment{}] (200, 400, 404, 500) -- places the printer with {id} in a location,
+an empty locationId takes it out
+*/
+func (ph PrinterHandler) placePrinter(w http.ResponseWriter, r *http.Request) {
+	var assignment types.LocationAssignment
+	err := json.NewDecoder(r.Body).Decode(&assignment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	printer, err := ph.Service.(PrinterServiceIface).PlacePrinter(chi.URLParam(r, "id"), assignment.LocationId)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, printer.Redacted())
+}
+
+/*
+GET /Printer/locations (200, 500) -- lists the locations printers can be placed in, with their paths
+*/
+func (ph PrinterHandler) listLocations(w http.ResponseWriter, r *http.Request) {
+	locations, err := ph.Service.(PrinterServiceIface).ListLocations()
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, locations)
+}
+
+/*
+POST /Printer/locations [Location{}] (201, 400, 500) -- adds a location, inside the one with parentId if set
+*/
+func (ph PrinterHandler) createLocation(w http.ResponseWriter, r *http.Request) {
+	var location types.Location
+	err := json.NewDecoder(r.Body).Decode(&location)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	location, err = ph.Service.(PrinterServiceIface).CreateLocation(location)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, location)
+}
+
+/*
+PUT /Printer/locations/{locationId} [Location{}] (200, 400, 404, 500) -- renames or moves a location
+*/
+func (ph PrinterHandler) updateLocation(w http.ResponseWriter, r *http.Request) {
+	var location types.Location
+	err := json.NewDecoder(r.Body).Decode(&location)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	location.Id = chi.URLParam(r, "locationId")
+	location, err = ph.Service.(PrinterServiceIface).UpdateLocation(location)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, location)
+}
+
+/*
+DELETE /Printer/locations/{locationId} (204, 404, 409, 500) -- removes a location with no printers or locations in it
+*/
+func (ph PrinterHandler) deleteLocation(w http.ResponseWriter, r *http.Request) {
+	err := ph.Service.(PrinterServiceIface).DeleteLocation(chi.URLParam(r, "locationId"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GET /Printer/{id}/usage (200, 404, 500) -- adds up the print hours, filament and jobs of the printer with {id}
+*/
+func (ph PrinterHandler) getUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := ph.Service.(PrinterServiceIface).GetUsage(chi.URLParam(r, "id"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+/*
+GET /Printer/{id}/maintenance (200, 404, 500) -- lists the maintenance tasks of the printer with {id}, overdue ones first
+*/
+func (ph PrinterHandler) listMaintenance(w http.ResponseWriter, r *http.Request) {
+	tasks, err := ph.Service.(PrinterServiceIface).ListMaintenance(chi.URLParam(r, "id"), false)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+/*
+GET /Printer/{id}/maintenance/overdue (200, 404, 500) -- lists the overdue maintenance tasks of the printer with {id}
+*/
+func (ph PrinterHandler) listOverdueMaintenance(w http.ResponseWriter, r *http.Request) {
+	tasks, err := ph.Service.(PrinterServiceIface).ListMaintenance(chi.URLParam(r, "id"), true)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+/*
+POST /Printer/{id}/maintenance [MaintenanceTask{}] (201, 400, 404, 500) -- adds a maintenance task to the printer with {id}
+*/
+func (ph PrinterHandler) createMaintenanceTask(w http.ResponseWriter, r *http.Request) {
+	var task types.MaintenanceTask
+	err := json.NewDecoder(r.Body).Decode(&task)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	task, err = ph.Service.(PrinterServiceIface).CreateMaintenanceTask(chi.URLParam(r, "id"), task, api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, task)
+}
+
+/*
+PUT /Printer/{id}/maintenance/{taskId} [MaintenanceTask{}] (200, 400, 404, 500) -- changes a maintenance task's name and intervals
+*/
+func (ph PrinterHandler) updateMaintenanceTask(w http.ResponseWriter, r *http.Request) {
+	var task types.MaintenanceTask
+	err := json.NewDecoder(r.Body).Decode(&task)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	task.Id = chi.URLParam(r, "taskId")
+	task, err = ph.Service.(PrinterServiceIface).UpdateMaintenanceTask(chi.URLParam(r, "id"), task)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+/*
+DELETE /Printer/{id}/maintenance/{taskId} (204, 404, 500) -- removes a maintenance task
+*/
+func (ph PrinterHandler) deleteMaintenanceTask(w http.ResponseWriter, r *http.Request) {
+	err := ph.Service.(PrinterServiceIface).DeleteMaintenanceTask(chi.URLParam(r, "id"), chi.URLParam(r, "taskId"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+POST /Printer/{id}/maintenance/{taskId}/done [MaintenanceDone{}] (200, 400, 404, 500) -- records a maintenance task as done
+*/
+func (ph PrinterHandler) completeMaintenanceTask(w http.ResponseWriter, r *http.Request) {
+	var done types.MaintenanceDone
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&done); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	status, err := ph.Service.(PrinterServiceIface).CompleteMaintenanceTask(chi.URLParam(r, "id"), chi.URLParam(r, "taskId"),
+		done.Notes, api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+/*
+GET /Printer/{id}/control/audit (200, 404, 500) -- lists the control commands sent to the printer with {id}, newest first
+*/
+func (ph PrinterHandler) listAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := ph.Service.(PrinterServiceIface).ListAudit(chi.URLParam(r, "id"))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+/*
+POST /Printer/{id}/command [CommandRequest{}] (204, 400, 403, 404, 409, 500) -- sends G-code commands to the printer with {id}.
+The commands are added to the printer's audit trail like those sent to /Printer/{id}/control.
+*/
+func (ph PrinterHandler) command(w http.ResponseWriter, r *http.Request) {
+	printerId := chi.URLParam(r, "id")
+	var cmd types.CommandRequest
+	err := json.NewDecoder(r.Body).Decode(&cmd)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(cmd.Commands) == 0 {
+		http.Error(w, "no commands given", http.StatusBadRequest)
+		return
+	}
+	_, err = ph.Service.(PrinterServiceIface).Control(printerId, types.ControlCommand{Command: types.CONTROL_GCODE, Commands: cmd.Commands}, api.RequestUser(r))
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+proxyError passes the printer's own status code through when it rejected the request
+*/
+func proxyError(w http.ResponseWriter, err error) {
+	var statusErr driver.StatusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &statusErr):
+		http.Error(w, statusErr.Error(), statusErr.HTTPStatus())
+	case errors.Is(err, ErrInvalidQueueOrder), errors.Is(err, ErrInvalidControl), errors.Is(err, ErrInvalidTask), errors.Is(err, ErrInvalidLocation), errors.Is(err, types.ErrSecretsRequired), errors.Is(err, os.ErrNotExist),
+		errors.Is(err, driver.ErrUnknownAPIType), errors.Is(err, driver.ErrUnknownStorage), errors.Is(err, webcam.ErrInvalidURL):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrPrinterNotFound), errors.Is(err, ErrQueueItemNotFound), errors.Is(err, ErrNoWebcam),
+		errors.Is(err, spoolstore.ErrSpoolNotFound), errors.Is(err, store.ErrTaskNotFound), errors.Is(err, store.ErrLocationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrLocationInUse), errors.Is(err, ErrPrinterNotIdle):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, driver.ErrNotSupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case errors.Is(err, ErrPollerNotRunning):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.As(err, &netErr):
+		// the printer could not be reached
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(js)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
+
 func (ph PrinterHandler) corsPreflightHandler(w http.ResponseWriter, r *http.Request) {
 	log.Info("CORS Request")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
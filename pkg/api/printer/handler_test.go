@@ -1,6 +1,7 @@
 package printer
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,13 +9,19 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"ymir/pkg/api"
+	jobtypes "ymir/pkg/api/job/types"
 	"ymir/pkg/api/printer/types"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/secrets"
 )
 
 type PrinterHandlerTestSuite struct {
@@ -74,6 +81,53 @@ func (suite *PrinterHandlerTestSuite) TestPrinterHandler_CreatePrinter_Good() {
 	assert.Equal(suite.T(), "\"{'status': 'ok'}\"\n", rr.Body.String(), "response should be JSON")
 }
 
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Test() {
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"ok", `{"url": "octopi.local", "apiKey": "ABC123", "type": {"Make": "Prusa"}}`, http.StatusOK},
+		{"bad api key", `{"url": "octopi.local", "apiKey": "typo"}`, http.StatusForbidden},
+		{"unreachable", `{"url": "http://unreachable", "apiKey": "ABC123"}`, http.StatusBadGateway},
+		{"bad body", `{`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/printer/test", strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		suite.handler.test(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+		if tt.code == http.StatusOK {
+			result := TestResult{}
+			assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &result))
+			assert.Equal(suite.T(), "1.9.3", result.ServerVersion)
+			assert.Equal(suite.T(), types.PrinterType{Make: "Prusa", Model: "Original Prusa i3 MK3S"}, result.Type)
+		}
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_CreatePrinter_UnknownProfile() {
+	body := &bytes.Buffer{}
+	multipartWriter := multipart.NewWriter(body)
+	_ = multipartWriter.WriteField("printerName", "TestPrinter")
+	_ = multipartWriter.WriteField("profile", "acme-printomatic")
+	_ = multipartWriter.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/printer", body)
+	req.Header.Set("content-type", multipartWriter.FormDataContentType())
+	rr := httptest.NewRecorder()
+	suite.handler.create(rr, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_ListProfiles() {
+	rr := httptest.NewRecorder()
+	suite.handler.listProfiles(rr, httptest.NewRequest(http.MethodGet, "/printer/profiles", nil))
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	var catalog []types.Profile
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &catalog))
+	assert.NotEmpty(suite.T(), catalog)
+}
+
 func (suite *PrinterHandlerTestSuite) TestPrinterHandler_CreatePrinter_Bad() {
 	pipeReader, pipeWriter := io.Pipe()
 	multipartWriter := multipart.NewWriter(pipeWriter)
@@ -125,6 +179,11 @@ func (suite *PrinterHandlerTestSuite) TestPrinterHandler_ListAll() {
 
 	assert.Len(suite.T(), printers, 2, "should be 2 printers")
 	assert.NotNil(suite.T(), printers, "should not be nil")
+	assert.NotContains(suite.T(), rr.Body.String(), "ABC123", "api keys should be redacted")
+	for _, printer := range printers {
+		assert.True(suite.T(), printer.HasKey)
+		assert.Equal(suite.T(), secrets.MASK, printer.APIKey)
+	}
 }
 
 func (suite *PrinterHandlerTestSuite) TestPrinterHandler_InspectPrinter() {
@@ -153,6 +212,8 @@ func (suite *PrinterHandlerTestSuite) TestPrinterHandler_InspectPrinter() {
 	assert.NotNil(suite.T(), printer, "should not be nil")
 	assert.Equal(suite.T(), id, printer.Id, "test-0")
 	assert.Equal(suite.T(), "test_1", printer.PrinterName, "name should be test_1")
+	assert.True(suite.T(), printer.HasKey)
+	assert.Equal(suite.T(), secrets.MASK, printer.APIKey, "api key should be redacted")
 }
 
 // DELETE /printer/{id}?rev
@@ -174,6 +235,389 @@ func (suite *PrinterHandlerTestSuite) TestPrinterHandler_DeletePrinter() {
 
 }
 
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_GetState() {
+	for _, tt := range []struct {
+		id   string
+		code int
+	}{
+		{"test-0", http.StatusOK},
+		{"offline", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/printer/{id}/state", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", tt.id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.getState(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, "printer status code should be passed through")
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_ListStatus() {
+	req := httptest.NewRequest(http.MethodGet, "/printer/status", nil)
+	rr := httptest.NewRecorder()
+	suite.handler.listStatus(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	statuses := map[string]poller.PrinterStatus{}
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &statuses))
+	assert.Equal(suite.T(), driver.STATE_IDLE, statuses["test-0"].State)
+	assert.Contains(suite.T(), rr.Body.String(), `"state":"idle"`, "status should be flattened into the cached entry")
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_GetJob() {
+	req := httptest.NewRequest(http.MethodGet, "/printer/{id}/job", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "test-0")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	suite.handler.getJob(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	var job driver.Job
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &job))
+	assert.Equal(suite.T(), 50.0, job.Completion)
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_ListJobs() {
+	req := httptest.NewRequest(http.MethodGet, "/printer/{id}/jobs", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "test-0")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	suite.handler.listJobs(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	jobs := map[string]jobtypes.Job{}
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &jobs))
+	assert.Equal(suite.T(), "test-0", jobs["job-0"].PrinterId)
+	assert.Equal(suite.T(), 12.5, jobs["job-0"].FilamentUsedG)
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_AssignSpool() {
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"assign", `{"spoolId": "s1"}`, http.StatusOK},
+		{"unassign", `{"spoolId": ""}`, http.StatusOK},
+		{"missing spool", `{"spoolId": "missing"}`, http.StatusNotFound},
+		{"bad body", `{`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/printer/{id}/spool", strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "test-0")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.assignSpool(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Locations() {
+	request := func(method string, target string, body string, params map[string]string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		for k, v := range params {
+			rctx.URLParams.Add(k, v)
+		}
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	rr := httptest.NewRecorder()
+	suite.handler.listLocations(rr, request(http.MethodGet, "/printer/locations", "", nil))
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	var locations []types.Location
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &locations))
+	assert.Len(suite.T(), locations, 2)
+
+	var printers map[string]types.Printer
+	rr = httptest.NewRecorder()
+	suite.handler.listAll(rr, request(http.MethodGet, "/printer?location=&tag=", "", nil))
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &printers))
+	assert.Len(suite.T(), printers, 2)
+	rr = httptest.NewRecorder()
+	suite.handler.listAll(rr, request(http.MethodGet, "/printer?tag=petg", "", nil))
+	printers = nil
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &printers))
+	assert.Empty(suite.T(), printers)
+	rr = httptest.NewRecorder()
+	suite.handler.listAll(rr, request(http.MethodGet, "/printer?location=Garage", "", nil))
+	printers = nil
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &printers))
+	assert.Empty(suite.T(), printers)
+
+	for _, tt := range []struct {
+		name   string
+		handle http.HandlerFunc
+		method string
+		body   string
+		params map[string]string
+		code   int
+	}{
+		{"create", suite.handler.createLocation, http.MethodPost, `{"name": "Shelf 1", "parentId": "r2"}`, nil, http.StatusCreated},
+		{"create without name", suite.handler.createLocation, http.MethodPost, `{"kind": "shelf"}`, nil, http.StatusBadRequest},
+		{"update", suite.handler.updateLocation, http.MethodPut, `{"name": "Lab"}`, map[string]string{"locationId": "r2"}, http.StatusOK},
+		{"update missing", suite.handler.updateLocation, http.MethodPut, `{"name": "Lab"}`, map[string]string{"locationId": "missing"}, http.StatusNotFound},
+		{"delete", suite.handler.deleteLocation, http.MethodDelete, "", map[string]string{"locationId": "r2"}, http.StatusNoContent},
+		{"delete in use", suite.handler.deleteLocation, http.MethodDelete, "", map[string]string{"locationId": "a"}, http.StatusConflict},
+		{"place", suite.handler.placePrinter, http.MethodPut, `{"locationId": "r2"}`, map[string]string{"id": "test-0"}, http.StatusOK},
+		{"place missing", suite.handler.placePrinter, http.MethodPut, `{"locationId": "missing"}`, map[string]string{"id": "test-0"}, http.StatusNotFound},
+		{"place bad body", suite.handler.placePrinter, http.MethodPut, `{`, map[string]string{"id": "test-0"}, http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		tt.handle(rr, request(tt.method, "/printer/locations", tt.body, tt.params))
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Maintenance() {
+	request := func(method string, body string, taskId string) *http.Request {
+		req := httptest.NewRequest(method, "/printer/{id}/maintenance?user=alice", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "test-0")
+		rctx.URLParams.Add("taskId", taskId)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	rr := httptest.NewRecorder()
+	suite.handler.listMaintenance(rr, request(http.MethodGet, "", ""))
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	var tasks []types.MaintenanceStatus
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &tasks))
+	assert.Len(suite.T(), tasks, 2)
+
+	rr = httptest.NewRecorder()
+	suite.handler.listOverdueMaintenance(rr, request(http.MethodGet, "", ""))
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &tasks))
+	assert.Len(suite.T(), tasks, 1)
+	assert.Equal(suite.T(), "Change nozzle", tasks[0].Name)
+	assert.NotEmpty(suite.T(), tasks[0].Reasons)
+
+	rr = httptest.NewRecorder()
+	suite.handler.getUsage(rr, request(http.MethodGet, "", ""))
+	var usage types.Usage
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &usage))
+	assert.Equal(suite.T(), 140, usage.Jobs)
+
+	for _, tt := range []struct {
+		name   string
+		handle http.HandlerFunc
+		method string
+		body   string
+		taskId string
+		code   int
+	}{
+		{"create", suite.handler.createMaintenanceTask, http.MethodPost, `{"name": "Clean rods", "intervalJobs": 50}`, "", http.StatusCreated},
+		{"create without interval", suite.handler.createMaintenanceTask, http.MethodPost, `{"name": "Clean rods"}`, "", http.StatusBadRequest},
+		{"update", suite.handler.updateMaintenanceTask, http.MethodPut, `{"name": "Clean rods", "intervalJobs": 40}`, "task-2", http.StatusOK},
+		{"update missing", suite.handler.updateMaintenanceTask, http.MethodPut, `{"name": "Clean rods", "intervalJobs": 40}`, "missing", http.StatusNotFound},
+		{"done", suite.handler.completeMaintenanceTask, http.MethodPost, `{"notes": "new 0.4 nozzle"}`, "task-0", http.StatusOK},
+		{"done without notes", suite.handler.completeMaintenanceTask, http.MethodPost, "", "task-0", http.StatusOK},
+		{"done missing", suite.handler.completeMaintenanceTask, http.MethodPost, "", "missing", http.StatusNotFound},
+		{"delete", suite.handler.deleteMaintenanceTask, http.MethodDelete, "", "task-0", http.StatusNoContent},
+		{"delete missing", suite.handler.deleteMaintenanceTask, http.MethodDelete, "", "missing", http.StatusNotFound},
+	} {
+		rr := httptest.NewRecorder()
+		tt.handle(rr, request(tt.method, tt.body, tt.taskId))
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Files() {
+	req := httptest.NewRequest(http.MethodGet, "/printer/{id}/files", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "test-0")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	suite.handler.listFiles(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	var files []driver.File
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &files))
+	assert.Len(suite.T(), files, 2)
+	assert.Equal(suite.T(), "sdcard", files[1].Location)
+
+	for _, tt := range []struct {
+		name     string
+		path     string
+		location string
+		code     int
+	}{
+		{"escaped path", "ymir%2Fbenchy.gcode", "", http.StatusNoContent},
+		{"sd card", "CUBE.GCO", "sdcard", http.StatusNoContent},
+		{"missing", "missing.gcode", "", http.StatusNotFound},
+		{"unknown storage", "CUBE.GCO", "usb", http.StatusBadRequest},
+	} {
+		for method, handle := range map[string]http.HandlerFunc{
+			http.MethodDelete: suite.handler.deleteFile,
+			http.MethodPost:   suite.handler.printFile,
+		} {
+			req := httptest.NewRequest(method, "/printer/{id}/files/{path}?location="+tt.location, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "test-0")
+			rctx.URLParams.Add("path", tt.path)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handle(rr, req)
+			assert.Equal(suite.T(), tt.code, rr.Code, method+" "+tt.name)
+		}
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Control() {
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+		want []string
+	}{
+		{"Jog", `{"command":"jog","x":-10}`, http.StatusOK, []string{"G91", "G1 X-10 F3000", "G90"}},
+		{"Pause", `{"command":"pause"}`, http.StatusOK, nil},
+		{"Invalid", `{"command":"fan"}`, http.StatusBadRequest, nil},
+		{"Bad Body", `{"command":`, http.StatusBadRequest, nil},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/printer/{id}/control?user=alice", strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "test-0")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.control(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+		if tt.code == http.StatusOK {
+			var entry types.AuditEntry
+			assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &entry))
+			assert.Equal(suite.T(), "alice (unverified, 192.0.2.1)", entry.User, tt.name)
+			assert.Equal(suite.T(), tt.want, entry.GCode, tt.name)
+		}
+	}
+
+	// a verified token says who it is, whatever the query says
+	token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"sub": "carol"})
+	assert.NoError(suite.T(), err)
+	req := httptest.NewRequest(http.MethodPost, "/printer/{id}/control?user=alice", strings.NewReader(`{"command":"pause"}`))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "test-0")
+	req = req.WithContext(jwtauth.NewContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx), token, nil))
+	rr := httptest.NewRecorder()
+	suite.handler.control(rr, req)
+	var entry types.AuditEntry
+	assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &entry))
+	assert.Equal(suite.T(), "carol", entry.User)
+
+	req = httptest.NewRequest(http.MethodGet, "/printer/{id}/control/audit", nil)
+	rctx = chi.NewRouteContext()
+	rctx.URLParams.Add("id", "test-0")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr = httptest.NewRecorder()
+	suite.handler.listAudit(rr, req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Contains(suite.T(), rr.Body.String(), `"user":"alice"`)
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_WebcamSnapshot() {
+	for _, tt := range []struct {
+		id   string
+		code int
+	}{
+		{"test-0", http.StatusOK},
+		{"no-webcam", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/printer/{id}/webcam/snapshot", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", tt.id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.getWebcamSnapshot(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.id)
+		if tt.code == http.StatusOK {
+			assert.Equal(suite.T(), "image/jpeg", rr.Header().Get("Content-Type"))
+			assert.Equal(suite.T(), []byte{0xff, 0xd8, 0xff, 0xd9}, rr.Body.Bytes())
+		}
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Queue() {
+	for _, tt := range []struct {
+		name    string
+		method  string
+		id      string
+		itemId  string
+		body    string
+		handler http.HandlerFunc
+		code    int
+	}{
+		{"get", http.MethodGet, "test-0", "", "", suite.handler.getQueue, http.StatusOK},
+		{"get unknown printer", http.MethodGet, "nope", "", "", suite.handler.getQueue, http.StatusNotFound},
+		{"add", http.MethodPost, "test-0", "", `{"file": "benchy.gcode"}`, suite.handler.addToQueue, http.StatusCreated},
+		{"add without file", http.MethodPost, "test-0", "", `{}`, suite.handler.addToQueue, http.StatusBadRequest},
+		{"reorder", http.MethodPut, "test-0", "", `{"items": ["item-0"]}`, suite.handler.reorderQueue, http.StatusOK},
+		{"reorder missing items", http.MethodPut, "test-0", "", `{"items": []}`, suite.handler.reorderQueue, http.StatusBadRequest},
+		{"remove", http.MethodDelete, "test-0", "item-0", "", suite.handler.removeFromQueue, http.StatusOK},
+		{"remove unknown item", http.MethodDelete, "test-0", "item-9", "", suite.handler.removeFromQueue, http.StatusNotFound},
+		{"bed clear", http.MethodPost, "test-0", "", "", suite.handler.confirmBedClear, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, "/printer/{id}/queue?user=bob", strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", tt.id)
+		rctx.URLParams.Add("itemId", tt.itemId)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		tt.handler(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.name)
+		if tt.code < 300 {
+			q := types.Queue{}
+			assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &q), tt.name)
+			assert.Equal(suite.T(), tt.id, q.PrinterId, tt.name)
+		}
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_JobCommand() {
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"command": "cancel"}`, http.StatusNoContent},
+		{`{"command": "explode"}`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/printer/{id}/job", strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "test-0")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.jobCommand(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.body)
+	}
+}
+
+func (suite *PrinterHandlerTestSuite) TestPrinterHandler_Command() {
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"commands": ["M104 S0", "M140 S0"]}`, http.StatusNoContent},
+		{`{"commands": []}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/printer/{id}/command", strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "test-0")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		suite.handler.command(rr, req)
+		assert.Equal(suite.T(), tt.code, rr.Code, tt.body)
+	}
+}
+
 func TestPrinterHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(PrinterHandlerTestSuite))
 }
@@ -1,8 +1,20 @@
 package printer
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
 	"github.com/stretchr/testify/mock"
+	jobtypes "ymir/pkg/api/job/types"
+	"ymir/pkg/api/printer/store"
 	"ymir/pkg/api/printer/types"
+	spoolstore "ymir/pkg/api/spool/store"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/octoprint"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/printer/profiles"
 )
 
 // MockPrinterService is a mock implementation of the Service interface for testing.
@@ -22,11 +34,13 @@ func (m *MockPrinterService) CreatePrinter(types.Printer) (string, error) {
 	return "", nil
 }
 
-func (m *MockPrinterService) ListPrinters() (map[string]types.Printer, error) {
+func (m *MockPrinterService) ListPrinters(filter types.PrinterFilter) (map[string]types.Printer, error) {
 	// Simulate returning a list of printers for testing.
 	printers := map[string]types.Printer{}
 	for i := 0; i < len(m.printers); i++ {
-		printers[m.printers[i].Id] = m.printers[i]
+		if filter.Matches(m.printers[i], map[string]types.Location{}) {
+			printers[m.printers[i].Id] = m.printers[i]
+		}
 	}
 	return printers, nil
 }
@@ -43,6 +57,230 @@ func (m *MockPrinterService) DeletePrinter(id string) error {
 	return nil
 }
 
+func (m *MockPrinterService) GetPrinterStatus(id string) (driver.Status, error) {
+	if id == "offline" {
+		return driver.Status{}, &octoprint.APIError{StatusCode: http.StatusForbidden, Status: "403 FORBIDDEN"}
+	}
+	return driver.Status{State: driver.STATE_PRINTING, Text: "Printing", Job: &driver.Job{File: "ymir/test.gcode", Completion: 50}}, nil
+}
+
+func (m *MockPrinterService) ConnectPrinter(id string) error {
+	return nil
+}
+
+func (m *MockPrinterService) ListPrinterStatus() (map[string]poller.PrinterStatus, error) {
+	return map[string]poller.PrinterStatus{
+		"test-0": {Id: "test-0", PrinterName: "test-0", Status: driver.Status{State: driver.STATE_IDLE, Text: "Operational"}},
+	}, nil
+}
+
+func (m *MockPrinterService) ListPrinterJobs(id string) (map[string]jobtypes.Job, error) {
+	return map[string]jobtypes.Job{
+		"job-0": {Id: "job-0", PrinterId: id, File: "cube.gcode", State: jobtypes.JOB_STATE_COMPLETED, FilamentUsedG: 12.5},
+	}, nil
+}
+
+func (m *MockPrinterService) GetQueue(id string) (types.Queue, error) {
+	if id != "test-0" {
+		return types.Queue{}, ErrPrinterNotFound
+	}
+	return types.Queue{PrinterId: id, Items: []types.QueueItem{{Id: "item-0", File: "cube.gcode"}}}, nil
+}
+
+func (m *MockPrinterService) AddToQueue(id string, file string, override bool, user string) (types.Queue, error) {
+	q, err := m.GetQueue(id)
+	q.Items = append(q.Items, types.QueueItem{Id: "item-1", File: file, User: user})
+	return q, err
+}
+
+func (m *MockPrinterService) ReorderQueue(id string, itemIds []string) (types.Queue, error) {
+	if len(itemIds) != 1 {
+		return types.Queue{}, ErrInvalidQueueOrder
+	}
+	return m.GetQueue(id)
+}
+
+func (m *MockPrinterService) RemoveFromQueue(id string, itemId string) (types.Queue, error) {
+	if itemId != "item-0" {
+		return types.Queue{}, ErrQueueItemNotFound
+	}
+	return types.Queue{PrinterId: id, Items: []types.QueueItem{}}, nil
+}
+
+func (m *MockPrinterService) ConfirmBedClear(id string) (types.Queue, error) {
+	q, err := m.GetQueue(id)
+	q.BedClear = true
+	return q, err
+}
+
+func (m *MockPrinterService) TestPrinter(p types.Printer) (TestResult, error) {
+	switch {
+	case p.URL == "http://unreachable":
+		return TestResult{}, &net.DNSError{Err: "no such host", Name: "unreachable"}
+	case p.APIKey != "ABC123":
+		return TestResult{}, &octoprint.APIError{StatusCode: http.StatusForbidden, Status: "403 FORBIDDEN"}
+	}
+	p.Type.Model = "Original Prusa i3 MK3S"
+	return TestResult{Info: driver.Info{Server: "OctoPrint", ServerVersion: "1.9.3"}, Type: p.Type}, nil
+}
+
+func (m *MockPrinterService) GetWebcamSnapshot(id string) ([]byte, string, error) {
+	if id == "no-webcam" {
+		return nil, "", ErrNoWebcam
+	}
+	return []byte{0xff, 0xd8, 0xff, 0xd9}, "image/jpeg", nil
+}
+
+func (m *MockPrinterService) ListPrinterFiles(id string) ([]driver.File, error) {
+	return []driver.File{
+		{Name: "benchy.gcode", Path: "ymir/benchy.gcode", Location: "local", Size: 1024},
+		{Name: "CUBE.GCO", Path: "CUBE.GCO", Location: "sdcard", Size: 2048},
+	}, nil
+}
+
+func (m *MockPrinterService) DeletePrinterFile(id string, location string, path string, user string) error {
+	return m.fileCommand(location, path)
+}
+
+func (m *MockPrinterService) PrintPrinterFile(id string, location string, path string, user string) error {
+	return m.fileCommand(location, path)
+}
+
+func (m *MockPrinterService) fileCommand(location string, path string) error {
+	switch {
+	case location != "" && location != "local" && location != "sdcard":
+		return fmt.Errorf("%w: %v", driver.ErrUnknownStorage, location)
+	case path != "ymir/benchy.gcode" && path != "CUBE.GCO":
+		return &octoprint.APIError{StatusCode: http.StatusNotFound, Status: "404 NOT FOUND"}
+	}
+	return nil
+}
+
+func (m *MockPrinterService) Control(id string, cmd types.ControlCommand, user string) (types.AuditEntry, error) {
+	entry := types.AuditEntry{Id: "audit-0", PrinterId: id, User: user, Command: cmd, Result: types.AUDIT_OK}
+	switch cmd.Command {
+	case types.CONTROL_PAUSE, types.CONTROL_RESUME, types.CONTROL_CANCEL, types.CONTROL_START:
+		return entry, nil
+	}
+	gcode, err := controlGCode(cmd, nil)
+	if err != nil {
+		return entry, err
+	}
+	entry.GCode = gcode
+	return entry, nil
+}
+
+func (m *MockPrinterService) ListAudit(id string) ([]types.AuditEntry, error) {
+	return []types.AuditEntry{
+		{Id: "audit-1", PrinterId: id, User: "alice", Command: types.ControlCommand{Command: types.CONTROL_CANCEL}, Result: types.AUDIT_OK},
+	}, nil
+}
+
+func (m *MockPrinterService) AssignSpool(id string, spoolId string) (types.Printer, error) {
+	if spoolId == "missing" {
+		return types.Printer{}, spoolstore.ErrSpoolNotFound
+	}
+	p := m.printers[0]
+	p.SpoolId = spoolId
+	return p, nil
+}
+
+func (m *MockPrinterService) PlacePrinter(id string, locationId string) (types.Printer, error) {
+	if locationId == "missing" {
+		return types.Printer{}, store.ErrLocationNotFound
+	}
+	p := m.printers[0]
+	p.Location = types.Location{Id: locationId, Name: "Room 2", Path: "Building A / Room 2"}
+	return p, nil
+}
+
+func (m *MockPrinterService) ListLocations() ([]types.Location, error) {
+	return []types.Location{
+		{Id: "a", Name: "Building A", Kind: "site", Path: "Building A"},
+		{Id: "r2", Name: "Room 2", ParentId: "a", Path: "Building A / Room 2"},
+	}, nil
+}
+
+func (m *MockPrinterService) CreateLocation(location types.Location) (types.Location, error) {
+	if location.Name == "" {
+		return types.Location{}, ErrInvalidLocation
+	}
+	location.Id = "new"
+	return location, nil
+}
+
+func (m *MockPrinterService) UpdateLocation(location types.Location) (types.Location, error) {
+	if location.Id == "missing" {
+		return types.Location{}, store.ErrLocationNotFound
+	}
+	return m.CreateLocation(location)
+}
+
+func (m *MockPrinterService) DeleteLocation(id string) error {
+	switch id {
+	case "missing":
+		return store.ErrLocationNotFound
+	case "a":
+		return ErrLocationInUse
+	}
+	return nil
+}
+
+func (m *MockPrinterService) GetUsage(id string) (types.Usage, error) {
+	return types.Usage{PrintHours: 512.5, FilamentM: 1800, Jobs: 140}, nil
+}
+
+func (m *MockPrinterService) ListMaintenance(id string, overdueOnly bool) ([]types.MaintenanceStatus, error) {
+	usage, _ := m.GetUsage(id)
+	tasks := []types.MaintenanceStatus{}
+	for _, t := range []types.MaintenanceTask{
+		{Id: "task-0", PrinterId: id, Name: "Change nozzle", IntervalHours: 500},
+		{Id: "task-1", PrinterId: id, Name: "Tension belts", IntervalMonths: 3, Created: types.MaintenanceRecord{Time: time.Now()}},
+	} {
+		if s := t.Status(usage, time.Now()); s.Overdue || !overdueOnly {
+			tasks = append(tasks, s)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MockPrinterService) CreateMaintenanceTask(id string, task types.MaintenanceTask, user string) (types.MaintenanceTask, error) {
+	if task.Name == "" || !task.HasInterval() {
+		return types.MaintenanceTask{}, ErrInvalidTask
+	}
+	task.Id = "task-2"
+	task.PrinterId = id
+	task.Created = types.MaintenanceRecord{Time: time.Now(), User: user}
+	return task, nil
+}
+
+func (m *MockPrinterService) UpdateMaintenanceTask(id string, task types.MaintenanceTask) (types.MaintenanceTask, error) {
+	if task.Id == "missing" {
+		return types.MaintenanceTask{}, store.ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (m *MockPrinterService) DeleteMaintenanceTask(id string, taskId string) error {
+	if taskId == "missing" {
+		return store.ErrTaskNotFound
+	}
+	return nil
+}
+
+func (m *MockPrinterService) CompleteMaintenanceTask(id string, taskId string, notes string, user string) (types.MaintenanceStatus, error) {
+	if taskId == "missing" {
+		return types.MaintenanceStatus{}, store.ErrTaskNotFound
+	}
+	task := types.MaintenanceTask{Id: taskId, PrinterId: id, Name: "Change nozzle", IntervalHours: 500}
+	task.History = []types.MaintenanceRecord{{Time: time.Now(), User: user, Notes: notes}}
+	return task.Status(types.Usage{}, time.Now()), nil
+}
+
+func (m *MockPrinterService) ListProfiles() []types.Profile {
+	return profiles.Catalog()
+}
+
 func (m *MockPrinterService) GetName() string {
 	return ""
 }
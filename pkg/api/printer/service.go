@@ -3,11 +3,28 @@ package printer
 import (
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"ymir/pkg/api"
+	jobs "ymir/pkg/api/job"
+	jobstore "ymir/pkg/api/job/store"
+	jobtypes "ymir/pkg/api/job/types"
 	"ymir/pkg/api/printer/store"
 	"ymir/pkg/api/printer/types"
+	spoolstore "ymir/pkg/api/spool/store"
+	"ymir/pkg/events"
+	driver "ymir/pkg/printer"
+	"ymir/pkg/printer/dispatcher"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/printer/profiles"
+	"ymir/pkg/printer/webcam"
 	"ymir/pkg/utils"
 )
 
@@ -17,13 +34,77 @@ type PrinterServiceIface interface {
 	UpdatePrinter(printer types.Printer) (err error)
 	DeletePrinter(id string) error
 	GetPrinter(id string) (types.Printer, error)
-	ListPrinters() (map[string]types.Printer, error)
+	ListPrinters(filter types.PrinterFilter) (map[string]types.Printer, error)
+	GetPrinterStatus(id string) (driver.Status, error)
+	ConnectPrinter(id string) error
+	ListPrinterStatus() (map[string]poller.PrinterStatus, error)
+	ListPrinterJobs(id string) (map[string]jobtypes.Job, error)
+	TestPrinter(printer types.Printer) (TestResult, error)
+	ListProfiles() []types.Profile
+	GetQueue(id string) (types.Queue, error)
+	AddToQueue(id string, file string, override bool, user string) (types.Queue, error)
+	ReorderQueue(id string, itemIds []string) (types.Queue, error)
+	RemoveFromQueue(id string, itemId string) (types.Queue, error)
+	ConfirmBedClear(id string) (types.Queue, error)
+	GetWebcamSnapshot(id string) ([]byte, string, error)
+	ListPrinterFiles(id string) ([]driver.File, error)
+	DeletePrinterFile(id string, location string, path string, user string) error
+	PrintPrinterFile(id string, location string, path string, user string) error
+	Control(id string, cmd types.ControlCommand, user string) (types.AuditEntry, error)
+	ListAudit(id string) ([]types.AuditEntry, error)
+	AssignSpool(id string, spoolId string) (types.Printer, error)
+	GetUsage(id string) (types.Usage, error)
+	ListMaintenance(id string, overdueOnly bool) ([]types.MaintenanceStatus, error)
+	CreateMaintenanceTask(id string, task types.MaintenanceTask, user string) (types.MaintenanceTask, error)
+	UpdateMaintenanceTask(id string, task types.MaintenanceTask) (types.MaintenanceTask, error)
+	DeleteMaintenanceTask(id string, taskId string) error
+	CompleteMaintenanceTask(id string, taskId string, notes string, user string) (types.MaintenanceStatus, error)
+	ListLocations() ([]types.Location, error)
+	CreateLocation(location types.Location) (types.Location, error)
+	UpdateLocation(location types.Location) (types.Location, error)
+	DeleteLocation(id string) error
+	PlacePrinter(id string, locationId string) (types.Printer, error)
+}
+
+const (
+	JOB_START  = "start"
+	JOB_PAUSE  = "pause"
+	JOB_RESUME = "resume"
+	JOB_CANCEL = "cancel"
+)
+
+var (
+	ErrPollerNotRunning  = errors.New("printer status poller is not running")
+	ErrPrinterNotFound   = errors.New("printer not found")
+	ErrPrinterNotIdle    = errors.New("printer is not idle")
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	ErrInvalidQueueOrder = errors.New("queue order must list every queued item once")
+	ErrNoWebcam          = errors.New("printer has no webcam snapshot url")
+	ErrInvalidControl    = errors.New("invalid control command")
+	ErrInvalidTask       = errors.New("maintenance task needs a name and an interval")
+	ErrInvalidLocation   = errors.New("invalid location")
+	ErrLocationInUse     = errors.New("location still has printers or locations in it")
+)
+
+/*
+TestResult is what the backend reported about a printer under test. Type is the printer's type with
+the blanks filled in from the backend.
+*/
+type TestResult struct {
+	driver.Info
+	Type types.PrinterType `json:"type"`
 }
 
 type PrinterService struct {
 	PrinterServiceIface
 	name         string
 	printerStore store.PrinterStoreIFace
+	jobStore     jobstore.JobStoreIFace
+	queueStore   store.QueueStoreIFace
+	auditStore   store.AuditStoreIFace
+	spoolStore   spoolstore.SpoolStoreIFace
+	maintStore   store.MaintenanceStoreIFace
+	locStore     store.LocationStoreIFace
 	config       *PrintersConfig
 }
 
@@ -32,6 +113,12 @@ func NewPrinterService() api.Service {
 		name:         "Printers",
 		config:       NewPrintersConfig(),
 		printerStore: store.NewPrinterDataStore(),
+		jobStore:     jobstore.NewJobDataStore(),
+		queueStore:   store.NewQueueDataStore(),
+		auditStore:   store.NewAuditDataStore(),
+		spoolStore:   spoolstore.NewSpoolDataStore(),
+		maintStore:   store.NewMaintenanceDataStore(),
+		locStore:     store.NewLocationDataStore(),
 	}
 
 	err := utils.MakeDirIfNotExists(ps.config.PrintersDir)
@@ -50,12 +137,28 @@ func (ps PrinterService) GetConfig() *PrintersConfig {
 	return ps.config
 }
 
-func (ps PrinterService) ListPrinters() (printers map[string]types.Printer, err error) {
+/*
+ListPrinters lists the printers matching filter, an empty filter lists them all
+*/
+func (ps PrinterService) ListPrinters(filter types.PrinterFilter) (printers map[string]types.Printer, err error) {
 	printers, err = ps.printerStore.List()
 	if err != nil {
 		log.Error(err)
 		return
 	}
+	locations, err := ps.locStore.List()
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	for id, p := range printers {
+		if !filter.Matches(p, locations) {
+			delete(printers, id)
+			continue
+		}
+		resolveLocation(&p, locations)
+		printers[id] = p
+	}
 	return
 }
 
@@ -65,15 +168,40 @@ func (ps PrinterService) GetPrinter(id string) (printer types.Printer, err error
 		log.Errorf("error retrieving printerl: %v", err)
 		return
 	} else if printer.Id == "" {
-		err = errors.New(fmt.Sprintf("printer with id: %v does not exist", id))
+		err = fmt.Errorf("printer with id: %v does not exist: %w", id, ErrPrinterNotFound)
+		return
+	}
+	locations, err := ps.locStore.List()
+	if err != nil {
+		return
 	}
+	resolveLocation(&printer, locations)
 	return
 }
 
+/*
+resolveLocation fills in the printer's location from the one it is placed in
+*/
+func resolveLocation(printer *types.Printer, locations map[string]types.Location) {
+	l, ok := locations[printer.Location.Id]
+	if !ok {
+		return
+	}
+	l.Path = types.LocationPath(l.Id, locations)
+	printer.Location = l
+}
+
+/*
+CreatePrinter saves a new printer. Printers without a profile get the catalog profile matching their make and model.
+*/
 func (ps PrinterService) CreatePrinter(printer types.Printer) (id string, err error) {
 	printer.Id = utils.GenId()
+	seedProfile(&printer)
+	if err = ps.placeIn(&printer, printer.Location.Id); err != nil {
+		return
+	}
 	if log.GetLevel() == log.DebugLevel {
-		fmt.Println(printer.Json())
+		fmt.Println(printer.Redacted().Json())
 	}
 
 	err = ps.printerStore.Create(printer)
@@ -86,7 +214,18 @@ func (ps PrinterService) CreatePrinter(printer types.Printer) (id string, err er
 	}
 }
 
+/*
+UpdatePrinter saves printer. A blank or masked API key or password leaves the stored one unchanged,
+unless the printer's address changed too.
+*/
 func (ps PrinterService) UpdatePrinter(printer types.Printer) (err error) {
+	if err = ps.keepSecrets(&printer); err != nil {
+		return
+	}
+	seedProfile(&printer)
+	if err = ps.placeIn(&printer, printer.Location.Id); err != nil {
+		return
+	}
 	err = ps.printerStore.Update(printer)
 	if err != nil {
 		log.Error(err)
@@ -102,6 +241,774 @@ func (ps PrinterService) DeletePrinter(id string) (err error) {
 	if err != nil {
 		return err
 	}
+	err = ps.queueStore.Delete(id)
+	if err != nil {
+		return err
+	}
+	tasks, err := ps.maintStore.ListByPrinter(id)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		if err := ps.maintStore.Delete(t.Id); err != nil {
+			return err
+		}
+	}
 	log.Infof("deleted printer %v in db", id)
 	return
 }
+
+/*
+driver returns the printer driver for the printer with {id} based on its APIType
+*/
+func (ps PrinterService) driver(id string) (driver.PrinterDriver, error) {
+	printer, err := ps.GetPrinter(id)
+	if err != nil {
+		return nil, err
+	}
+	return driver.NewDriver(printer)
+}
+
+func (ps PrinterService) GetPrinterStatus(id string) (status driver.Status, err error) {
+	d, err := ps.driver(id)
+	if err != nil {
+		return
+	}
+	status, err = d.Status()
+	if err != nil {
+		log.Errorf("error getting status for printer %v: %v", id, err)
+	}
+	return
+}
+
+func (ps PrinterService) ConnectPrinter(id string) (err error) {
+	d, err := ps.driver(id)
+	if err != nil {
+		return
+	}
+	err = d.Connect()
+	if err != nil {
+		log.Errorf("error connecting printer %v: %v", id, err)
+		return
+	}
+	log.Infof("connected printer %v", id)
+	return
+}
+
+/*
+jobControl translates the body of POST /printer/{id}/job into the control command it stands for
+*/
+func jobControl(cmd types.JobCommand) types.ControlCommand {
+	if cmd.Command == JOB_PAUSE && cmd.Action == JOB_RESUME {
+		return types.ControlCommand{Command: types.CONTROL_RESUME}
+	}
+	return types.ControlCommand{Command: cmd.Command, Path: cmd.Path, Location: cmd.Location}
+}
+
+/*
+ListPrinterFiles lists the print files on every storage of the printer, e.g. OctoPrint's uploads and the SD card
+*/
+func (ps PrinterService) ListPrinterFiles(id string) ([]driver.File, error) {
+	d, err := ps.driver(id)
+	if err != nil {
+		return nil, err
+	}
+	files, err := d.ListFiles()
+	if err != nil {
+		log.Errorf("error listing files on printer %v: %v", id, err)
+	}
+	return files, err
+}
+
+/*
+DeletePrinterFile deletes a file from the printer's storage. An empty location is the storage Ymir uploads to.
+It is audited like a control command.
+*/
+func (ps PrinterService) DeletePrinterFile(id string, location string, path string, user string) error {
+	_, err := ps.Control(id, types.ControlCommand{Command: types.CONTROL_DELETE, Path: path, Location: location}, user)
+	return err
+}
+
+/*
+PrintPrinterFile starts printing a file that is already on the printer. It is audited like a control command.
+*/
+func (ps PrinterService) PrintPrinterFile(id string, location string, path string, user string) error {
+	_, err := ps.Control(id, types.ControlCommand{Command: types.CONTROL_START, Path: path, Location: location}, user)
+	return err
+}
+
+/*
+start prints a file that is on the printer and records the job like an upload that starts printing does
+*/
+func (ps PrinterService) start(d driver.PrinterDriver, printer types.Printer, location string, path string, user string) error {
+	if err := d.Start(location, path); err != nil {
+		return err
+	}
+	j := jobtypes.Job{
+		File:        path,
+		PrinterId:   printer.Id,
+		PrinterName: printer.PrinterName,
+		User:        user,
+	}
+	if _, err := jobs.RecordStart(ps.jobStore, j); err != nil {
+		// the print has started, so don't fail over its history
+		log.Errorf("could not record job for %v on printer %v: %v", path, printer.PrinterName, err)
+	}
+	return nil
+}
+
+/*
+ListPrinterStatus returns the status of every printer as last seen by the background poller
+*/
+func (ps PrinterService) ListPrinterStatus() (statuses map[string]poller.PrinterStatus, err error) {
+	p := poller.Default()
+	if p == nil {
+		return nil, ErrPollerNotRunning
+	}
+	return p.Statuses(), nil
+}
+
+/*
+ListPrinterJobs lists the print jobs Ymir sent to the printer
+*/
+func (ps PrinterService) ListPrinterJobs(id string) (map[string]jobtypes.Job, error) {
+	return ps.jobStore.ListByPrinter(id)
+}
+
+func (ps PrinterService) GetQueue(id string) (types.Queue, error) {
+	if _, err := ps.GetPrinter(id); err != nil {
+		return types.Queue{}, err
+	}
+	return ps.queueStore.Get(id)
+}
+
+/*
+AddToQueue appends a print file to the printer's queue. With override it is printed even if it fails the preflight check.
+*/
+func (ps PrinterService) AddToQueue(id string, file string, override bool, user string) (types.Queue, error) {
+	if _, err := os.Stat(file); err != nil {
+		return types.Queue{}, err
+	}
+	item := types.QueueItem{
+		Id:        utils.GenId(),
+		File:      file,
+		User:      user,
+		DateAdded: time.Now(),
+		Override:  override,
+	}
+	return ps.modifyQueue(id, func(q *types.Queue) error {
+		q.Items = append(q.Items, item)
+		return nil
+	})
+}
+
+/*
+ReorderQueue puts the queued items in the order of itemIds, which must hold every item id once
+*/
+func (ps PrinterService) ReorderQueue(id string, itemIds []string) (types.Queue, error) {
+	return ps.modifyQueue(id, func(q *types.Queue) error {
+		if len(itemIds) != len(q.Items) {
+			return ErrInvalidQueueOrder
+		}
+		items := make(map[string]types.QueueItem, len(q.Items))
+		for _, item := range q.Items {
+			items[item.Id] = item
+		}
+		ordered := make([]types.QueueItem, 0, len(itemIds))
+		for _, itemId := range itemIds {
+			item, ok := items[itemId]
+			if !ok {
+				return ErrInvalidQueueOrder
+			}
+			delete(items, itemId)
+			ordered = append(ordered, item)
+		}
+		q.Items = ordered
+		return nil
+	})
+}
+
+func (ps PrinterService) RemoveFromQueue(id string, itemId string) (types.Queue, error) {
+	return ps.modifyQueue(id, func(q *types.Queue) error {
+		for i, item := range q.Items {
+			if item.Id == itemId {
+				q.Items = append(q.Items[:i], q.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrQueueItemNotFound
+	})
+}
+
+/*
+ConfirmBedClear lets the dispatcher start the next queued item. The poller has to have seen the printer idle,
+a bed can't be cleared in the middle of a print, and nothing may have been dispatched to it since.
+*/
+func (ps PrinterService) ConfirmBedClear(id string) (types.Queue, error) {
+	p := poller.Default()
+	if p == nil {
+		return types.Queue{}, ErrPollerNotRunning
+	}
+	if status, ok := p.Status(id); !ok || status.State != driver.STATE_IDLE {
+		return types.Queue{}, ErrPrinterNotIdle
+	}
+	if d := dispatcher.Default(); d != nil && d.Dispatched(id) {
+		return types.Queue{}, ErrPrinterNotIdle
+	}
+	return ps.modifyQueue(id, func(q *types.Queue) error {
+		q.BedClear = true
+		q.LastError = ""
+		return nil
+	})
+}
+
+/*
+modifyQueue changes the queue of an existing printer and tells the dispatcher and clients about it
+*/
+func (ps PrinterService) modifyQueue(id string, modify func(q *types.Queue) error) (types.Queue, error) {
+	if _, err := ps.GetPrinter(id); err != nil {
+		return types.Queue{}, err
+	}
+	q, err := ps.queueStore.Modify(id, modify)
+	if err != nil {
+		return types.Queue{}, err
+	}
+	events.Publish(events.Event{
+		Type:      events.QUEUE_CHANGED,
+		PrinterId: id,
+		Data:      q,
+	})
+	return q, nil
+}
+
+/*
+ListProfiles returns the catalog of printer profiles
+*/
+func (ps PrinterService) ListProfiles() []types.Profile {
+	return profiles.Catalog()
+}
+
+/*
+seedProfile gives a printer without a profile the catalog profile for its make and model, if there is one
+*/
+func seedProfile(printer *types.Printer) {
+	if printer.Profile != nil {
+		return
+	}
+	if profile, ok := profiles.Match(printer.Type); ok {
+		printer.Profile = &profile
+	}
+}
+
+/*
+keepSecrets fills in the stored secrets of a saved printer that a client sent back redacted
+*/
+func (ps PrinterService) keepSecrets(printer *types.Printer) error {
+	if printer.Id == "" {
+		return nil
+	}
+	stored, err := ps.printerStore.Inspect(printer.Id)
+	if err != nil || stored.Id == "" {
+		return nil
+	}
+	return printer.KeepSecrets(stored)
+}
+
+/*
+TestPrinter contacts the printer with the given settings, which need not be saved yet, to check the url and
+credentials and find out what it runs. A saved printer's secrets are only used at its saved address.
+*/
+func (ps PrinterService) TestPrinter(printer types.Printer) (TestResult, error) {
+	if err := ps.keepSecrets(&printer); err != nil {
+		return TestResult{}, err
+	}
+	d, err := driver.NewDriver(printer)
+	if err != nil {
+		return TestResult{}, err
+	}
+	info, err := d.Info()
+	if err != nil {
+		log.Infof("testing printer %v failed: %v", printer.PrinterName, err)
+		return TestResult{}, err
+	}
+	result := TestResult{Info: info, Type: printer.Type}
+	if result.Type.Make == "" {
+		result.Type.Make = info.Make
+	}
+	if result.Type.Model == "" {
+		result.Type.Model = info.Model
+	}
+	if result.Type.Version == "" {
+		result.Type.Version = info.FirmwareVersion
+	}
+	return result, nil
+}
+
+/*
+GetWebcamSnapshot fetches a frame from the printer's webcam so clients need not reach the webcam themselves
+*/
+func (ps PrinterService) GetWebcamSnapshot(id string) ([]byte, string, error) {
+	printer, err := ps.GetPrinter(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if printer.SnapshotURL == "" {
+		return nil, "", fmt.Errorf("printer %v: %w", printer.PrinterName, ErrNoWebcam)
+	}
+	return webcam.Snapshot(printer.SnapshotURL)
+}
+
+/*
+AssignSpool loads the spool with spoolId into the printer, or unloads its spool when spoolId is empty
+*/
+func (ps PrinterService) AssignSpool(id string, spoolId string) (types.Printer, error) {
+	printer, err := ps.GetPrinter(id)
+	if err != nil {
+		return types.Printer{}, err
+	}
+	if spoolId != "" {
+		if _, err := ps.spoolStore.Inspect(spoolId); err != nil {
+			return types.Printer{}, err
+		}
+	}
+	printer.SpoolId = spoolId
+	if err := ps.printerStore.Update(printer); err != nil {
+		return types.Printer{}, err
+	}
+	log.Infof("assigned spool %q to printer %v", spoolId, id)
+	return printer.Redacted(), nil
+}
+
+/*
+Control sends a control command to the printer and adds it to the printer's audit trail, whether it worked or not
+*/
+func (ps PrinterService) Control(id string, cmd types.ControlCommand, user string) (types.AuditEntry, error) {
+	printer, err := ps.GetPrinter(id)
+	if err != nil {
+		return types.AuditEntry{}, err
+	}
+	entry := types.AuditEntry{
+		Id:          uuid.New().String(),
+		PrinterId:   printer.Id,
+		PrinterName: printer.PrinterName,
+		Time:        time.Now(),
+		User:        user,
+		Command:     cmd,
+	}
+	err = ps.control(printer, &entry)
+	if err != nil {
+		entry.Result = err.Error()
+		log.Errorf("control command %v from %v on printer %v failed: %v", cmd.Command, user, printer.PrinterName, err)
+	} else {
+		entry.Result = types.AUDIT_OK
+		log.Infof("control command %v from %v on printer %v", cmd.Command, user, printer.PrinterName)
+	}
+	if auditErr := ps.auditStore.Add(entry); auditErr != nil {
+		log.Errorf("could not audit control command %v on printer %v: %v", cmd.Command, printer.PrinterName, auditErr)
+	}
+	return entry, err
+}
+
+func (ps PrinterService) control(printer types.Printer, entry *types.AuditEntry) error {
+	cmd := entry.Command
+	switch cmd.Command {
+	case types.CONTROL_PAUSE, types.CONTROL_RESUME, types.CONTROL_CANCEL, types.CONTROL_START, types.CONTROL_DELETE:
+		if (cmd.Command == types.CONTROL_START || cmd.Command == types.CONTROL_DELETE) && cmd.Path == "" {
+			return fmt.Errorf("%w: %v needs a path", ErrInvalidControl, cmd.Command)
+		}
+		d, err := driver.NewDriver(printer)
+		if err != nil {
+			return err
+		}
+		switch cmd.Command {
+		case types.CONTROL_PAUSE:
+			return d.Pause()
+		case types.CONTROL_RESUME:
+			return d.Resume()
+		case types.CONTROL_START:
+			return ps.start(d, printer, cmd.Location, cmd.Path, entry.User)
+		case types.CONTROL_DELETE:
+			return d.DeleteFile(cmd.Location, cmd.Path)
+		}
+		return d.Cancel()
+	}
+	gcode, err := controlGCode(cmd, printer.Profile)
+	if err != nil {
+		return err
+	}
+	entry.GCode = gcode
+	d, err := driver.NewDriver(printer)
+	if err != nil {
+		return err
+	}
+	return d.SendGCode(gcode...)
+}
+
+const (
+	// JOG_FEEDRATE is used for jog moves that don't give one, in mm/min
+	JOG_FEEDRATE = 3000
+)
+
+/*
+controlGCode translates a control command into G-code. Temperatures above the profile's limits are refused.
+*/
+func controlGCode(cmd types.ControlCommand, profile *types.Profile) ([]string, error) {
+	switch cmd.Command {
+	case types.CONTROL_TEMPERATURE:
+		if cmd.Target == nil || *cmd.Target < 0 {
+			return nil, fmt.Errorf("%w: temperature needs a target of 0 or more", ErrInvalidControl)
+		}
+		target := *cmd.Target
+		switch {
+		case cmd.Heater == "bed":
+			if profile != nil && profile.MaxBedTemp > 0 && target > profile.MaxBedTemp {
+				return nil, fmt.Errorf("%w: %v°C is above the bed's maximum of %v°C", ErrInvalidControl, target, profile.MaxBedTemp)
+			}
+			return []string{fmt.Sprintf("M140 S%g", target)}, nil
+		case strings.HasPrefix(cmd.Heater, "tool"):
+			tool, err := strconv.Atoi(strings.TrimPrefix(cmd.Heater, "tool"))
+			if err != nil || tool < 0 {
+				return nil, fmt.Errorf("%w: unknown heater %v", ErrInvalidControl, cmd.Heater)
+			}
+			if profile != nil && profile.MaxHotendTemp > 0 && target > profile.MaxHotendTemp {
+				return nil, fmt.Errorf("%w: %v°C is above the hotend's maximum of %v°C", ErrInvalidControl, target, profile.MaxHotendTemp)
+			}
+			return []string{fmt.Sprintf("M104 T%d S%g", tool, target)}, nil
+		}
+		return nil, fmt.Errorf("%w: unknown heater %v", ErrInvalidControl, cmd.Heater)
+	case types.CONTROL_HOME:
+		home := "G28"
+		for _, a := range cmd.Axes {
+			switch axis := strings.ToUpper(a); axis {
+			case "X", "Y", "Z":
+				home += " " + axis
+			default:
+				return nil, fmt.Errorf("%w: unknown axis %v", ErrInvalidControl, a)
+			}
+		}
+		return []string{home}, nil
+	case types.CONTROL_JOG:
+		if cmd.X == 0 && cmd.Y == 0 && cmd.Z == 0 {
+			return nil, fmt.Errorf("%w: jog needs a distance", ErrInvalidControl)
+		}
+		feedrate := cmd.Feedrate
+		if feedrate <= 0 {
+			feedrate = JOG_FEEDRATE
+		}
+		move := "G1"
+		for _, axis := range []struct {
+			name     string
+			distance float64
+		}{{"X", cmd.X}, {"Y", cmd.Y}, {"Z", cmd.Z}} {
+			if axis.distance != 0 {
+				move += fmt.Sprintf(" %v%g", axis.name, axis.distance)
+			}
+		}
+		// relative moves for the jog only, prints and the next command expect absolute positioning
+		return []string{"G91", fmt.Sprintf("%v F%g", move, feedrate), "G90"}, nil
+	case types.CONTROL_FAN:
+		if cmd.Speed == nil || *cmd.Speed < 0 || *cmd.Speed > 100 {
+			return nil, fmt.Errorf("%w: fan needs a speed from 0 to 100%%", ErrInvalidControl)
+		}
+		if *cmd.Speed == 0 {
+			return []string{"M107"}, nil
+		}
+		return []string{fmt.Sprintf("M106 S%d", int(math.Round(*cmd.Speed*255/100)))}, nil
+	case types.CONTROL_GCODE:
+		if len(cmd.Commands) == 0 {
+			return nil, fmt.Errorf("%w: no commands given", ErrInvalidControl)
+		}
+		return cmd.Commands, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrInvalidControl, cmd.Command)
+}
+
+/*
+ListAudit returns the control commands sent to the printer, newest first
+*/
+func (ps PrinterService) ListAudit(id string) ([]types.AuditEntry, error) {
+	if _, err := ps.GetPrinter(id); err != nil {
+		return nil, err
+	}
+	return ps.auditStore.ListByPrinter(id)
+}
+
+/*
+GetUsage adds up the print hours, filament and number of the jobs that ended on the printer.
+Jobs that were never seen to end are left out, their end time is only a guess.
+*/
+func (ps PrinterService) GetUsage(id string) (types.Usage, error) {
+	if _, err := ps.GetPrinter(id); err != nil {
+		return types.Usage{}, err
+	}
+	jobs, err := ps.jobStore.ListByPrinter(id)
+	if err != nil {
+		return types.Usage{}, err
+	}
+	usage := types.Usage{}
+	for _, j := range jobs {
+		if j.Active() || j.EndTime == nil || j.State == jobtypes.JOB_STATE_UNKNOWN {
+			continue
+		}
+		usage.PrintHours += j.Duration().Hours()
+		usage.FilamentM += j.FilamentUsedM
+		usage.Jobs++
+	}
+	return usage, nil
+}
+
+/*
+ListMaintenance lists the printer's maintenance tasks, overdue ones first
+*/
+func (ps PrinterService) ListMaintenance(id string, overdueOnly bool) ([]types.MaintenanceStatus, error) {
+	usage, err := ps.GetUsage(id)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := ps.maintStore.ListByPrinter(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	statuses := []types.MaintenanceStatus{}
+	for _, t := range tasks {
+		s := t.Status(usage, now)
+		if s.Overdue || !overdueOnly {
+			statuses = append(statuses, s)
+		}
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Overdue != statuses[j].Overdue {
+			return statuses[i].Overdue
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+/*
+CreateMaintenanceTask adds a task to the printer. Its intervals count from the printer's usage now.
+*/
+func (ps PrinterService) CreateMaintenanceTask(id string, task types.MaintenanceTask, user string) (types.MaintenanceTask, error) {
+	if task.Name == "" || !task.HasInterval() {
+		return types.MaintenanceTask{}, ErrInvalidTask
+	}
+	usage, err := ps.GetUsage(id)
+	if err != nil {
+		return types.MaintenanceTask{}, err
+	}
+	task.Id = utils.GenId()
+	task.PrinterId = id
+	task.Created = types.MaintenanceRecord{Time: time.Now(), Usage: usage, User: user}
+	task.History = []types.MaintenanceRecord{}
+	if err := ps.maintStore.Update(task); err != nil {
+		return types.MaintenanceTask{}, err
+	}
+	log.Infof("created maintenance task %v for printer %v", task.Id, id)
+	return task, nil
+}
+
+/*
+UpdateMaintenanceTask changes a task's name, description and intervals, keeping when it was done
+*/
+func (ps PrinterService) UpdateMaintenanceTask(id string, task types.MaintenanceTask) (types.MaintenanceTask, error) {
+	if task.Name == "" || !task.HasInterval() {
+		return types.MaintenanceTask{}, ErrInvalidTask
+	}
+	stored, err := ps.maintenanceTask(id, task.Id)
+	if err != nil {
+		return types.MaintenanceTask{}, err
+	}
+	task.PrinterId = stored.PrinterId
+	task.Created = stored.Created
+	task.History = stored.History
+	return task, ps.maintStore.Update(task)
+}
+
+func (ps PrinterService) DeleteMaintenanceTask(id string, taskId string) error {
+	if _, err := ps.maintenanceTask(id, taskId); err != nil {
+		return err
+	}
+	return ps.maintStore.Delete(taskId)
+}
+
+/*
+CompleteMaintenanceTask records the task as done now, which restarts its intervals
+*/
+func (ps PrinterService) CompleteMaintenanceTask(id string, taskId string, notes string, user string) (types.MaintenanceStatus, error) {
+	task, err := ps.maintenanceTask(id, taskId)
+	if err != nil {
+		return types.MaintenanceStatus{}, err
+	}
+	usage, err := ps.GetUsage(id)
+	if err != nil {
+		return types.MaintenanceStatus{}, err
+	}
+	now := time.Now()
+	task.History = append(task.History, types.MaintenanceRecord{Time: now, Usage: usage, User: user, Notes: notes})
+	if err := ps.maintStore.Update(task); err != nil {
+		return types.MaintenanceStatus{}, err
+	}
+	log.Infof("maintenance task %v of printer %v done by %v", taskId, id, user)
+	return task.Status(usage, now), nil
+}
+
+/*
+maintenanceTask gets the task with taskId as long as it belongs to the printer
+*/
+func (ps PrinterService) maintenanceTask(id string, taskId string) (types.MaintenanceTask, error) {
+	task, err := ps.maintStore.Inspect(taskId)
+	if err != nil {
+		return types.MaintenanceTask{}, err
+	}
+	if task.PrinterId != id {
+		return types.MaintenanceTask{}, store.ErrTaskNotFound
+	}
+	return task, nil
+}
+
+/*
+ListLocations lists every location with its path, sorted by path so parents come before what is in them
+*/
+func (ps PrinterService) ListLocations() ([]types.Location, error) {
+	locations, err := ps.locStore.List()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]types.Location, 0, len(locations))
+	for id, l := range locations {
+		l.Path = types.LocationPath(id, locations)
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list, nil
+}
+
+func (ps PrinterService) CreateLocation(location types.Location) (types.Location, error) {
+	location.Id = utils.GenId()
+	return ps.saveLocation(location)
+}
+
+/*
+UpdateLocation renames or moves a location. Printers placed in it get its new name.
+*/
+func (ps PrinterService) UpdateLocation(location types.Location) (types.Location, error) {
+	if _, err := ps.locStore.Inspect(location.Id); err != nil {
+		return types.Location{}, err
+	}
+	location, err := ps.saveLocation(location)
+	if err != nil {
+		return types.Location{}, err
+	}
+	printers, err := ps.printerStore.List()
+	if err != nil {
+		return types.Location{}, err
+	}
+	for _, p := range printers {
+		if p.Location.Id != location.Id || p.Location.Name == location.Name {
+			continue
+		}
+		p.Location.Name = location.Name
+		if err := ps.printerStore.Update(p); err != nil {
+			return types.Location{}, err
+		}
+	}
+	return location, nil
+}
+
+/*
+DeleteLocation removes an empty location. Locations with printers or other locations in them are kept.
+*/
+func (ps PrinterService) DeleteLocation(id string) error {
+	locations, err := ps.locStore.List()
+	if err != nil {
+		return err
+	}
+	if _, ok := locations[id]; !ok {
+		return store.ErrLocationNotFound
+	}
+	for _, l := range locations {
+		if l.ParentId == id {
+			return fmt.Errorf("%w: %v is inside it", ErrLocationInUse, l.Name)
+		}
+	}
+	printers, err := ps.printerStore.List()
+	if err != nil {
+		return err
+	}
+	for _, p := range printers {
+		if p.Location.Id == id {
+			return fmt.Errorf("%w: %v is placed in it", ErrLocationInUse, p.PrinterName)
+		}
+	}
+	log.Infof("deleted location %v", id)
+	return ps.locStore.Delete(id)
+}
+
+/*
+PlacePrinter puts the printer in the location with locationId, or takes it out of its location when locationId is empty
+*/
+func (ps PrinterService) PlacePrinter(id string, locationId string) (types.Printer, error) {
+	printer, err := ps.GetPrinter(id)
+	if err != nil {
+		return types.Printer{}, err
+	}
+	printer.Location = types.Location{}
+	if err := ps.placeIn(&printer, locationId); err != nil {
+		return types.Printer{}, err
+	}
+	if err := ps.printerStore.Update(printer); err != nil {
+		return types.Printer{}, err
+	}
+	log.Infof("placed printer %v in location %q", id, locationId)
+	return ps.GetPrinter(id)
+}
+
+/*
+placeIn sets the printer's location to a copy of the one with locationId. Without a locationId the printer
+keeps the location name it was given, as printers did before locations existed.
+*/
+func (ps PrinterService) placeIn(printer *types.Printer, locationId string) error {
+	if locationId == "" {
+		return nil
+	}
+	l, err := ps.locStore.Inspect(locationId)
+	if err != nil {
+		return err
+	}
+	printer.Location = l
+	return nil
+}
+
+/*
+saveLocation checks the location has a name and a parent that exists and is not inside it, then stores it
+*/
+func (ps PrinterService) saveLocation(location types.Location) (types.Location, error) {
+	location.Name = strings.TrimSpace(location.Name)
+	if location.Name == "" {
+		return types.Location{}, fmt.Errorf("%w: a location needs a name", ErrInvalidLocation)
+	}
+	locations, err := ps.locStore.List()
+	if err != nil {
+		return types.Location{}, err
+	}
+	if location.ParentId != "" {
+		if _, ok := locations[location.ParentId]; !ok {
+			return types.Location{}, fmt.Errorf("%w: parent %v does not exist", ErrInvalidLocation, location.ParentId)
+		}
+		locations[location.Id] = location
+		for _, l := range types.Ancestry(location.ParentId, locations) {
+			if l.Id == location.Id {
+				return types.Location{}, fmt.Errorf("%w: a location can't be inside itself", ErrInvalidLocation)
+			}
+		}
+	}
+	if err := ps.locStore.Update(location); err != nil {
+		return types.Location{}, err
+	}
+	locations[location.Id] = location
+	location.Path = types.LocationPath(location.Id, locations)
+	return location, nil
+}
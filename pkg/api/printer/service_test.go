@@ -4,16 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
 	"ymir/pkg/api/printer/store"
 	"ymir/pkg/api/printer/types"
+	"ymir/pkg/events"
 	"ymir/pkg/logger"
+	"ymir/pkg/printer/poller"
 )
 
 const (
@@ -38,6 +44,9 @@ logLevel="DEBUG"
 [datastore]
 dbFile = "test.db"
 
+[secrets]
+key = "test"
+
 [printers]
 printersDir="TEST_DIR/printers"
 `)
@@ -100,6 +109,21 @@ func (suite *PrintersServiceTestSuite) TestCreatePrinter() {
 	assert.NoError(suite.T(), err)
 }
 
+func (suite *PrintersServiceTestSuite) TestCreatePrinter_SeedsProfile() {
+	id, err := suite.service.CreatePrinter(types.Printer{Type: types.PrinterType{Make: "Prusa", Model: "MK4"}})
+	assert.NoError(suite.T(), err)
+	pri, _ := suite.service.GetPrinter(id)
+	if assert.NotNil(suite.T(), pri.Profile, "should match the catalog") {
+		assert.Equal(suite.T(), "prusa-mk4", pri.Profile.Id)
+		assert.True(suite.T(), pri.Profile.BinaryGCode)
+	}
+
+	custom := types.Profile{Id: "custom", BuildVolume: types.BuildVolume{X: 100, Y: 100, Z: 100}}
+	id, _ = suite.service.CreatePrinter(types.Printer{Type: types.PrinterType{Model: "MK4"}, Profile: &custom})
+	pri, _ = suite.service.GetPrinter(id)
+	assert.Equal(suite.T(), "custom", pri.Profile.Id, "an existing profile is kept")
+}
+
 func (suite *PrintersServiceTestSuite) TestUpdatePrinter() {
 	newPri := suite.testPrinters[1]
 	newName := "testUpdate"
@@ -111,8 +135,32 @@ func (suite *PrintersServiceTestSuite) TestUpdatePrinter() {
 	assert.Equal(suite.T(), newName, pri.PrinterName, "should be equal")
 }
 
+func (suite *PrintersServiceTestSuite) TestUpdatePrinter_KeepsSecrets() {
+	redacted := suite.testPrinters[0].Redacted()
+	redacted.PrinterName = "renamed"
+	err := suite.service.UpdatePrinter(redacted)
+	assert.NoError(suite.T(), err)
+	pri, _ := suite.service.GetPrinter(redacted.Id)
+	assert.Equal(suite.T(), "renamed", pri.PrinterName)
+	assert.Equal(suite.T(), "ABC123", pri.APIKey, "a masked key leaves the stored one")
+
+	pri.APIKey = "NEWKEY"
+	err = suite.service.UpdatePrinter(pri)
+	assert.NoError(suite.T(), err)
+	pri, _ = suite.service.GetPrinter(redacted.Id)
+	assert.Equal(suite.T(), "NEWKEY", pri.APIKey)
+
+	moved := pri.Redacted()
+	moved.URL = "http://elsewhere.example"
+	assert.ErrorIs(suite.T(), suite.service.UpdatePrinter(moved), types.ErrSecretsRequired)
+	_, err = suite.service.TestPrinter(moved)
+	assert.ErrorIs(suite.T(), err, types.ErrSecretsRequired, "the stored key is never sent to a new address")
+	pri, _ = suite.service.GetPrinter(redacted.Id)
+	assert.Equal(suite.T(), "NEWKEY", pri.APIKey)
+}
+
 func (suite *PrintersServiceTestSuite) TestListPrinters() {
-	Printers, err := suite.service.ListPrinters()
+	Printers, err := suite.service.ListPrinters(types.PrinterFilter{})
 	assert.NoError(suite.T(), err, "should be no error")
 	assert.Len(suite.T(), Printers, 1, "should be 1")
 	assert.IsType(suite.T(), map[string]types.Printer{}, Printers, "should be type []Printer.Printer{}")
@@ -134,6 +182,253 @@ func (suite *PrintersServiceTestSuite) TestDeletePrinter() {
 	assert.Error(suite.T(), err, "should be error")
 }
 
+func (suite *PrintersServiceTestSuite) TestConfirmBedClear_NeedsIdle() {
+	id := suite.testPrinters[0].Id
+	_, err := suite.service.ConfirmBedClear(id)
+	assert.ErrorIs(suite.T(), err, ErrPollerNotRunning)
+
+	poller.SetDefault(poller.NewPoller(suite.service.printerStore, time.Minute))
+	defer poller.SetDefault(nil)
+	_, err = suite.service.ConfirmBedClear(id)
+	assert.ErrorIs(suite.T(), err, ErrPrinterNotIdle, "a printer the poller hasn't seen could be printing")
+	q, err := suite.service.GetQueue(id)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), q.BedClear)
+}
+
+func TestJobControl(t *testing.T) {
+	assert.Equal(t, types.ControlCommand{Command: types.CONTROL_RESUME}, jobControl(types.JobCommand{Command: JOB_PAUSE, Action: JOB_RESUME}))
+	assert.Equal(t, types.ControlCommand{Command: types.CONTROL_PAUSE}, jobControl(types.JobCommand{Command: JOB_PAUSE}))
+	assert.Equal(t, types.ControlCommand{Command: types.CONTROL_START, Path: "cube.gcode", Location: "sdcard"},
+		jobControl(types.JobCommand{Command: JOB_START, Path: "cube.gcode", Location: "sdcard"}))
+}
+
+func (suite *PrintersServiceTestSuite) TestControl() {
+	commands := [][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/printer/command":
+			cmd := struct{ Commands []string }{}
+			_ = json.NewDecoder(r.Body).Decode(&cmd)
+			commands = append(commands, cmd.Commands)
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/job":
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+	p := suite.testPrinters[1]
+	p.URL = srv.URL
+	p.APIType = types.API_TYPE_OCTOPRINT
+	p.Profile = &types.Profile{MaxHotendTemp: 300, MaxBedTemp: 120}
+	assert.NoError(suite.T(), suite.service.printerStore.Create(p))
+
+	target := 215.0
+	entry, err := suite.service.Control(p.Id, types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "tool0", Target: &target}, "alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), types.AUDIT_OK, entry.Result)
+	assert.Equal(suite.T(), [][]string{{"M104 T0 S215"}}, commands)
+
+	_, err = suite.service.Control(p.Id, types.ControlCommand{Command: types.CONTROL_CANCEL}, "bob")
+	assert.Error(suite.T(), err, "octoprint refuses to cancel when not printing")
+	hot := 350.0
+	_, err = suite.service.Control(p.Id, types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "tool0", Target: &hot}, "bob")
+	assert.ErrorIs(suite.T(), err, ErrInvalidControl)
+	assert.Len(suite.T(), commands, 1, "refused commands are not sent")
+
+	audit, err := suite.service.ListAudit(p.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), audit, 3)
+	assert.Equal(suite.T(), "alice", audit[2].User)
+	assert.Equal(suite.T(), []string{"M104 T0 S215"}, audit[2].GCode)
+	assert.Equal(suite.T(), types.CONTROL_CANCEL, audit[1].Command.Command)
+	assert.NotEqual(suite.T(), types.AUDIT_OK, audit[1].Result, "failures are audited with their error")
+	assert.Contains(suite.T(), audit[0].Result, "above the hotend's maximum")
+
+	_, err = suite.service.Control("missing", types.ControlCommand{Command: types.CONTROL_HOME}, "bob")
+	assert.ErrorIs(suite.T(), err, ErrPrinterNotFound)
+}
+
+func (suite *PrintersServiceTestSuite) TestPrintPrinterFile() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	p := suite.testPrinters[1]
+	p.Id = "test-print-file"
+	p.URL = srv.URL
+	p.APIType = types.API_TYPE_OCTOPRINT
+	assert.NoError(suite.T(), suite.service.printerStore.Create(p))
+	subscription, unsubscribe := events.Subscribe(events.DEFAULT_BUFFER)
+	defer unsubscribe()
+
+	assert.NoError(suite.T(), suite.service.PrintPrinterFile(p.Id, "local", "cube.gcode", "alice"))
+	jobs, err := suite.service.jobStore.ListByPrinter(p.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), jobs, 1)
+	for _, j := range jobs {
+		assert.Equal(suite.T(), "cube.gcode", j.File)
+		assert.Equal(suite.T(), "alice", j.User)
+		assert.Equal(suite.T(), jobtypes.JOB_STATE_PRINTING, j.State)
+	}
+	select {
+	case e := <-subscription:
+		assert.Equal(suite.T(), events.JOB_STARTED, e.Type)
+		assert.Equal(suite.T(), p.Id, e.PrinterId)
+	case <-time.After(time.Second):
+		suite.T().Error("JOB_STARTED was not published")
+	}
+
+	assert.NoError(suite.T(), suite.service.DeletePrinterFile(p.Id, "local", "cube.gcode", "bob"))
+	audit, err := suite.service.ListAudit(p.Id)
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), audit, 2) {
+		assert.Equal(suite.T(), types.ControlCommand{Command: types.CONTROL_DELETE, Path: "cube.gcode", Location: "local"}, audit[0].Command)
+		assert.Equal(suite.T(), "bob", audit[0].User)
+		assert.Equal(suite.T(), types.CONTROL_START, audit[1].Command.Command)
+		assert.Equal(suite.T(), "alice", audit[1].User)
+	}
+}
+
+func (suite *PrintersServiceTestSuite) TestMaintenance() {
+	id := suite.testPrinters[0].Id
+	job := func(jobId string, hours float64, state string) {
+		start := time.Now().Add(-48 * time.Hour)
+		end := start.Add(time.Duration(hours * float64(time.Hour)))
+		err := suite.service.jobStore.Create(jobtypes.Job{Id: jobId, PrinterId: id, StartTime: start, EndTime: &end, State: state, FilamentUsedM: 10})
+		assert.NoError(suite.T(), err)
+	}
+	job("maint-1", 2, jobtypes.JOB_STATE_COMPLETED)
+	job("maint-2", 1, jobtypes.JOB_STATE_CANCELLED)
+	job("maint-3", 5, jobtypes.JOB_STATE_UNKNOWN)
+
+	usage, err := suite.service.GetUsage(id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), types.Usage{PrintHours: 3, FilamentM: 20, Jobs: 2}, usage, "jobs never seen ending are left out")
+
+	_, err = suite.service.CreateMaintenanceTask(id, types.MaintenanceTask{Name: "no interval"}, "alice")
+	assert.ErrorIs(suite.T(), err, ErrInvalidTask)
+	task, err := suite.service.CreateMaintenanceTask(id, types.MaintenanceTask{Name: "Change nozzle", IntervalHours: 1}, "alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), usage, task.Created.Usage)
+	_, err = suite.service.CreateMaintenanceTask(id, types.MaintenanceTask{Name: "Clean bed", IntervalJobs: 1}, "alice")
+	assert.NoError(suite.T(), err)
+
+	overdue, err := suite.service.ListMaintenance(id, true)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), overdue, "intervals count from when the task was created")
+
+	job("maint-4", 1.5, jobtypes.JOB_STATE_COMPLETED)
+	all, err := suite.service.ListMaintenance(id, false)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), all, 2)
+	assert.True(suite.T(), all[0].Overdue)
+	assert.True(suite.T(), all[1].Overdue)
+
+	status, err := suite.service.CompleteMaintenanceTask(id, task.Id, "0.4mm brass", "bob")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), status.Overdue)
+	assert.Equal(suite.T(), "0.4mm brass", status.LastDone().Notes)
+	overdue, err = suite.service.ListMaintenance(id, true)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), overdue, 1)
+	assert.Equal(suite.T(), "Clean bed", overdue[0].Name)
+
+	_, err = suite.service.CompleteMaintenanceTask("other-printer", task.Id, "", "bob")
+	assert.ErrorIs(suite.T(), err, store.ErrTaskNotFound)
+	assert.NoError(suite.T(), suite.service.DeleteMaintenanceTask(id, task.Id))
+}
+
+func (suite *PrintersServiceTestSuite) TestLocations() {
+	site, err := suite.service.CreateLocation(types.Location{Name: "Building A", Kind: "site"})
+	assert.NoError(suite.T(), err)
+	room, err := suite.service.CreateLocation(types.Location{Name: "Room 2", ParentId: site.Id})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Building A / Room 2", room.Path)
+	_, err = suite.service.CreateLocation(types.Location{Name: " "})
+	assert.ErrorIs(suite.T(), err, ErrInvalidLocation)
+	_, err = suite.service.CreateLocation(types.Location{Name: "Shelf", ParentId: "missing"})
+	assert.ErrorIs(suite.T(), err, ErrInvalidLocation)
+	site.ParentId = room.Id
+	_, err = suite.service.UpdateLocation(site)
+	assert.ErrorIs(suite.T(), err, ErrInvalidLocation, "a location can't be inside itself")
+
+	id := suite.testPrinters[0].Id
+	_, err = suite.service.PlacePrinter(id, "missing")
+	assert.ErrorIs(suite.T(), err, store.ErrLocationNotFound)
+	printer, err := suite.service.PlacePrinter(id, room.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Building A / Room 2", printer.Location.Path)
+	other, err := suite.service.CreatePrinter(types.Printer{PrinterName: "other", Location: types.Location{Name: "Garage"}, Tags: []string{"PETG"}})
+	assert.NoError(suite.T(), err)
+
+	printers, err := suite.service.ListPrinters(types.PrinterFilter{Location: site.Id})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), printers, 1)
+	assert.Contains(suite.T(), printers, id)
+	printers, _ = suite.service.ListPrinters(types.PrinterFilter{Location: "garage"})
+	assert.Contains(suite.T(), printers, other)
+	printers, _ = suite.service.ListPrinters(types.PrinterFilter{Tag: "petg"})
+	assert.Len(suite.T(), printers, 1)
+	assert.Contains(suite.T(), printers, other)
+
+	room.Name = "Lab"
+	_, err = suite.service.UpdateLocation(room)
+	assert.NoError(suite.T(), err)
+	stored, _ := suite.service.printerStore.Inspect(id)
+	assert.Equal(suite.T(), "Lab", stored.Location.Name, "printers keep the name of their location")
+
+	assert.ErrorIs(suite.T(), suite.service.DeleteLocation(site.Id), ErrLocationInUse)
+	assert.ErrorIs(suite.T(), suite.service.DeleteLocation(room.Id), ErrLocationInUse)
+	printer, err = suite.service.PlacePrinter(id, "")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), printer.Location.Name)
+	assert.NoError(suite.T(), suite.service.DeleteLocation(room.Id))
+	assert.NoError(suite.T(), suite.service.DeleteLocation(site.Id))
+	assert.ErrorIs(suite.T(), suite.service.DeleteLocation(site.Id), store.ErrLocationNotFound)
+}
+
+func TestControlGCode(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	profile := &types.Profile{MaxHotendTemp: 300, MaxBedTemp: 110}
+	tests := []struct {
+		name    string
+		cmd     types.ControlCommand
+		want    []string
+		wantErr bool
+	}{
+		{"Bed", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "bed", Target: f(60)}, []string{"M140 S60"}, false},
+		{"Second Tool", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "tool1", Target: f(212.5)}, []string{"M104 T1 S212.5"}, false},
+		{"Heater Off", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "tool0", Target: f(0)}, []string{"M104 T0 S0"}, false},
+		{"Bed Too Hot", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "bed", Target: f(120)}, nil, true},
+		{"No Target", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "bed"}, nil, true},
+		{"Unknown Heater", types.ControlCommand{Command: types.CONTROL_TEMPERATURE, Heater: "chamber", Target: f(40)}, nil, true},
+		{"Home All", types.ControlCommand{Command: types.CONTROL_HOME}, []string{"G28"}, false},
+		{"Home XY", types.ControlCommand{Command: types.CONTROL_HOME, Axes: []string{"x", "Y"}}, []string{"G28 X Y"}, false},
+		{"Home Unknown Axis", types.ControlCommand{Command: types.CONTROL_HOME, Axes: []string{"e"}}, nil, true},
+		{"Jog", types.ControlCommand{Command: types.CONTROL_JOG, X: 10, Z: -0.5}, []string{"G91", "G1 X10 Z-0.5 F3000", "G90"}, false},
+		{"Jog Feedrate", types.ControlCommand{Command: types.CONTROL_JOG, Y: 1, Feedrate: 600}, []string{"G91", "G1 Y1 F600", "G90"}, false},
+		{"Jog Nowhere", types.ControlCommand{Command: types.CONTROL_JOG}, nil, true},
+		{"Fan Half", types.ControlCommand{Command: types.CONTROL_FAN, Speed: f(50)}, []string{"M106 S128"}, false},
+		{"Fan Off", types.ControlCommand{Command: types.CONTROL_FAN, Speed: f(0)}, []string{"M107"}, false},
+		{"Fan Too Fast", types.ControlCommand{Command: types.CONTROL_FAN, Speed: f(150)}, nil, true},
+		{"GCode", types.ControlCommand{Command: types.CONTROL_GCODE, Commands: []string{"M500"}}, []string{"M500"}, false},
+		{"No GCode", types.ControlCommand{Command: types.CONTROL_GCODE}, nil, true},
+		{"Unknown", types.ControlCommand{Command: "explode"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := controlGCode(tt.cmd, profile)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidControl)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 /*
 Utility Functions
 */
@@ -143,6 +438,7 @@ func getTestPrinters(num int) (printers []types.Printer) {
 		_ = json.Unmarshal([]byte(types.TestPrinter), &printer)
 		printer.PrinterName = fmt.Sprintf("test_%v", i+1)
 		printer.Id = fmt.Sprintf("test-%v", i)
+		printer.APIKey = "ABC123"
 		printers = append(printers, printer)
 	}
 
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/printer/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	AUDIT_BUCKET = "audit"
+)
+
+type AuditStoreIFace interface {
+	Add(entry types.AuditEntry) (err error)
+	ListByPrinter(printerId string) (entries []types.AuditEntry, err error)
+}
+
+/*
+AuditStore keeps the control commands sent to printers. Entries are only ever added.
+*/
+type AuditStore struct {
+	AuditStoreIFace
+	ds db.BoltDBDataStore
+}
+
+func NewAuditDataStore() (store AuditStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := AuditStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := d.ds.CreateBucket(AUDIT_BUCKET)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+func (as AuditStore) Add(entry types.AuditEntry) (err error) {
+	return as.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		eJson, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(AUDIT_BUCKET)).Put([]byte(entry.Id), eJson)
+	})
+}
+
+/*
+ListByPrinter returns the printer's audit trail, newest first
+*/
+func (as AuditStore) ListByPrinter(printerId string) ([]types.AuditEntry, error) {
+	entries := []types.AuditEntry{}
+	err := as.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(AUDIT_BUCKET)).ForEach(func(k, v []byte) error {
+			e := types.AuditEntry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				log.Error("error unmarshalling audit entry")
+				return err
+			}
+			if e.PrinterId == printerId {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries, err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+)
+
+type AuditStoreTestSuite struct {
+	suite.Suite
+	store AuditStore
+}
+
+func (suite *AuditStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewAuditDataStore().(AuditStore)
+}
+
+func (suite *AuditStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *AuditStoreTestSuite) TestListByPrinter() {
+	now := time.Now()
+	entries := []types.AuditEntry{
+		{Id: "a1", PrinterId: "p1", Time: now.Add(-time.Hour), User: "alice", Command: types.ControlCommand{Command: types.CONTROL_PAUSE}, Result: types.AUDIT_OK},
+		{Id: "a2", PrinterId: "p2", Time: now.Add(-time.Minute), User: "bob", Command: types.ControlCommand{Command: types.CONTROL_HOME}, Result: types.AUDIT_OK},
+		{Id: "a3", PrinterId: "p1", Time: now, User: "bob", Command: types.ControlCommand{Command: types.CONTROL_CANCEL}, Result: "printer is not printing"},
+	}
+	for _, e := range entries {
+		assert.NoError(suite.T(), suite.store.Add(e))
+	}
+
+	list, err := suite.store.ListByPrinter("p1")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 2)
+	assert.Equal(suite.T(), "a3", list[0].Id, "newest first")
+	assert.Equal(suite.T(), "printer is not printing", list[0].Result)
+	assert.Equal(suite.T(), types.CONTROL_PAUSE, list[1].Command.Command)
+
+	list, err = suite.store.ListByPrinter("p3")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), list)
+}
+
+func TestAuditStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditStoreTestSuite))
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/printer/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	LOCATIONS_BUCKET = "locations"
+)
+
+var (
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+type LocationStoreIFace interface {
+	Update(location types.Location) (err error)
+	Delete(id string) (err error)
+	Inspect(id string) (location types.Location, err error)
+	List() (locations map[string]types.Location, err error)
+}
+
+/*
+LocationStore keeps the places printers are kept in
+*/
+type LocationStore struct {
+	LocationStoreIFace
+	ds db.BoltDBDataStore
+}
+
+func NewLocationDataStore() (store LocationStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := LocationStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := d.ds.CreateBucket(LOCATIONS_BUCKET)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+func (ls LocationStore) Update(location types.Location) (err error) {
+	// the path is worked out when read
+	location.Path = ""
+	return ls.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		lJson, err := json.Marshal(location)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(LOCATIONS_BUCKET)).Put([]byte(location.Id), lJson)
+	})
+}
+
+func (ls LocationStore) Delete(id string) (err error) {
+	return ls.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(LOCATIONS_BUCKET))
+		if b.Get([]byte(id)) == nil {
+			return ErrLocationNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (ls LocationStore) Inspect(id string) (location types.Location, err error) {
+	err = ls.ds.GetDB().View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(LOCATIONS_BUCKET)).Get([]byte(id))
+		if v == nil {
+			return ErrLocationNotFound
+		}
+		return json.Unmarshal(v, &location)
+	})
+	return location, err
+}
+
+func (ls LocationStore) List() (map[string]types.Location, error) {
+	locations := map[string]types.Location{}
+	err := ls.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(LOCATIONS_BUCKET)).ForEach(func(k, v []byte) error {
+			l := types.Location{}
+			if err := json.Unmarshal(v, &l); err != nil {
+				log.Error("error unmarshalling location")
+				return err
+			}
+			locations[string(k)] = l
+			return nil
+		})
+	})
+	return locations, err
+}
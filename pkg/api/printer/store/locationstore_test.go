@@ -0,0 +1,57 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+)
+
+type LocationStoreTestSuite struct {
+	suite.Suite
+	store LocationStore
+}
+
+func (suite *LocationStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewLocationDataStore().(LocationStore)
+}
+
+func (suite *LocationStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *LocationStoreTestSuite) TestCRUD() {
+	assert.NoError(suite.T(), suite.store.Update(types.Location{Id: "l1", Name: "Building A", Kind: "site"}))
+	assert.NoError(suite.T(), suite.store.Update(types.Location{Id: "l2", Name: "Room 2", ParentId: "l1", Path: "Building A / Room 2"}))
+
+	l, err := suite.store.Inspect("l2")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "l1", l.ParentId)
+	assert.Empty(suite.T(), l.Path, "paths aren't stored")
+
+	locations, err := suite.store.List()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), locations, 2)
+
+	assert.NoError(suite.T(), suite.store.Delete("l2"))
+	_, err = suite.store.Inspect("l2")
+	assert.ErrorIs(suite.T(), err, ErrLocationNotFound)
+	assert.ErrorIs(suite.T(), suite.store.Delete("l2"), ErrLocationNotFound)
+}
+
+func TestLocationStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(LocationStoreTestSuite))
+}
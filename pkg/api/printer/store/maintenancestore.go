@@ -0,0 +1,96 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/printer/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	MAINTENANCE_BUCKET = "maintenance"
+)
+
+var (
+	ErrTaskNotFound = errors.New("maintenance task not found")
+)
+
+type MaintenanceStoreIFace interface {
+	Update(task types.MaintenanceTask) (err error)
+	Delete(id string) (err error)
+	Inspect(id string) (task types.MaintenanceTask, err error)
+	ListByPrinter(printerId string) (tasks []types.MaintenanceTask, err error)
+}
+
+/*
+MaintenanceStore keeps the printers' maintenance tasks with the record of when they were done
+*/
+type MaintenanceStore struct {
+	MaintenanceStoreIFace
+	ds db.BoltDBDataStore
+}
+
+func NewMaintenanceDataStore() (store MaintenanceStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := MaintenanceStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := d.ds.CreateBucket(MAINTENANCE_BUCKET)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+func (ms MaintenanceStore) Update(task types.MaintenanceTask) (err error) {
+	return ms.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		tJson, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(MAINTENANCE_BUCKET)).Put([]byte(task.Id), tJson)
+	})
+}
+
+func (ms MaintenanceStore) Delete(id string) (err error) {
+	return ms.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MAINTENANCE_BUCKET))
+		if b.Get([]byte(id)) == nil {
+			return ErrTaskNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (ms MaintenanceStore) Inspect(id string) (task types.MaintenanceTask, err error) {
+	err = ms.ds.GetDB().View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(MAINTENANCE_BUCKET)).Get([]byte(id))
+		if v == nil {
+			return ErrTaskNotFound
+		}
+		return json.Unmarshal(v, &task)
+	})
+	return task, err
+}
+
+func (ms MaintenanceStore) ListByPrinter(printerId string) ([]types.MaintenanceTask, error) {
+	tasks := []types.MaintenanceTask{}
+	err := ms.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(MAINTENANCE_BUCKET)).ForEach(func(k, v []byte) error {
+			t := types.MaintenanceTask{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				log.Error("error unmarshalling maintenance task")
+				return err
+			}
+			if t.PrinterId == printerId {
+				tasks = append(tasks, t)
+			}
+			return nil
+		})
+	})
+	return tasks, err
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+)
+
+type MaintenanceStoreTestSuite struct {
+	suite.Suite
+	store MaintenanceStore
+}
+
+func (suite *MaintenanceStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewMaintenanceDataStore().(MaintenanceStore)
+}
+
+func (suite *MaintenanceStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *MaintenanceStoreTestSuite) TestCRUD() {
+	created := types.MaintenanceRecord{Time: time.Now()}
+	for _, t := range []types.MaintenanceTask{
+		{Id: "t1", PrinterId: "p1", Name: "Change nozzle", IntervalHours: 500, Created: created},
+		{Id: "t2", PrinterId: "p1", Name: "Tension belts", IntervalMonths: 3, Created: created},
+		{Id: "t3", PrinterId: "p2", Name: "Clean rods", IntervalJobs: 50, Created: created},
+	} {
+		assert.NoError(suite.T(), suite.store.Update(t))
+	}
+
+	tasks, err := suite.store.ListByPrinter("p1")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tasks, 2)
+
+	t, err := suite.store.Inspect("t1")
+	assert.NoError(suite.T(), err)
+	t.History = append(t.History, types.MaintenanceRecord{Time: time.Now(), Notes: "0.4 hardened steel"})
+	assert.NoError(suite.T(), suite.store.Update(t))
+	t, err = suite.store.Inspect("t1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "0.4 hardened steel", t.LastDone().Notes)
+
+	assert.NoError(suite.T(), suite.store.Delete("t1"))
+	_, err = suite.store.Inspect("t1")
+	assert.ErrorIs(suite.T(), err, ErrTaskNotFound)
+	assert.ErrorIs(suite.T(), suite.store.Delete("t1"), ErrTaskNotFound)
+}
+
+func TestMaintenanceStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceStoreTestSuite))
+}
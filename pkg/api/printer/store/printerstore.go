@@ -2,11 +2,13 @@ package store
 
 import (
 	"encoding/json"
+	"fmt"
 
 	log "github.com/sirupsen/logrus"
 	bolt "go.etcd.io/bbolt"
 	"ymir/pkg/api/printer/types"
 	db "ymir/pkg/db/boltdatastore"
+	"ymir/pkg/secrets"
 )
 
 const (
@@ -22,10 +24,14 @@ type PrinterStoreIFace interface {
 	Truncate() (err error)
 }
 
+/*
+PrinterStore keeps the printers' API keys and passwords encrypted. Callers always see them in plain text.
+*/
 type PrinterStore struct {
 	PrinterStoreIFace
 	bucket string
 	ds     db.BoltDBDataStore
+	cipher *secrets.Cipher
 }
 
 func NewPrinterDataStore() (store PrinterStoreIFace) {
@@ -38,6 +44,15 @@ func NewPrinterDataStore() (store PrinterStoreIFace) {
 		log.Error("could not create bucket:")
 		return nil
 	}
+	d.cipher, err = secrets.Default()
+	if err != nil {
+		log.Errorf("could not load the secrets key: %v", err)
+		return nil
+	}
+	err = d.encryptPlainText()
+	if err != nil {
+		log.Errorf("could not encrypt printer secrets: %v", err)
+	}
 	return d
 }
 
@@ -55,6 +70,10 @@ func (ms PrinterStore) Create(printer types.Printer) (err error) {
 }
 
 func (ms PrinterStore) Update(printer types.Printer) (err error) {
+	printer, err = ms.seal(printer)
+	if err != nil {
+		return err
+	}
 	err = ms.ds.GetDB().Update(func(tx *bolt.Tx) (err error) {
 		b := tx.Bucket([]byte(PRINTERS_BUCKET))
 		mJson, err := json.Marshal(printer)
@@ -98,7 +117,14 @@ func (ms PrinterStore) List() (map[string]types.Printer, error) {
 				log.Error("error unmarshalling printer")
 				return err
 			}
-			printers[string(k)] = m
+			// one printer whose secrets can't be read, e.g. after the key file was lost, shouldn't hide the others.
+			// Its secrets stay sealed so saving the printer keeps them for when the key is back.
+			opened, err := ms.open(m)
+			if err != nil {
+				log.Errorf("could not decrypt the secrets of printer %v, leaving them encrypted: %v", m.Id, err)
+				opened = m
+			}
+			printers[string(k)] = opened
 		}
 		return err
 	})
@@ -116,7 +142,80 @@ func (ms PrinterStore) Inspect(id string) (mod types.Printer, err error) {
 		}
 		return err
 	})
-	return mod, nil
+	if err != nil {
+		return mod, nil
+	}
+	return ms.open(mod)
+}
+
+/*
+seal encrypts the printer's secrets for storage
+*/
+func (ms PrinterStore) seal(printer types.Printer) (types.Printer, error) {
+	var err error
+	printer.HasKey = false
+	printer.HasPassword = false
+	if printer.APIKey, err = ms.cipher.Encrypt(printer.APIKey); err != nil {
+		return printer, err
+	}
+	if printer.Password, err = ms.cipher.Encrypt(printer.Password); err != nil {
+		return printer, err
+	}
+	return printer, nil
+}
+
+/*
+open decrypts the secrets of a stored printer
+*/
+func (ms PrinterStore) open(printer types.Printer) (types.Printer, error) {
+	var err error
+	if printer.APIKey, err = ms.cipher.Decrypt(printer.APIKey); err != nil {
+		return printer, fmt.Errorf("printer %v api key: %w", printer.Id, err)
+	}
+	if printer.Password, err = ms.cipher.Decrypt(printer.Password); err != nil {
+		return printer, fmt.Errorf("printer %v password: %w", printer.Id, err)
+	}
+	return printer, nil
+}
+
+/*
+encryptPlainText rewrites printers saved before their secrets were encrypted
+*/
+func (ms PrinterStore) encryptPlainText() error {
+	return ms.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PRINTERS_BUCKET))
+		if b == nil {
+			return nil
+		}
+		sealed := map[string][]byte{}
+		err := b.ForEach(func(k, v []byte) error {
+			p := types.Printer{}
+			if err := json.Unmarshal(v, &p); err != nil {
+				return err
+			}
+			if (p.APIKey == "" || secrets.IsEncrypted(p.APIKey)) && (p.Password == "" || secrets.IsEncrypted(p.Password)) {
+				return nil
+			}
+			p, err := ms.seal(p)
+			if err != nil {
+				return err
+			}
+			sealed[string(k)], err = json.Marshal(p)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range sealed {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		if len(sealed) > 0 {
+			log.Infof("encrypted the secrets of %v printers", len(sealed))
+		}
+		return nil
+	})
 }
 
 func (ms PrinterStore) NumPrinters() int {
@@ -11,7 +11,9 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	bolt "go.etcd.io/bbolt"
 	"ymir/pkg/api/printer/types"
+	"ymir/pkg/secrets"
 )
 
 const (
@@ -32,6 +34,9 @@ func (suite *PrinterStoreTestSuite) SetupSuite() {
 	var tomlExample = []byte(`
 [datastore]
 dbFile = "test.db"
+
+[secrets]
+key = "test"
 `)
 
 	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
@@ -108,6 +113,70 @@ func (suite *PrinterStoreTestSuite) TestPrinterInspect() {
 	assert.Equal(suite.T(), suite.testPrinters[0], mod, "should be equal")
 }
 
+func (suite *PrinterStoreTestSuite) TestPrinterSecretsEncrypted() {
+	printer := suite.testPrinters[0]
+	printer.Id = "secrets"
+	printer.APIKey = "ABC123"
+	printer.Password = "hunter2"
+	err := suite.store.Create(printer)
+	assert.NoError(suite.T(), err)
+	defer suite.store.Delete(printer.Id)
+
+	raw := suite.rawPrinter(printer.Id)
+	assert.True(suite.T(), secrets.IsEncrypted(raw.APIKey), "api key should be encrypted")
+	assert.True(suite.T(), secrets.IsEncrypted(raw.Password), "password should be encrypted")
+
+	pri, err := suite.store.Inspect(printer.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer, pri, "should decrypt")
+}
+
+func (suite *PrinterStoreTestSuite) TestPrinterSecretsMigrated() {
+	legacy := suite.testPrinters[0]
+	legacy.Id = "legacy"
+	legacy.APIKey = "ABC123"
+	js, _ := json.Marshal(legacy)
+	err := suite.store.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PRINTERS_BUCKET)).Put([]byte(legacy.Id), js)
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.store.Delete(legacy.Id)
+
+	pri, err := suite.store.Inspect(legacy.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), legacy.APIKey, pri.APIKey, "plain text keys are still readable")
+
+	err = suite.store.encryptPlainText()
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), secrets.IsEncrypted(suite.rawPrinter(legacy.Id).APIKey), "should be encrypted")
+}
+
+func (suite *PrinterStoreTestSuite) TestPrinterSecretsUnreadable() {
+	other, err := secrets.NewCipher(&secrets.SecretsConfig{Key: "a lost key"})
+	assert.NoError(suite.T(), err)
+	lost := suite.testPrinters[0]
+	lost.Id = "lost-key"
+	lost.APIKey, _ = other.Encrypt("ABC123")
+	js, _ := json.Marshal(lost)
+	err = suite.store.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PRINTERS_BUCKET)).Put([]byte(lost.Id), js)
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.store.Delete(lost.Id)
+
+	printers, err := suite.store.List()
+	assert.NoError(suite.T(), err, "the other printers are still listed")
+	assert.Equal(suite.T(), suite.testPrinters[0], printers[suite.testPrinters[0].Id])
+	assert.Equal(suite.T(), lost.APIKey, printers[lost.Id].APIKey, "the secret is left sealed")
+}
+
+func (suite *PrinterStoreTestSuite) rawPrinter(id string) (printer types.Printer) {
+	_ = suite.store.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return json.Unmarshal(tx.Bucket([]byte(PRINTERS_BUCKET)).Get([]byte(id)), &printer)
+	})
+	return
+}
+
 func TestPrinterStoreTestSuite(t *testing.T) {
 	suite.Run(t, new(PrinterStoreTestSuite))
 }
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/printer/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	QUEUES_BUCKET = "queues"
+)
+
+type QueueStoreIFace interface {
+	Get(printerId string) (queue types.Queue, err error)
+	Modify(printerId string, modify func(queue *types.Queue) error) (queue types.Queue, err error)
+	Delete(printerId string) (err error)
+	List() (queues map[string]types.Queue, err error)
+}
+
+/*
+QueueStore keeps one queue per printer, keyed by printer id
+*/
+type QueueStore struct {
+	QueueStoreIFace
+	ds db.BoltDBDataStore
+}
+
+func NewQueueDataStore() (store QueueStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := QueueStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := d.ds.CreateBucket(QUEUES_BUCKET)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+/*
+Get returns the printer's queue, which is empty if nothing was ever queued
+*/
+func (qs QueueStore) Get(printerId string) (queue types.Queue, err error) {
+	err = qs.ds.GetDB().View(func(tx *bolt.Tx) error {
+		queue, err = get(tx.Bucket([]byte(QUEUES_BUCKET)), printerId)
+		return err
+	})
+	return queue, err
+}
+
+/*
+Modify reads, changes and writes the printer's queue in one transaction so the dispatcher and the api
+don't overwrite each other. Nothing is written if modify returns an error.
+*/
+func (qs QueueStore) Modify(printerId string, modify func(queue *types.Queue) error) (queue types.Queue, err error) {
+	err = qs.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(QUEUES_BUCKET))
+		queue, err = get(b, printerId)
+		if err != nil {
+			return err
+		}
+		if err = modify(&queue); err != nil {
+			return err
+		}
+		qJson, err := json.Marshal(queue)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(printerId), qJson)
+	})
+	return queue, err
+}
+
+func get(b *bolt.Bucket, printerId string) (types.Queue, error) {
+	queue := types.Queue{PrinterId: printerId, Items: []types.QueueItem{}}
+	v := b.Get([]byte(printerId))
+	if v == nil {
+		return queue, nil
+	}
+	err := json.Unmarshal(v, &queue)
+	return queue, err
+}
+
+func (qs QueueStore) Delete(printerId string) (err error) {
+	return qs.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(QUEUES_BUCKET)).Delete([]byte(printerId))
+	})
+}
+
+func (qs QueueStore) List() (map[string]types.Queue, error) {
+	queues := map[string]types.Queue{}
+	err := qs.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(QUEUES_BUCKET)).ForEach(func(k, v []byte) error {
+			q := types.Queue{}
+			if err := json.Unmarshal(v, &q); err != nil {
+				log.Error("error unmarshalling queue")
+				return err
+			}
+			queues[string(k)] = q
+			return nil
+		})
+	})
+	return queues, err
+}
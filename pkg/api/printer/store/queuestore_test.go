@@ -0,0 +1,83 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+)
+
+type QueueStoreTestSuite struct {
+	suite.Suite
+	store QueueStore
+}
+
+func (suite *QueueStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewQueueDataStore().(QueueStore)
+}
+
+func (suite *QueueStoreTestSuite) SetupTest() {
+	queues, _ := suite.store.List()
+	for id := range queues {
+		assert.NoError(suite.T(), suite.store.Delete(id))
+	}
+}
+
+func (suite *QueueStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *QueueStoreTestSuite) TestGet_Empty() {
+	q, err := suite.store.Get("p1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "p1", q.PrinterId)
+	assert.Empty(suite.T(), q.Items)
+	assert.False(suite.T(), q.BedClear)
+}
+
+func (suite *QueueStoreTestSuite) TestModify() {
+	q, err := suite.store.Modify("p1", func(q *types.Queue) error {
+		q.Items = append(q.Items, types.QueueItem{Id: "i1", File: "a.gcode"}, types.QueueItem{Id: "i2", File: "b.gcode"})
+		q.BedClear = true
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), q.Items, 2)
+
+	stored, err := suite.store.Get("p1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), q, stored)
+
+	queues, err := suite.store.List()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), queues, 1)
+}
+
+func (suite *QueueStoreTestSuite) TestModify_ErrorWritesNothing() {
+	failed := errors.New("nope")
+	_, err := suite.store.Modify("p1", func(q *types.Queue) error {
+		q.BedClear = true
+		return failed
+	})
+	assert.ErrorIs(suite.T(), err, failed)
+	queues, _ := suite.store.List()
+	assert.Empty(suite.T(), queues)
+}
+
+func TestQueueStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(QueueStoreTestSuite))
+}
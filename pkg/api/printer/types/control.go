@@ -0,0 +1,62 @@
+package types
+
+import (
+	"time"
+)
+
+const (
+	CONTROL_PAUSE       = "pause"
+	CONTROL_RESUME      = "resume"
+	CONTROL_CANCEL      = "cancel"
+	CONTROL_START       = "start"
+	CONTROL_DELETE      = "delete"
+	CONTROL_TEMPERATURE = "temperature"
+	CONTROL_HOME        = "home"
+	CONTROL_JOG         = "jog"
+	CONTROL_FAN         = "fan"
+	CONTROL_GCODE       = "gcode"
+
+	AUDIT_OK = "ok"
+)
+
+/*
+ControlCommand is the body of POST /printer/{id}/control. Command is one of the CONTROL_* names and only the
+fields it uses are read:
+
+  - start: Path is the file on the printer to print and Location its storage, e.g. sdcard, when it is not where Ymir uploads to
+  - delete: Path and Location are the file on the printer to delete, like for start
+  - temperature: Heater is tool0, tool1, ... or bed, Target in °C with 0 turning it off
+  - home: Axes are some of x, y and z, all of them when empty
+  - jog: X, Y and Z are relative moves in mm at Feedrate in mm/min
+  - fan: Speed in percent, 0 turning the fan off
+  - gcode: Commands are sent as they are
+*/
+type ControlCommand struct {
+	Command  string   `json:"command"`
+	Heater   string   `json:"heater,omitempty"`
+	Target   *float64 `json:"target,omitempty"`
+	Axes     []string `json:"axes,omitempty"`
+	X        float64  `json:"x,omitempty"`
+	Y        float64  `json:"y,omitempty"`
+	Z        float64  `json:"z,omitempty"`
+	Feedrate float64  `json:"feedrate,omitempty"`
+	Speed    *float64 `json:"speed,omitempty"`
+	Commands []string `json:"commands,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Location string   `json:"location,omitempty"`
+}
+
+/*
+AuditEntry records a control command sent to a printer, who sent it and what came of it.
+Result is AUDIT_OK or the error the command failed with.
+*/
+type AuditEntry struct {
+	Id          string         `json:"_id"`
+	PrinterId   string         `json:"printerId"`
+	PrinterName string         `json:"printerName,omitempty"`
+	Time        time.Time      `json:"time"`
+	User        string         `json:"user,omitempty"`
+	Command     ControlCommand `json:"command"`
+	GCode       []string       `json:"gcode,omitempty"`
+	Result      string         `json:"result"`
+}
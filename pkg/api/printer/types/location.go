@@ -0,0 +1,95 @@
+package types
+
+import (
+	"strings"
+)
+
+const (
+	// LOCATION_SEPARATOR joins the names of a location and its parents into its path
+	LOCATION_SEPARATOR = " / "
+)
+
+/*
+Location is a place printers are kept in, such as a site, a room or a shelf, nested through ParentId.
+Kind says what sort of place it is and is up to the user.
+
+A printer's Location is a copy of the one it was placed in, kept up to date when the location changes.
+Printers added before locations existed only have a Name.
+Path is the names from the top location down, e.g. "Building A / Room 2 / Shelf 1", filled in when read.
+*/
+type Location struct {
+	Id       string `json:"_id,omitempty"`
+	Name     string `json:"name"`
+	ParentId string `json:"parentId,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+/*
+PrinterFilter narrows a list of printers to the ones in a location, including the locations inside it,
+and with a tag. Location is a location id or name. Empty fields match every printer.
+*/
+type PrinterFilter struct {
+	Location string
+	Tag      string
+}
+
+/*
+Ancestry is the location and its parents, the location first
+*/
+func Ancestry(id string, locations map[string]Location) []Location {
+	chain := []Location{}
+	seen := map[string]bool{}
+	for id != "" && !seen[id] {
+		l, ok := locations[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		chain = append(chain, l)
+		id = l.ParentId
+	}
+	return chain
+}
+
+/*
+LocationPath is the names of the location and its parents from the top down
+*/
+func LocationPath(id string, locations map[string]Location) string {
+	chain := Ancestry(id, locations)
+	names := make([]string, len(chain))
+	for i, l := range chain {
+		names[len(chain)-1-i] = l.Name
+	}
+	return strings.Join(names, LOCATION_SEPARATOR)
+}
+
+/*
+Matches is true when the printer is in the filter's location, or a location inside it, and has its tag
+*/
+func (f PrinterFilter) Matches(p Printer, locations map[string]Location) bool {
+	if f.Tag != "" {
+		tagged := false
+		for _, t := range p.Tags {
+			if strings.EqualFold(t, f.Tag) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if f.Location == "" {
+		return true
+	}
+	if strings.EqualFold(p.Location.Name, f.Location) {
+		return true
+	}
+	for _, l := range Ancestry(p.Location.Id, locations) {
+		if l.Id == f.Location || strings.EqualFold(l.Name, f.Location) {
+			return true
+		}
+	}
+	return false
+}
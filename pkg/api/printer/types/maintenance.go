@@ -0,0 +1,112 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+Usage is how much a printer has printed, added up from the jobs that ended on it
+*/
+type Usage struct {
+	PrintHours float64 `json:"printHours"`
+	FilamentM  float64 `json:"filamentM"`
+	Jobs       int     `json:"jobs"`
+}
+
+/*
+MaintenanceRecord is the printer's usage when a task was done, or when it was created for a task not done yet
+*/
+type MaintenanceRecord struct {
+	Time  time.Time `json:"time"`
+	Usage Usage     `json:"usage"`
+	User  string    `json:"user,omitempty"`
+	Notes string    `json:"notes,omitempty"`
+}
+
+/*
+MaintenanceTask is maintenance due every so many print hours, meters of filament or jobs, or every so many days
+or months, whichever comes first. Intervals left at 0 don't apply, at least one has to be set.
+*/
+type MaintenanceTask struct {
+	Id                string              `json:"_id,omitempty"`
+	PrinterId         string              `json:"printerId"`
+	Name              string              `json:"name"`
+	Description       string              `json:"description,omitempty"`
+	IntervalHours     float64             `json:"intervalHours,omitempty"`
+	IntervalFilamentM float64             `json:"intervalFilamentM,omitempty"`
+	IntervalJobs      int                 `json:"intervalJobs,omitempty"`
+	IntervalDays      int                 `json:"intervalDays,omitempty"`
+	IntervalMonths    int                 `json:"intervalMonths,omitempty"`
+	Created           MaintenanceRecord   `json:"created"`
+	History           []MaintenanceRecord `json:"history"`
+}
+
+/*
+MaintenanceStatus is a task with how far the printer has come since it was last done.
+Reasons says which intervals have run out when it is overdue.
+*/
+type MaintenanceStatus struct {
+	MaintenanceTask
+	Since   Usage     `json:"since"`
+	DueDate time.Time `json:"dueDate,omitempty"`
+	Overdue bool      `json:"overdue"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+/*
+MaintenanceDone is the body of POST /printer/{id}/maintenance/{taskId}/done
+*/
+type MaintenanceDone struct {
+	Notes string `json:"notes"`
+}
+
+/*
+HasInterval is false for a task that would never be due
+*/
+func (t MaintenanceTask) HasInterval() bool {
+	return t.IntervalHours > 0 || t.IntervalFilamentM > 0 || t.IntervalJobs > 0 || t.IntervalDays > 0 || t.IntervalMonths > 0
+}
+
+/*
+LastDone is the latest record of the task, its creation when it was never done
+*/
+func (t MaintenanceTask) LastDone() MaintenanceRecord {
+	if len(t.History) == 0 {
+		return t.Created
+	}
+	return t.History[len(t.History)-1]
+}
+
+/*
+Status compares the task's intervals with the printer's usage and the time since the task was last done
+*/
+func (t MaintenanceTask) Status(usage Usage, now time.Time) MaintenanceStatus {
+	last := t.LastDone()
+	s := MaintenanceStatus{
+		MaintenanceTask: t,
+		Since: Usage{
+			PrintHours: usage.PrintHours - last.Usage.PrintHours,
+			FilamentM:  usage.FilamentM - last.Usage.FilamentM,
+			Jobs:       usage.Jobs - last.Usage.Jobs,
+		},
+		Reasons: []string{},
+	}
+	if t.IntervalHours > 0 && s.Since.PrintHours >= t.IntervalHours {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%.1f print hours since last done, due every %v", s.Since.PrintHours, t.IntervalHours))
+	}
+	if t.IntervalFilamentM > 0 && s.Since.FilamentM >= t.IntervalFilamentM {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%.1fm of filament since last done, due every %vm", s.Since.FilamentM, t.IntervalFilamentM))
+	}
+	if t.IntervalJobs > 0 && s.Since.Jobs >= t.IntervalJobs {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%v jobs since last done, due every %v", s.Since.Jobs, t.IntervalJobs))
+	}
+	if t.IntervalDays > 0 || t.IntervalMonths > 0 {
+		s.DueDate = last.Time.AddDate(0, t.IntervalMonths, t.IntervalDays)
+		if !now.Before(s.DueDate) {
+			s.Reasons = append(s.Reasons, fmt.Sprintf("due on %v", s.DueDate.Format(time.DateOnly)))
+		}
+	}
+	s.Overdue = len(s.Reasons) > 0
+	return s
+}
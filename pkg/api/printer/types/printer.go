@@ -2,11 +2,21 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"ymir/pkg/secrets"
+)
+
+const (
+	API_TYPE_OCTOPRINT = "OctoPrint"
+	API_TYPE_MOONRAKER = "Moonraker"
+	API_TYPE_PRUSALINK = "PrusaLink"
+	API_TYPE_DUET      = "Duet"
+	API_TYPE_MARLIN    = "Marlin"
 )
 
 /*
@@ -23,11 +33,24 @@ type Printer struct {
 	URL         string      `json:"url"`
 	APIType     string      `json:"apiType"`
 	APIKey      string      `json:"apiKey"`
+	Username    string      `json:"username,omitempty"`
+	Password    string      `json:"password,omitempty"`
+	DevicePath  string      `json:"devicePath,omitempty"`
+	BaudRate    int         `json:"baudRate,omitempty"`
 	Location    Location    `json:"location"`
 	Type        PrinterType `json:"type"`
 	DateAdded   time.Time   `json:"dateAdded"`
 	Tags        []string    `json:"tags"`
 	AutoConnect bool        `json:"autoConnect"`
+	Profile     *Profile    `json:"profile,omitempty"`
+	// SnapshotURL serves a single webcam frame and StreamURL the live view, both optional
+	SnapshotURL string `json:"snapshotUrl,omitempty"`
+	StreamURL   string `json:"streamUrl,omitempty"`
+	// SpoolId is the filament spool loaded in the printer, used filament is taken off it as jobs finish
+	SpoolId string `json:"spoolId,omitempty"`
+	// HasKey and HasPassword tell clients a secret is set when APIKey and Password are redacted
+	HasKey      bool `json:"hasKey,omitempty"`
+	HasPassword bool `json:"hasPassword,omitempty"`
 }
 
 type PrinterType struct {
@@ -37,11 +60,82 @@ type PrinterType struct {
 }
 
 /*
-*
-Holding struct for future expansion
+JobCommand is the body of POST /printer/{id}/job.
+Command is one of start, pause, resume or cancel. Path is the file to start and Location the storage it is on,
+e.g. sdcard, when it is not where Ymir uploads to.
+OctoPrint style {"command": "pause", "action": "resume"} is accepted as well.
 */
-type Location struct {
-	Name string `json:"name"`
+type JobCommand struct {
+	Command  string `json:"command"`
+	Action   string `json:"action,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+/*
+SpoolAssignment is the body of PUT /printer/{id}/spool, an empty SpoolId takes the spool off the printer
+*/
+type SpoolAssignment struct {
+	SpoolId string `json:"spoolId"`
+}
+
+/*
+LocationAssignment is the body of PUT /printer/{id}/location, an empty LocationId takes the printer out of its location
+*/
+type LocationAssignment struct {
+	LocationId string `json:"locationId"`
+}
+
+/*
+CommandRequest is the body of POST /printer/{id}/command
+*/
+type CommandRequest struct {
+	Commands []string `json:"commands"`
+}
+
+/*
+Redacted is the printer as API clients see it, with the API key masked and the password left out
+*/
+func (p Printer) Redacted() Printer {
+	p.HasKey = p.APIKey != ""
+	p.HasPassword = p.Password != ""
+	p.APIKey = secrets.Mask(p.APIKey)
+	p.Password = ""
+	return p
+}
+
+var (
+	ErrSecretsRequired = errors.New("the printer's address changed, send its api key and password again")
+)
+
+/*
+SameEndpoint is true when p talks to the same printer the same way as stored
+*/
+func (p Printer) SameEndpoint(stored Printer) bool {
+	return p.URL == stored.URL && p.APIType == stored.APIType && p.DevicePath == stored.DevicePath
+}
+
+/*
+KeepSecrets carries over the stored API key and password when an update leaves them blank or masked.
+They are only ever sent to the stored address: when p points somewhere else a masked secret is ErrSecretsRequired
+and a blank one stays blank.
+*/
+func (p *Printer) KeepSecrets(stored Printer) error {
+	keepKey := p.APIKey == "" || secrets.IsMasked(p.APIKey)
+	keepPassword := p.Password == "" || secrets.IsMasked(p.Password)
+	if !p.SameEndpoint(stored) {
+		if (secrets.IsMasked(p.APIKey) && stored.APIKey != "") || (secrets.IsMasked(p.Password) && stored.Password != "") {
+			return ErrSecretsRequired
+		}
+		return nil
+	}
+	if keepKey {
+		p.APIKey = stored.APIKey
+	}
+	if keepPassword {
+		p.Password = stored.Password
+	}
+	return nil
 }
 
 func (p *Printer) WriteModel(dir string) error {
@@ -53,7 +147,7 @@ func (p *Printer) WriteModel(dir string) error {
 	return nil
 }
 
-func (p *Printer) Json() string {
+func (p Printer) Json() string {
 	data, _ := json.MarshalIndent(p, "", "\t")
 	return string(data)
 }
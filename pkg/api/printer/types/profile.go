@@ -0,0 +1,41 @@
+package types
+
+const (
+	GCODE_FLAVOR_MARLIN  = "marlin"
+	GCODE_FLAVOR_MARLIN2 = "marlin2"
+	GCODE_FLAVOR_KLIPPER = "klipper"
+	GCODE_FLAVOR_REPRAP  = "reprapfirmware"
+)
+
+/*
+Profile describes what a printer can do. Printers get a copy of the matching profile from the bundled catalog
+which can then be edited to suit the machine, e.g. after a nozzle swap.
+*/
+type Profile struct {
+	// Id is the catalog entry the profile was seeded from
+	Id          string      `json:"_id"`
+	Make        string      `json:"make"`
+	Model       string      `json:"model"`
+	BuildVolume BuildVolume `json:"buildVolume"`
+	// Nozzles are the nozzle diameters in mm the printer can be fitted with
+	Nozzles   []float64 `json:"nozzles"`
+	Materials []string  `json:"materials"`
+	// MaxHotendTemp and MaxBedTemp are in °C
+	MaxHotendTemp float64 `json:"maxHotendTemp"`
+	MaxBedTemp    float64 `json:"maxBedTemp"`
+	GCodeFlavor   string  `json:"gcodeFlavor"`
+	BinaryGCode   bool    `json:"binaryGCode"`
+	// SlicerModels are the printer names slicers write into G-code for this machine, e.g. PrusaSlicer's printer_model
+	SlicerModels []string `json:"slicerModels,omitempty"`
+	// Aliases are other names the make and model are known by
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+/*
+BuildVolume is the printable area in mm
+*/
+type BuildVolume struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+)
+
+/*
+Queue is the list of print files waiting for a printer. The dispatcher starts the first item once the printer
+is idle and someone has confirmed the bed is clear. Starting a print uses up the confirmation.
+*/
+type Queue struct {
+	PrinterId string      `json:"printerId"`
+	Items     []QueueItem `json:"items"`
+	BedClear  bool        `json:"bedClear"`
+	LastError string      `json:"lastError,omitempty"`
+}
+
+type QueueItem struct {
+	Id        string    `json:"_id"`
+	File      string    `json:"file"`
+	User      string    `json:"user,omitempty"`
+	DateAdded time.Time `json:"dateAdded"`
+	// Override sends the file even if the preflight check finds it incompatible with the printer
+	Override bool `json:"override,omitempty"`
+}
+
+/*
+QueueAddRequest is the body of POST /printer/{id}/queue. File is the print file path as sent to /model/file/printer.
+*/
+type QueueAddRequest struct {
+	File     string `json:"file"`
+	Override bool   `json:"override,omitempty"`
+}
+
+/*
+QueueOrderRequest is the body of PUT /printer/{id}/queue. Items holds every item id of the queue in the new order.
+*/
+type QueueOrderRequest struct {
+	Items []string `json:"items"`
+}
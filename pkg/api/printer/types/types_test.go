@@ -71,3 +71,92 @@ func TestPrinter_Json(t *testing.T) {
 		})
 	}
 }
+
+func TestMaintenanceTask_Status(t *testing.T) {
+	created := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	task := MaintenanceTask{
+		Name:          "Change nozzle",
+		IntervalHours: 500,
+		Created:       MaintenanceRecord{Time: created, Usage: Usage{PrintHours: 100}},
+	}
+	s := task.Status(Usage{PrintHours: 450, Jobs: 20}, created.AddDate(1, 0, 0))
+	assert.False(t, s.Overdue, "hours count from when the task was created")
+	assert.Equal(t, 350.0, s.Since.PrintHours)
+
+	s = task.Status(Usage{PrintHours: 600}, created)
+	assert.True(t, s.Overdue)
+	assert.Len(t, s.Reasons, 1)
+
+	task.History = []MaintenanceRecord{{Time: created.AddDate(0, 1, 0), Usage: Usage{PrintHours: 590}}}
+	assert.False(t, task.Status(Usage{PrintHours: 600}, created).Overdue, "done since")
+
+	belts := MaintenanceTask{Name: "Tension belts", IntervalMonths: 3, Created: MaintenanceRecord{Time: created}}
+	s = belts.Status(Usage{}, created.AddDate(0, 2, 0))
+	assert.False(t, s.Overdue)
+	assert.Equal(t, created.AddDate(0, 3, 0), s.DueDate)
+	assert.True(t, belts.Status(Usage{}, created.AddDate(0, 3, 0)).Overdue)
+
+	assert.False(t, MaintenanceTask{Name: "never"}.HasInterval())
+}
+
+func TestPrinterFilter_Matches(t *testing.T) {
+	locations := map[string]Location{
+		"a":  {Id: "a", Name: "Building A"},
+		"b":  {Id: "b", Name: "Building B"},
+		"r2": {Id: "r2", Name: "Room 2", ParentId: "a"},
+		"s1": {Id: "s1", Name: "Shelf 1", ParentId: "r2"},
+	}
+	assert.Equal(t, "Building A / Room 2 / Shelf 1", LocationPath("s1", locations))
+	assert.Equal(t, "", LocationPath("gone", locations))
+
+	onShelf := Printer{Location: Location{Id: "s1", Name: "Shelf 1"}, Tags: []string{"PLA", "big"}}
+	legacy := Printer{Location: Location{Name: "Home"}}
+	for _, tt := range []struct {
+		name    string
+		filter  PrinterFilter
+		printer Printer
+		want    bool
+	}{
+		{"empty filter", PrinterFilter{}, legacy, true},
+		{"own location", PrinterFilter{Location: "s1"}, onShelf, true},
+		{"parent by id", PrinterFilter{Location: "a"}, onShelf, true},
+		{"parent by name", PrinterFilter{Location: "building a"}, onShelf, true},
+		{"other building", PrinterFilter{Location: "b"}, onShelf, false},
+		{"legacy name", PrinterFilter{Location: "Home"}, legacy, true},
+		{"tag", PrinterFilter{Tag: "pla"}, onShelf, true},
+		{"missing tag", PrinterFilter{Tag: "petg"}, onShelf, false},
+		{"location and tag", PrinterFilter{Location: "r2", Tag: "big"}, onShelf, true},
+		{"location without tag", PrinterFilter{Location: "r2", Tag: "big"}, legacy, false},
+	} {
+		assert.Equal(t, tt.want, tt.filter.Matches(tt.printer, locations), tt.name)
+	}
+}
+
+func TestPrinter_KeepSecrets(t *testing.T) {
+	stored := Printer{URL: "http://printer.local", APIType: API_TYPE_OCTOPRINT, APIKey: "ABC123", Password: "hunter2"}
+	masked := stored.Redacted()
+
+	p := masked
+	assert.NoError(t, p.KeepSecrets(stored))
+	assert.Equal(t, "ABC123", p.APIKey)
+	assert.Equal(t, "hunter2", p.Password)
+
+	p = masked
+	p.URL = "http://elsewhere.example"
+	assert.ErrorIs(t, p.KeepSecrets(stored), ErrSecretsRequired, "secrets never follow a changed address")
+
+	p = Printer{URL: "http://elsewhere.example", APIType: API_TYPE_OCTOPRINT}
+	assert.NoError(t, p.KeepSecrets(stored))
+	assert.Empty(t, p.APIKey, "blank secrets stay blank at a new address")
+	assert.Empty(t, p.Password)
+
+	p = masked
+	p.APIType = API_TYPE_MOONRAKER
+	assert.ErrorIs(t, p.KeepSecrets(stored), ErrSecretsRequired)
+
+	p = masked
+	p.URL, p.APIKey = "http://elsewhere.example", "NEWKEY"
+	p.Password = "newpass"
+	assert.NoError(t, p.KeepSecrets(stored))
+	assert.Equal(t, "NEWKEY", p.APIKey)
+}
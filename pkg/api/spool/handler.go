@@ -0,0 +1,176 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api"
+	"ymir/pkg/api/spool/store"
+	"ymir/pkg/api/spool/types"
+)
+
+type SpoolHandler struct {
+	api.Handler
+}
+
+func NewSpoolHandler() api.HandlerIFace {
+	sh := SpoolHandler{
+		Handler: api.Handler{
+			Prefix:  "/spool",
+			Service: NewSpoolService(),
+		},
+	}
+	sh.Routes = sh.addRoutes()
+	return sh
+}
+
+func (sh SpoolHandler) addRoutes() []api.Route {
+	return []api.Route{
+		{
+			"listAllSpools",
+			http.MethodGet,
+			"/",
+			false,
+			sh.listAll,
+		},
+		{
+			"createSpool",
+			http.MethodPost,
+			"/",
+			false,
+			sh.create,
+		},
+		{
+			"inspectSpool",
+			http.MethodGet,
+			"/{id}",
+			false,
+			sh.inspect,
+		},
+		{
+			"updateSpool",
+			http.MethodPut,
+			"/{id}",
+			false,
+			sh.update,
+		},
+		{
+			"deleteSpool",
+			http.MethodDelete,
+			"/{id}",
+			false,
+			sh.delete,
+		},
+	}
+}
+
+func (sh SpoolHandler) GetRoutes() []api.Route {
+	return sh.Routes
+}
+
+func (sh SpoolHandler) GetService() api.Service {
+	return sh.Service
+}
+
+func (sh SpoolHandler) GetPrefix() string {
+	return sh.Prefix
+}
+
+/*
+GET /spool (200, 500) -- lists the filament spool inventory
+*/
+func (sh SpoolHandler) listAll(w http.ResponseWriter, r *http.Request) {
+	spools, err := sh.Service.(SpoolServiceIface).ListSpools()
+	if err != nil {
+		log.Errorf("list spools service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, spools)
+}
+
+/*
+POST /spool [Spool{}] (201, 400, 500) -- adds a spool and returns it with its id
+*/
+func (sh SpoolHandler) create(w http.ResponseWriter, r *http.Request) {
+	spool := types.Spool{}
+	if err := json.NewDecoder(r.Body).Decode(&spool); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spool, err := sh.Service.(SpoolServiceIface).CreateSpool(spool)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, spool)
+}
+
+/*
+GET /spool/{id} (200, 404, 500) -- gets the spool with {id}
+*/
+func (sh SpoolHandler) inspect(w http.ResponseWriter, r *http.Request) {
+	spool, err := sh.Service.(SpoolServiceIface).GetSpool(chi.URLParam(r, "id"))
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, spool)
+}
+
+/*
+PUT /spool/{id} [Spool{}] (200, 400, 404, 500) -- replaces the spool with {id}, e.g. to correct its remaining weight
+*/
+func (sh SpoolHandler) update(w http.ResponseWriter, r *http.Request) {
+	spool := types.Spool{}
+	if err := json.NewDecoder(r.Body).Decode(&spool); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spool.Id = chi.URLParam(r, "id")
+	if err := sh.Service.(SpoolServiceIface).UpdateSpool(spool); err != nil {
+		serviceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, spool)
+}
+
+/*
+DELETE /spool/{id} (200, 404, 500) -- removes the spool with {id} from the inventory and from its printer
+*/
+func (sh SpoolHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := sh.Service.(SpoolServiceIface).DeleteSpool(chi.URLParam(r, "id")); err != nil {
+		serviceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func serviceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrSpoolNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidSpool):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Errorf("spool service error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(js)
+	if err != nil {
+		log.Errorf("http write error: %v", err)
+	}
+}
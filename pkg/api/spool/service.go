@@ -0,0 +1,120 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api"
+	printerstore "ymir/pkg/api/printer/store"
+	"ymir/pkg/api/spool/store"
+	"ymir/pkg/api/spool/types"
+	"ymir/pkg/utils"
+)
+
+var (
+	ErrInvalidSpool = errors.New("invalid spool")
+)
+
+type SpoolServiceIface interface {
+	api.Service
+	ListSpools() (map[string]types.Spool, error)
+	GetSpool(id string) (types.Spool, error)
+	CreateSpool(spool types.Spool) (types.Spool, error)
+	UpdateSpool(spool types.Spool) error
+	DeleteSpool(id string) error
+}
+
+type SpoolService struct {
+	SpoolServiceIface
+	name         string
+	spoolStore   store.SpoolStoreIFace
+	printerStore printerstore.PrinterStoreIFace
+}
+
+func NewSpoolService() api.Service {
+	return SpoolService{
+		name:         "Spools",
+		spoolStore:   store.NewSpoolDataStore(),
+		printerStore: printerstore.NewPrinterDataStore(),
+	}
+}
+
+func (ss SpoolService) GetName() (name string) {
+	return ss.name
+}
+
+func (ss SpoolService) ListSpools() (map[string]types.Spool, error) {
+	return ss.spoolStore.List()
+}
+
+func (ss SpoolService) GetSpool(id string) (types.Spool, error) {
+	return ss.spoolStore.Inspect(id)
+}
+
+/*
+CreateSpool adds a spool to the inventory. A new spool without a remaining weight is full.
+*/
+func (ss SpoolService) CreateSpool(spool types.Spool) (types.Spool, error) {
+	spool.Id = utils.GenId()
+	spool.DateAdded = time.Now()
+	if spool.RemainingWeightG == 0 {
+		spool.RemainingWeightG = spool.InitialWeightG
+	}
+	if err := validate(spool); err != nil {
+		return types.Spool{}, err
+	}
+	if err := ss.spoolStore.Create(spool); err != nil {
+		return types.Spool{}, err
+	}
+	log.Infof("created spool %v in db", spool.Id)
+	return spool, nil
+}
+
+func (ss SpoolService) UpdateSpool(spool types.Spool) error {
+	stored, err := ss.spoolStore.Inspect(spool.Id)
+	if err != nil {
+		return err
+	}
+	spool.DateAdded = stored.DateAdded
+	if err := validate(spool); err != nil {
+		return err
+	}
+	return ss.spoolStore.Update(spool)
+}
+
+/*
+DeleteSpool removes the spool and takes it off the printers it was assigned to
+*/
+func (ss SpoolService) DeleteSpool(id string) error {
+	if err := ss.spoolStore.Delete(id); err != nil {
+		return err
+	}
+	printers, err := ss.printerStore.List()
+	if err != nil {
+		return err
+	}
+	for _, p := range printers {
+		if p.SpoolId == id {
+			p.SpoolId = ""
+			if err := ss.printerStore.Update(p); err != nil {
+				return err
+			}
+		}
+	}
+	log.Infof("deleted spool %v in db", id)
+	return nil
+}
+
+func validate(spool types.Spool) error {
+	switch {
+	case spool.Material == "":
+		return fmt.Errorf("%w: material is required", ErrInvalidSpool)
+	case spool.Diameter <= 0:
+		return fmt.Errorf("%w: diameter must be greater than 0", ErrInvalidSpool)
+	case spool.InitialWeightG < 0 || spool.RemainingWeightG < 0 || spool.Cost < 0:
+		return fmt.Errorf("%w: weights and cost can't be negative", ErrInvalidSpool)
+	}
+	return nil
+}
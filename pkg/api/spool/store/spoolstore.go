@@ -0,0 +1,96 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"ymir/pkg/api/spool/types"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	SPOOLS_BUCKET = "spools"
+)
+
+var (
+	ErrSpoolNotFound = errors.New("spool not found")
+)
+
+type SpoolStoreIFace interface {
+	Create(spool types.Spool) (err error)
+	Update(spool types.Spool) (err error)
+	Delete(id string) (err error)
+	List() (spools map[string]types.Spool, err error)
+	Inspect(id string) (spool types.Spool, err error)
+}
+
+type SpoolStore struct {
+	SpoolStoreIFace
+	ds db.BoltDBDataStore
+}
+
+func NewSpoolDataStore() (store SpoolStoreIFace) {
+	config := db.NewBoltDBDataStoreConfig()
+	d := SpoolStore{
+		ds: *db.NewBoltDBDatastore(config),
+	}
+	err := d.ds.CreateBucket(SPOOLS_BUCKET)
+	if err != nil {
+		log.Error("could not create bucket:")
+		return nil
+	}
+	return d
+}
+
+func (ss SpoolStore) Create(spool types.Spool) (err error) {
+	return ss.Update(spool)
+}
+
+func (ss SpoolStore) Update(spool types.Spool) (err error) {
+	return ss.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		sJson, err := json.Marshal(spool)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(SPOOLS_BUCKET)).Put([]byte(spool.Id), sJson)
+	})
+}
+
+func (ss SpoolStore) Delete(id string) (err error) {
+	return ss.ds.GetDB().Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(SPOOLS_BUCKET))
+		if b.Get([]byte(id)) == nil {
+			return ErrSpoolNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (ss SpoolStore) List() (map[string]types.Spool, error) {
+	spools := map[string]types.Spool{}
+	err := ss.ds.GetDB().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(SPOOLS_BUCKET)).ForEach(func(k, v []byte) error {
+			s := types.Spool{}
+			if err := json.Unmarshal(v, &s); err != nil {
+				log.Error("error unmarshalling spool")
+				return err
+			}
+			spools[string(k)] = s
+			return nil
+		})
+	})
+	return spools, err
+}
+
+func (ss SpoolStore) Inspect(id string) (spool types.Spool, err error) {
+	err = ss.ds.GetDB().View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(SPOOLS_BUCKET)).Get([]byte(id))
+		if v == nil {
+			return ErrSpoolNotFound
+		}
+		return json.Unmarshal(v, &spool)
+	})
+	return spool, err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/spool/types"
+)
+
+const (
+	TEST_DB = "test.db"
+)
+
+type SpoolStoreTestSuite struct {
+	suite.Suite
+	store SpoolStore
+}
+
+func (suite *SpoolStoreTestSuite) SetupSuite() {
+	viper.SetConfigType("toml")
+	var tomlExample = []byte(`
+[datastore]
+dbFile = "test.db"
+`)
+	err := viper.ReadConfig(bytes.NewBuffer(tomlExample))
+	if err != nil {
+		suite.T().Errorf("Error: %v", err)
+	}
+	suite.store = NewSpoolDataStore().(SpoolStore)
+}
+
+func (suite *SpoolStoreTestSuite) TearDownSuite() {
+	os.Remove(TEST_DB)
+}
+
+func (suite *SpoolStoreTestSuite) TestCRUD() {
+	s := types.Spool{Id: "s1", Material: "PLA", Diameter: 1.75, InitialWeightG: 1000, RemainingWeightG: 1000}
+	assert.NoError(suite.T(), suite.store.Create(s))
+
+	s.Use(250)
+	assert.NoError(suite.T(), suite.store.Update(s))
+	got, err := suite.store.Inspect("s1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 750.0, got.RemainingWeightG)
+
+	spools, err := suite.store.List()
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), spools, "s1")
+
+	assert.NoError(suite.T(), suite.store.Delete("s1"))
+	_, err = suite.store.Inspect("s1")
+	assert.ErrorIs(suite.T(), err, ErrSpoolNotFound)
+	assert.ErrorIs(suite.T(), suite.store.Delete("s1"), ErrSpoolNotFound)
+}
+
+func TestSpoolStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(SpoolStoreTestSuite))
+}
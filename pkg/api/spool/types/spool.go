@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+)
+
+/*
+Spool is a roll of filament. RemainingWeightG goes down by the filament used of each job printed from it
+while it is assigned to a printer. Cost is the price paid for the whole spool.
+*/
+type Spool struct {
+	Id               string    `json:"_id,omitempty"`
+	Material         string    `json:"material"`
+	Color            string    `json:"color,omitempty"`
+	Brand            string    `json:"brand,omitempty"`
+	Diameter         float64   `json:"diameter"`
+	InitialWeightG   float64   `json:"initialWeightG"`
+	RemainingWeightG float64   `json:"remainingWeightG"`
+	Cost             float64   `json:"cost,omitempty"`
+	Location         string    `json:"location,omitempty"`
+	DateAdded        time.Time `json:"dateAdded"`
+}
+
+/*
+Use takes grams of filament off the spool, down to empty
+*/
+func (s *Spool) Use(grams float64) {
+	s.RemainingWeightG -= grams
+	if s.RemainingWeightG < 0 {
+		s.RemainingWeightG = 0
+	}
+}
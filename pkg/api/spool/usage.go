@@ -0,0 +1,91 @@
+package spool
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	jobtypes "ymir/pkg/api/job/types"
+	printerstore "ymir/pkg/api/printer/store"
+	"ymir/pkg/api/spool/store"
+	bus "ymir/pkg/events"
+)
+
+const (
+	// LOW_SPOOL_G is the remaining weight below which a spool is logged as running low
+	LOW_SPOOL_G = 50.0
+)
+
+/*
+Usage takes the filament used by finished jobs off the spool assigned to their printer.
+Cancelled and failed jobs count too, with the part of the filament they printed.
+*/
+type Usage struct {
+	spools      store.SpoolStoreIFace
+	printers    printerstore.PrinterStoreIFace
+	lock        sync.Mutex
+	unsubscribe func()
+	done        chan struct{}
+}
+
+func NewUsage(spools store.SpoolStoreIFace, printers printerstore.PrinterStoreIFace) *Usage {
+	return &Usage{spools: spools, printers: printers}
+}
+
+/*
+Start subscribes to job events until Stop is called
+*/
+func (u *Usage) Start() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.unsubscribe != nil {
+		return
+	}
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	u.unsubscribe = unsubscribe
+	u.done = make(chan struct{})
+	go func() {
+		defer close(u.done)
+		for e := range events {
+			u.handle(e)
+		}
+	}()
+}
+
+func (u *Usage) Stop() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.unsubscribe == nil {
+		return
+	}
+	u.unsubscribe()
+	<-u.done
+	u.unsubscribe = nil
+}
+
+func (u *Usage) handle(e bus.Event) {
+	if e.Type != bus.JOB_FINISHED {
+		return
+	}
+	j, ok := e.Data.(jobtypes.Job)
+	if !ok || j.FilamentUsedG <= 0 {
+		return
+	}
+	p, err := u.printers.Inspect(j.PrinterId)
+	if err != nil || p.SpoolId == "" {
+		return
+	}
+	s, err := u.spools.Inspect(p.SpoolId)
+	if err != nil {
+		log.Errorf("could not find spool %v of printer %v: %v", p.SpoolId, p.Id, err)
+		return
+	}
+	s.Use(j.FilamentUsedG)
+	if err := u.spools.Update(s); err != nil {
+		log.Errorf("could not update spool %v: %v", s.Id, err)
+		return
+	}
+	log.Infof("used %.1fg of spool %v on printer %v, %.1fg left", j.FilamentUsedG, s.Id, p.Id, s.RemainingWeightG)
+	if s.RemainingWeightG < LOW_SPOOL_G {
+		log.Warnf("spool %v on printer %v is running low, %.1fg left", s.Id, p.Id, s.RemainingWeightG)
+	}
+}
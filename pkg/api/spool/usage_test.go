@@ -0,0 +1,84 @@
+package spool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
+	printerstore "ymir/pkg/api/printer/store"
+	printertypes "ymir/pkg/api/printer/types"
+	"ymir/pkg/api/spool/store"
+	"ymir/pkg/api/spool/types"
+	bus "ymir/pkg/events"
+)
+
+/*
+memSpools and memPrinters keep the stores in memory so usage tests don't need a db
+*/
+type memSpools struct {
+	store.SpoolStore
+	spools map[string]types.Spool
+}
+
+func (m *memSpools) Inspect(id string) (types.Spool, error) {
+	s, ok := m.spools[id]
+	if !ok {
+		return s, store.ErrSpoolNotFound
+	}
+	return s, nil
+}
+
+func (m *memSpools) Update(s types.Spool) error {
+	m.spools[s.Id] = s
+	return nil
+}
+
+type memPrinters struct {
+	printerstore.PrinterStore
+	printers map[string]printertypes.Printer
+}
+
+func (m *memPrinters) Inspect(id string) (printertypes.Printer, error) {
+	return m.printers[id], nil
+}
+
+type UsageTestSuite struct {
+	suite.Suite
+	spools *memSpools
+	usage  *Usage
+}
+
+func (suite *UsageTestSuite) SetupTest() {
+	suite.spools = &memSpools{spools: map[string]types.Spool{
+		"s1": {Id: "s1", Material: "PLA", Diameter: 1.75, InitialWeightG: 1000, RemainingWeightG: 30},
+	}}
+	printers := &memPrinters{printers: map[string]printertypes.Printer{
+		"p1": {Id: "p1", SpoolId: "s1"},
+		"p2": {Id: "p2"},
+	}}
+	suite.usage = NewUsage(suite.spools, printers)
+}
+
+func (suite *UsageTestSuite) finished(printerId string, usedG float64) {
+	j := jobtypes.Job{Id: "j1", PrinterId: printerId, State: jobtypes.JOB_STATE_COMPLETED, FilamentUsedG: usedG}
+	suite.usage.handle(bus.Event{Type: bus.JOB_FINISHED, PrinterId: printerId, Time: time.Now(), Data: j})
+}
+
+func (suite *UsageTestSuite) TestDeducted() {
+	suite.finished("p1", 12.5)
+	assert.Equal(suite.T(), 17.5, suite.spools.spools["s1"].RemainingWeightG)
+	suite.finished("p1", 20)
+	assert.Equal(suite.T(), 0.0, suite.spools.spools["s1"].RemainingWeightG, "a spool doesn't go below empty")
+}
+
+func (suite *UsageTestSuite) TestNoSpool() {
+	suite.finished("p2", 12.5)
+	suite.usage.handle(bus.Event{Type: bus.JOB_STARTED, PrinterId: "p1", Data: jobtypes.Job{PrinterId: "p1", FilamentUsedG: 5}})
+	assert.Equal(suite.T(), 30.0, suite.spools.spools["s1"].RemainingWeightG)
+}
+
+func TestUsageTestSuite(t *testing.T) {
+	suite.Run(t, new(UsageTestSuite))
+}
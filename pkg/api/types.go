@@ -1,8 +1,11 @@
 package api
 
 import (
+	"fmt"
+	"net"
 	"net/http"
 
+	"github.com/go-chi/jwtauth/v5"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -51,3 +54,23 @@ func (ds DataService) Write() (err error) {
 	log.Info("In Service Write")
 	return nil
 }
+
+/*
+RequestUser is who made the request for history and audit records. On protected routes it is the subject of the
+verified JWT. Otherwise Ymir can't tell who the client is, so it is the client's address and a name sent in the
+?user= query param is only recorded next to it, marked as unverified.
+*/
+func RequestUser(r *http.Request) string {
+	token, _, err := jwtauth.FromContext(r.Context())
+	if verifyErr, _ := r.Context().Value(jwtauth.ErrorCtxKey).(error); token != nil && err == nil && verifyErr == nil && token.Subject() != "" {
+		return token.Subject()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if user := r.URL.Query().Get("user"); user != "" {
+		return fmt.Sprintf("%v (unverified, %v)", user, host)
+	}
+	return host
+}
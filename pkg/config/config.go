@@ -25,12 +25,49 @@ fileOut = true
 [datastore]
 dbFile="~/.ymir/ymir.db"
 
+[secrets]
+keyFile="~/.ymir/secret.key"
+
 [models]
 uploadsTempDir="~/.ymir/uploads/tmp"
 modelsDir="~/.ymir/models"
 
 [printers]
 printersDir="~/.ymir/printers"
+pollInterval=10
+timelapseInterval=30
+
+[notifications]
+retries=5
+backoff=2
+# templates are json (the default), ntfy, discord and slack. Leave out events to get all of them
+# [[notifications.webhooks]]
+# url="https://ntfy.sh/my-printers"
+# template="ntfy"
+# events=["print.finished", "print.failed", "print.cancelled", "printer.offline"]
+
+[cost]
+currency="USD"
+defaultPricePerKg=25.0
+powerWatts=120
+electricityRate=0.15
+wearPerHour=0.10
+# filament prices per kg by material, others cost defaultPricePerKg
+# [cost.filamentPricePerKg]
+# PLA=20.0
+# PETG=25.0
+
+[gcode]
+# used to estimate print times and filament weights of files the slicer left them out of
+acceleration=1000
+travelAcceleration=1250
+jerk=8
+maxFeedrate=200
+filamentDiameter=1.75
+defaultDensity=1.24
+# densities in g/cm³ by material, PLA, PETG, ABS, ASA, TPU, PC and PA are known
+# [gcode.density]
+# PLA=1.24
 
 [http]
 hostname = "0.0.0.0"
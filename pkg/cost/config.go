@@ -0,0 +1,71 @@
+package cost
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	_COST = "cost"
+)
+
+/*
+[cost]
+currency="USD"
+defaultPricePerKg=25.0
+powerWatts=120
+electricityRate=0.15
+wearPerHour=0.10
+[cost.filamentPricePerKg]
+PLA=20.0
+*/
+type CostConfig struct {
+	Currency string `toml:"currency"`
+	// FilamentPricePerKg is the price by material, materials not listed cost DefaultPricePerKg
+	FilamentPricePerKg map[string]float64 `toml:"filamentPricePerKg"`
+	DefaultPricePerKg  float64            `toml:"defaultPricePerKg"`
+	// PowerWatts is the average power draw of a printer while printing
+	PowerWatts float64 `toml:"powerWatts"`
+	// ElectricityRate is the price of a kWh
+	ElectricityRate float64 `toml:"electricityRate"`
+	// WearPerHour covers nozzles, belts, build plates and the printer itself
+	WearPerHour float64 `toml:"wearPerHour"`
+}
+
+func NewCostConfig() *CostConfig {
+	c := &CostConfig{
+		Currency:           "USD",
+		FilamentPricePerKg: map[string]float64{},
+		DefaultPricePerKg:  25.0,
+		PowerWatts:         120,
+		ElectricityRate:    0.15,
+		WearPerHour:        0.10,
+	}
+
+	h := viper.Sub(_COST)
+	if h != nil {
+		err := h.Unmarshal(c)
+		if err != nil {
+			log.Error(_COST, " config error: ", err.Error())
+		}
+	}
+	return c
+}
+
+func (c *CostConfig) StringJSON() string {
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func (c *CostConfig) StringToml() (config string) {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,54 @@
+package cost
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCostConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		configFile string
+		want       *CostConfig
+	}{
+		{
+			"No Config File",
+			"",
+			&CostConfig{
+				Currency:           "USD",
+				FilamentPricePerKg: map[string]float64{},
+				DefaultPricePerKg:  25.0,
+				PowerWatts:         120,
+				ElectricityRate:    0.15,
+				WearPerHour:        0.10,
+			},
+		},
+		{
+			"With Good Config File",
+			"testdata/goodConfig.toml",
+			&CostConfig{
+				Currency: "EUR",
+				// viper lower cases keys, prices are looked up case insensitively
+				FilamentPricePerKg: map[string]float64{"pla": 20.0, "petg": 30.0},
+				DefaultPricePerKg:  25.0,
+				PowerWatts:         200,
+				ElectricityRate:    0.30,
+				WearPerHour:        0.10,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.configFile != "" {
+				//run viper first to get valid config
+				viper.SetConfigFile(tt.configFile)
+				if err := viper.ReadInConfig(); err != nil {
+					t.Errorf("Error reading config file %v: %v\n", tt.configFile, err)
+				}
+			}
+			assert.Equalf(t, tt.want, NewCostConfig(), "Should Be Equal")
+		})
+	}
+}
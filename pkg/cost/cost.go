@@ -0,0 +1,130 @@
+/*
+Package cost estimates what a print costs in filament, electricity and machine wear.
+*/
+package cost
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"ymir/pkg/gcode"
+)
+
+/*
+Estimate is the cost of a print broken down by what it is spent on, in the configured currency
+*/
+type Estimate struct {
+	Currency    string  `json:"currency"`
+	FilamentG   float64 `json:"filamentG"`
+	Hours       float64 `json:"hours"`
+	Filament    float64 `json:"filament"`
+	Electricity float64 `json:"electricity"`
+	Wear        float64 `json:"wear"`
+	Total       float64 `json:"total"`
+}
+
+/*
+FileEstimate is the estimated cost of one of a model's print files
+*/
+type FileEstimate struct {
+	Path     string   `json:"path"`
+	Estimate Estimate `json:"estimate"`
+}
+
+/*
+Summary is what a model costs to print: an estimate for each of its print files and the cost of the jobs
+that printed them so far, from the filament they used and how long they ran
+*/
+type Summary struct {
+	ModelId string         `json:"modelId"`
+	Files   []FileEstimate `json:"files"`
+	Jobs    int            `json:"jobs"`
+	Printed Estimate       `json:"printed"`
+}
+
+/*
+PricePerKg is the price of the material, matched case insensitively
+*/
+func (c *CostConfig) PricePerKg(material string) float64 {
+	for m, price := range c.FilamentPricePerKg {
+		if strings.EqualFold(m, strings.TrimSpace(material)) {
+			return price
+		}
+	}
+	return c.DefaultPricePerKg
+}
+
+/*
+Calculate prices grams of filament, one amount per extruder with its material, printed over d
+*/
+func (c *CostConfig) Calculate(materials []string, grams []float64, d time.Duration) Estimate {
+	e := Estimate{Currency: c.Currency, Hours: d.Hours()}
+	for i, g := range grams {
+		material := ""
+		if i < len(materials) {
+			material = materials[i]
+		} else if len(materials) > 0 {
+			material = materials[0]
+		}
+		e.FilamentG += g
+		e.Filament += g / 1000 * c.PricePerKg(material)
+	}
+	e.Electricity = c.PowerWatts / 1000 * e.Hours * c.ElectricityRate
+	e.Wear = c.WearPerHour * e.Hours
+	e.Total = e.Filament + e.Electricity + e.Wear
+	return e.round()
+}
+
+/*
+EstimateGCode prices a print file from its slicer metadata. Amounts the slicer left out count as 0.
+*/
+func (c *CostConfig) EstimateGCode(meta gcode.GCodeMetaData) Estimate {
+	d, _ := gcode.ParseTotalTime(meta.TotalTime)
+	return c.Calculate(split(meta.Material), amounts(meta.FilamentUsedG), d)
+}
+
+/*
+Add sums two estimates, e.g. of all the jobs that printed a model
+*/
+func (e Estimate) Add(o Estimate) Estimate {
+	e.FilamentG += o.FilamentG
+	e.Hours += o.Hours
+	e.Filament += o.Filament
+	e.Electricity += o.Electricity
+	e.Wear += o.Wear
+	e.Total += o.Total
+	return e.round()
+}
+
+func (e Estimate) round() Estimate {
+	cents := func(v float64) float64 { return math.Round(v*100) / 100 }
+	e.FilamentG = cents(e.FilamentG)
+	e.Hours = cents(e.Hours)
+	e.Filament = cents(e.Filament)
+	e.Electricity = cents(e.Electricity)
+	e.Wear = cents(e.Wear)
+	e.Total = cents(e.Total)
+	return e
+}
+
+/*
+split splits multi extruder materials such as "PLA;PETG"
+*/
+func split(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' })
+}
+
+/*
+amounts reads slicer amounts like "12.3" or "1.5, 4.25" for multi extruder prints
+*/
+func amounts(value string) []float64 {
+	grams := []float64{}
+	for _, a := range split(value) {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(a), 64); err == nil {
+			grams = append(grams, v)
+		}
+	}
+	return grams
+}
@@ -0,0 +1,51 @@
+package cost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"ymir/pkg/gcode"
+)
+
+func testConfig() *CostConfig {
+	return &CostConfig{
+		Currency:           "USD",
+		FilamentPricePerKg: map[string]float64{"pla": 20.0, "petg": 30.0},
+		DefaultPricePerKg:  25.0,
+		PowerWatts:         200,
+		ElectricityRate:    0.25,
+		WearPerHour:        0.10,
+	}
+}
+
+func TestEstimateGCode(t *testing.T) {
+	c := testConfig()
+
+	e := c.EstimateGCode(gcode.GCodeMetaData{Material: "PLA", FilamentUsedG: "50", TotalTime: "2h 0m 0s"})
+	assert.Equal(t, Estimate{
+		Currency:    "USD",
+		FilamentG:   50,
+		Hours:       2,
+		Filament:    1.0, // 50g of PLA at 20/kg
+		Electricity: 0.1, // 0.4kWh at 0.25
+		Wear:        0.2,
+		Total:       1.3,
+	}, e)
+
+	e = c.EstimateGCode(gcode.GCodeMetaData{Material: "PLA;PETG", FilamentUsedG: "10, 20", TotalTime: "1h0m0s"})
+	assert.Equal(t, 30.0, e.FilamentG)
+	assert.Equal(t, 0.8, e.Filament, "each extruder at the price of its material")
+
+	e = c.EstimateGCode(gcode.GCodeMetaData{Material: "ASA", FilamentUsedG: "100"})
+	assert.Equal(t, 2.5, e.Total, "unknown materials at the default price, no time no power")
+}
+
+func TestEstimateAdd(t *testing.T) {
+	c := testConfig()
+	a := c.Calculate([]string{"PLA"}, []float64{50}, 2*time.Hour)
+	sum := a.Add(a)
+	assert.Equal(t, 100.0, sum.FilamentG)
+	assert.Equal(t, 2.6, sum.Total)
+	assert.Equal(t, "USD", sum.Currency)
+}
@@ -0,0 +1,120 @@
+/*
+Package events is a small in-process publish/subscribe bus. The poller and services publish
+printer, job, upload and queue events and the /v1/events handler pushes them to clients.
+*/
+package events
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// PRINTER_STATE is published when a printer changes state e.g. idle -> printing
+	PRINTER_STATE = "printer.state"
+	// JOB_PROGRESS is published when the progress of a printer's job changes
+	JOB_PROGRESS = "job.progress"
+	// UPLOAD_COMPLETE is published when a print file has been sent to a printer
+	UPLOAD_COMPLETE = "upload.complete"
+	// QUEUE_CHANGED is published when a printer's print queue changes, including when the dispatcher starts the next item
+	QUEUE_CHANGED = "queue.changed"
+	// JOB_STARTED is published with the recorded job when Ymir starts a print
+	JOB_STARTED = "job.started"
+	// JOB_FINISHED is published with the recorded job when it completes, is cancelled or fails
+	JOB_FINISHED = "job.finished"
+
+	DEFAULT_BUFFER = 64
+)
+
+type Event struct {
+	Type      string      `json:"type"`
+	PrinterId string      `json:"printerId,omitempty"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+/*
+StateChange is the Data of a PRINTER_STATE event
+*/
+type StateChange struct {
+	PrinterName string `json:"printerName"`
+	Previous    string `json:"previous"`
+	State       string `json:"state"`
+	Text        string `json:"text"`
+}
+
+/*
+Upload is the Data of an UPLOAD_COMPLETE event
+*/
+type Upload struct {
+	PrinterName string `json:"printerName"`
+	File        string `json:"file"`
+	Print       bool   `json:"print"`
+}
+
+/*
+Bus fans events out to subscribers. A subscriber that does not keep up misses events rather than blocking publishers.
+*/
+type Bus struct {
+	lock        sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+var bus = NewBus()
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+/*
+Subscribe returns a channel receiving every event published from now on, and a func to unsubscribe
+*/
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	c := make(chan Event, buffer)
+	b.lock.Lock()
+	b.subscribers[c] = struct{}{}
+	b.lock.Unlock()
+
+	once := sync.Once{}
+	return c, func() {
+		once.Do(func() {
+			b.lock.Lock()
+			delete(b.subscribers, c)
+			b.lock.Unlock()
+			close(c)
+		})
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for c := range b.subscribers {
+		select {
+		case c <- e:
+		default:
+			log.Warnf("event subscriber is not keeping up, dropped %v event", e.Type)
+		}
+	}
+}
+
+/*
+Subscribe subscribes to the process wide bus
+*/
+func Subscribe(buffer int) (<-chan Event, func()) {
+	return bus.Subscribe(buffer)
+}
+
+/*
+Publish publishes to the process wide bus
+*/
+func Publish(e Event) {
+	bus.Publish(e)
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+	c1, unsubscribe1 := b.Subscribe(1)
+	c2, unsubscribe2 := b.Subscribe(1)
+	defer unsubscribe2()
+
+	b.Publish(Event{Type: PRINTER_STATE, PrinterId: "p1"})
+	e := <-c1
+	assert.Equal(t, PRINTER_STATE, e.Type)
+	assert.False(t, e.Time.IsZero(), "publish should stamp the time")
+	assert.Equal(t, "p1", (<-c2).PrinterId)
+
+	unsubscribe1()
+	unsubscribe1()
+	_, open := <-c1
+	assert.False(t, open, "unsubscribing closes the channel")
+
+	// a full subscriber misses events instead of blocking
+	b.Publish(Event{Type: JOB_PROGRESS})
+	b.Publish(Event{Type: UPLOAD_COMPLETE})
+	assert.Equal(t, JOB_PROGRESS, (<-c2).Type)
+	assert.Len(t, c2, 0)
+}
@@ -0,0 +1,107 @@
+package gcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	_GCODE = "gcode"
+)
+
+/*
+[gcode]
+acceleration=1000
+travelAcceleration=1250
+jerk=8
+maxFeedrate=200
+filamentDiameter=1.75
+defaultDensity=1.24
+[gcode.density]
+PETG=1.27
+*/
+type GCodeConfig struct {
+	// Acceleration is for printing moves and TravelAcceleration for the others, in mm/s², until the file sets its own with M204
+	Acceleration       float64 `toml:"acceleration"`
+	TravelAcceleration float64 `toml:"travelAcceleration"`
+	// Jerk is the speed change in mm/s the printer makes at a corner without slowing down, until the file sets it with M205
+	Jerk float64 `toml:"jerk"`
+	// MaxFeedrate caps the speed of every move in mm/s
+	MaxFeedrate      float64 `toml:"maxFeedrate"`
+	FilamentDiameter float64 `toml:"filamentDiameter"`
+	// Density is g/cm³ by material, materials not listed weigh DefaultDensity
+	Density        map[string]float64 `toml:"density"`
+	DefaultDensity float64            `toml:"defaultDensity"`
+}
+
+func NewGCodeConfig() *GCodeConfig {
+	c := &GCodeConfig{
+		Acceleration:       1000,
+		TravelAcceleration: 1250,
+		Jerk:               8,
+		MaxFeedrate:        200,
+		FilamentDiameter:   1.75,
+		// viper lower cases keys, so these are too
+		Density: map[string]float64{
+			"pla":  1.24,
+			"petg": 1.27,
+			"abs":  1.04,
+			"asa":  1.07,
+			"tpu":  1.21,
+			"pc":   1.20,
+			"pa":   1.14,
+		},
+		DefaultDensity: 1.24,
+	}
+
+	h := viper.Sub(_GCODE)
+	if h != nil {
+		err := h.Unmarshal(c)
+		if err != nil {
+			log.Error(_GCODE, " config error: ", err.Error())
+		}
+	}
+	return c
+}
+
+/*
+MaterialDensity is the density of material in g/cm³. Multi material files list theirs like "PLA;PETG", the first one is used.
+*/
+func (c *GCodeConfig) MaterialDensity(material string) float64 {
+	first := strings.FieldsFunc(material, func(r rune) bool { return r == ';' || r == ',' })
+	if len(first) > 0 {
+		if d, ok := c.Density[strings.ToLower(strings.TrimSpace(first[0]))]; ok {
+			return d
+		}
+	}
+	return c.DefaultDensity
+}
+
+/*
+Grams is the weight of mm of filament of material
+*/
+func (c *GCodeConfig) Grams(mm float64, material string) float64 {
+	r := c.FilamentDiameter / 2
+	// mm³ to cm³
+	return mm * math.Pi * r * r / 1000 * c.MaterialDensity(material)
+}
+
+func (c *GCodeConfig) StringJSON() string {
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func (c *GCodeConfig) StringToml() (config string) {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,65 @@
+package gcode
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGCodeConfig(t *testing.T) {
+	densities := func(petg float64) map[string]float64 {
+		return map[string]float64{"pla": 1.24, "petg": petg, "abs": 1.04, "asa": 1.07, "tpu": 1.21, "pc": 1.20, "pa": 1.14}
+	}
+	tests := []struct {
+		name       string
+		configFile string
+		want       *GCodeConfig
+	}{
+		{
+			"No Config File",
+			"",
+			&GCodeConfig{
+				Acceleration:       1000,
+				TravelAcceleration: 1250,
+				Jerk:               8,
+				MaxFeedrate:        200,
+				FilamentDiameter:   1.75,
+				Density:            densities(1.27),
+				DefaultDensity:     1.24,
+			},
+		},
+		{
+			"With Good Config File",
+			"testdata/goodConfig.toml",
+			&GCodeConfig{
+				Acceleration:       4000,
+				TravelAcceleration: 5000,
+				Jerk:               10,
+				MaxFeedrate:        300,
+				FilamentDiameter:   1.75,
+				Density:            densities(1.29),
+				DefaultDensity:     1.24,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.configFile != "" {
+				//run viper first to get valid config
+				viper.SetConfigFile(tt.configFile)
+				if err := viper.ReadInConfig(); err != nil {
+					t.Errorf("Error reading config file %v: %v\n", tt.configFile, err)
+				}
+			}
+			assert.Equalf(t, tt.want, NewGCodeConfig(), "Should Be Equal")
+		})
+	}
+}
+
+func TestGCodeConfig_Grams(t *testing.T) {
+	c := &GCodeConfig{FilamentDiameter: 1.75, Density: map[string]float64{"petg": 1.27}, DefaultDensity: 1.24}
+	assert.Equal(t, 1.27, c.MaterialDensity("PETG;PLA"))
+	assert.Equal(t, 1.24, c.MaterialDensity("unobtainium"))
+	assert.InDelta(t, 2.98, c.Grams(1000, "PLA"), 0.01)
+}
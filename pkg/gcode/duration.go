@@ -0,0 +1,46 @@
+package gcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ParseTotalTime reads the print time slicers write, PrusaSlicer's "1d 2h 3m 4s" as well as Go durations like
+"1h2m3s", which is how Marlin flavored files end up in TotalTime
+*/
+func ParseTotalTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	var total time.Duration
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("no print time in %q", s)
+	}
+	for _, f := range fields {
+		if len(f) < 2 {
+			return 0, fmt.Errorf("invalid print time %q", s)
+		}
+		n, err := strconv.Atoi(f[:len(f)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid print time %q", s)
+		}
+		switch f[len(f)-1] {
+		case 'd':
+			total += time.Duration(n) * 24 * time.Hour
+		case 'h':
+			total += time.Duration(n) * time.Hour
+		case 'm':
+			total += time.Duration(n) * time.Minute
+		case 's':
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid print time %q", s)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,26 @@
+package gcode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTotalTime(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"1h 23m 45s":  time.Hour + 23*time.Minute + 45*time.Second,
+		"1d 2h 3m 4s": 26*time.Hour + 3*time.Minute + 4*time.Second,
+		" 35m 10s ":   35*time.Minute + 10*time.Second,
+		"2h3m0s":      2*time.Hour + 3*time.Minute,
+		"45s":         45 * time.Second,
+	} {
+		d, err := ParseTotalTime(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, d, in)
+	}
+	for _, in := range []string{"", "soon", "1x", "h"} {
+		_, err := ParseTotalTime(in)
+		assert.Error(t, err, in)
+	}
+}
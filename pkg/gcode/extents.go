@@ -0,0 +1,27 @@
+package gcode
+
+import (
+	"io"
+)
+
+/*
+Extents is the bounding box in mm of the extruding moves in a G-code file, i.e. of what actually gets printed.
+Moves is the number of extruding moves seen; the box is meaningless when it is 0.
+*/
+type Extents struct {
+	MinX  float64 `json:"minX"`
+	MaxX  float64 `json:"maxX"`
+	MinY  float64 `json:"minY"`
+	MaxY  float64 `json:"maxY"`
+	MinZ  float64 `json:"minZ"`
+	MaxZ  float64 `json:"maxZ"`
+	Moves int     `json:"moves"`
+}
+
+/*
+ScanExtents follows the moves in r and returns the extents of the ones that extrude
+*/
+func ScanExtents(r io.Reader) (Extents, error) {
+	stats, err := Analyze(r, NewGCodeConfig())
+	return stats.Extents, err
+}
@@ -0,0 +1,41 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanExtents(t *testing.T) {
+	ext, err := ScanExtents(strings.NewReader(`; purge line
+G90
+M83
+G1 Z0.2 F720
+G1 X0 Y-3 F1000 ; travel
+G1 X60 E9 F1000 ; intro line
+G1 X100 E12.5
+G92 E0
+G1 X10 Y10 Z0.2 F9000
+G1 X200 Y10 E5
+G1 Y150 E5
+G91
+G1 Z10 ; lift
+G1 X40 Y40 ; park
+G90
+G1 X120 Y100 Z30.4 E-1 ; retract only
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, ext.Moves)
+	assert.Equal(t, Extents{MinX: 0, MaxX: 200, MinY: -3, MaxY: 150, MinZ: 0.2, MaxZ: 0.2, Moves: 4}, ext)
+
+	ext, err = ScanExtents(strings.NewReader("M82\nG92 E0\nG1 X5 Y5 Z1\nG1 X6 E1\nG1 X10 E0.5\nG1 X20 E2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ext.Moves, "absolute extrusion only counts increases")
+	assert.Equal(t, 5.0, ext.MinX)
+	assert.Equal(t, 20.0, ext.MaxX)
+
+	ext, err = ScanExtents(strings.NewReader("G28\nG1 X10 Y10\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Extents{}, ext, "no extrusion, no extents")
+}
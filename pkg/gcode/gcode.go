@@ -20,6 +20,7 @@ import (
 type GCode struct {
 	MetaData GCodeMetaData
 	FilePath string
+	config   *GCodeConfig
 }
 
 type GCodeMetaData struct {
@@ -34,15 +35,55 @@ type GCodeMetaData struct {
 	FilamentUsedM  string `json:"filamentUsedM,omitempty"`
 	PrinterType    string `json:"printerType,omitempty"`
 	Thumbnail      string `json:"thumbnail,omitempty"`
+	// Flavor is the G-code dialect Cura sliced for, e.g. Marlin or Griffin
+	Flavor      string       `json:"flavor,omitempty"`
+	BoundingBox *BoundingBox `json:"boundingBox,omitempty"`
+	// Stats is worked out from the moves, it fills in what the slicer comments leave out
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+/*
+BoundingBox is the extents of the print in mm as the slicer reported them
+*/
+type BoundingBox struct {
+	MinX float64 `json:"minX"`
+	MaxX float64 `json:"maxX"`
+	MinY float64 `json:"minY"`
+	MaxY float64 `json:"maxY"`
+	MinZ float64 `json:"minZ"`
+	MaxZ float64 `json:"maxZ"`
+}
+
+/*
+FilamentMeters is the filament length the slicer reported, added up over extruders. PrusaSlicer reports mm,
+Cura and Marlin flavored files meters like "1.23m".
+*/
+func (m GCodeMetaData) FilamentMeters() float64 {
+	total := 0.0
+	for _, a := range strings.Split(m.FilamentUsedM, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(a), "m"), 64)
+		if err == nil {
+			total += v
+		}
+	}
+	if m.GCodeType == "PRUSA" {
+		return total / 1000
+	}
+	return total
 }
 
 func NewGCode(filePath string) *GCode {
 	return &GCode{
 		MetaData: GCodeMetaData{},
 		FilePath: filePath,
+		config:   NewGCodeConfig(),
 	}
 }
 
+/*
+ParseGCode reads what the slicer wrote in its comments. It doesn't follow the moves, call AnalyzeMoves as well
+where Stats are needed or the comments might leave something out.
+*/
 func (gc *GCode) ParseGCode(debug bool) error {
 	log.Infof("Parsing Gcode file: %v", gc.FilePath)
 	// first open the file
@@ -62,11 +103,12 @@ func (gc *GCode) ParseGCode(debug bool) error {
 	if strings.HasPrefix(line, ";") {
 		if gCodeType == "MARLIN" {
 			gc.ParseMarlin(scanner)
+		} else if gCodeType == "CURA" {
+			gc.ParseCura(scanner)
 		} else if gCodeType == "PRUSA" {
 			gc.ParsePrusa(scanner)
 		} else {
-			//log.Errorf("Unknown GCode Type")
-			return errors.New("unknown GCode Type")
+			log.Debugf("unknown GCode type of %v, only its moves can tell", gc.FilePath)
 		}
 	}
 
@@ -78,6 +120,43 @@ func (gc *GCode) ParseGCode(debug bool) error {
 	return nil
 }
 
+/*
+AnalyzeMoves follows the moves of the file into MetaData.Stats and fills in the print time, layer height and filament
+the slicer comments left out. It reads the whole file, so call it after ParseGCode and only where the stats are used.
+*/
+func (gc *GCode) AnalyzeMoves() error {
+	if gc.config == nil {
+		gc.config = NewGCodeConfig()
+	}
+	stats, err := FileStats(gc.FilePath, gc.config)
+	if err != nil {
+		log.Errorf("could not follow the moves of %v: %v", gc.FilePath, err)
+		return err
+	}
+	stats.FilamentG = gc.config.Grams(stats.ExtrusionMM, gc.MetaData.Material)
+	gc.MetaData.Stats = &stats
+
+	m := &gc.MetaData
+	if m.TotalTime == "" && stats.EstimatedSeconds > 0 {
+		m.TotalTime = stats.EstimatedTime().String()
+	}
+	if m.LayerHeight == "" && stats.Layers > 0 {
+		m.LayerHeight = strconv.FormatFloat(stats.LayerHeight(), 'f', -1, 64)
+	}
+	if m.FilamentUsedG == "" && stats.ExtrusionMM > 0 {
+		m.FilamentUsedG = strconv.FormatFloat(stats.FilamentG, 'f', 2, 64)
+	}
+	if m.FilamentUsedM == "" && stats.ExtrusionMM > 0 {
+		// the same units the slicer would have used, see FilamentMeters
+		if m.GCodeType == "PRUSA" {
+			m.FilamentUsedM = strconv.FormatFloat(stats.ExtrusionMM, 'f', 2, 64)
+		} else {
+			m.FilamentUsedM = strconv.FormatFloat(stats.ExtrusionMM/1000, 'f', 2, 64) + "m"
+		}
+	}
+	return nil
+}
+
 func (gc *GCode) ParsePrusa(scanner *bufio.Scanner) error {
 	lineNumber := 1
 	gc.MetaData.GCodeType = "PRUSA"
@@ -195,9 +274,114 @@ func (gc *GCode) ParseMarlin(scanner *bufio.Scanner) error {
 	return nil
 }
 
+/*
+ParseCura reads the header Cura writes before the first layer, in its Marlin style flavors or Ultimaker's Griffin,
+and the thumbnail its Create Thumbnail script adds
+*/
+func (gc *GCode) ParseCura(scanner *bufio.Scanner) error {
+	lineNumber := 1
+	gc.MetaData.GCodeType = "CURA"
+	line := scanner.Text()
+	for {
+		if strings.HasPrefix(line, ";") {
+			line = strings.TrimSpace(line[1:])
+			if strings.HasPrefix(line, "LAYER:") {
+				// the header is over, what follows is the print
+				break
+			}
+			if strings.HasPrefix(line, "thumbnail begin") || strings.HasPrefix(line, "thumbnail_JPG begin") {
+				mime := "image/png"
+				if strings.HasPrefix(line, "thumbnail_JPG") {
+					mime = "image/jpeg"
+				}
+				thumbNail, endLine := ExtractThumbnail(scanner, lineNumber)
+				lineNumber = endLine
+				gc.MetaData.Thumbnail = fmt.Sprintf("data:%s;base64,%s", mime, thumbNail)
+			} else {
+				gc.curaSetting(line)
+			}
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line = scanner.Text()
+		lineNumber++
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.New(fmt.Sprintf("error scanning %v line %v: %v", gc.FilePath, lineNumber, err))
+	}
+	return nil
+}
+
+/*
+curaSetting puts a "key:value" line of Cura's header into MetaData
+*/
+func (gc *GCode) curaSetting(line string) {
+	if strings.HasPrefix(line, "Generated with") {
+		gc.MetaData.CreatedBy = strings.TrimSpace(strings.TrimPrefix(line, "Generated with"))
+		return
+	}
+	k, v, ok := strings.Cut(line, ":")
+	if !ok {
+		return
+	}
+	v = strings.TrimSpace(v)
+	number := func(set func(*BoundingBox, float64)) {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Errorf("%v in %v is not a number: %v", k, gc.FilePath, v)
+			return
+		}
+		if gc.MetaData.BoundingBox == nil {
+			gc.MetaData.BoundingBox = &BoundingBox{}
+		}
+		set(gc.MetaData.BoundingBox, f)
+	}
+	switch strings.TrimSpace(k) {
+	case "FLAVOR":
+		gc.MetaData.Flavor = v
+	case "TIME", "PRINT.TIME":
+		tInt, err := strconv.Atoi(v)
+		if err != nil {
+			log.Errorf(err.Error())
+			return
+		}
+		gc.MetaData.TotalTime = (time.Duration(tInt) * time.Second).String()
+	case "Filament used":
+		gc.MetaData.FilamentUsedM = v
+	case "Layer height":
+		gc.MetaData.LayerHeight = v
+	case "TARGET_MACHINE.NAME":
+		gc.MetaData.PrinterType = v
+	case "EXTRUDER_TRAIN.0.NOZZLE.DIAMETER":
+		gc.MetaData.NozzleDiameter = v
+	case "GENERATOR.NAME":
+		gc.MetaData.CreatedBy = v
+	case "GENERATOR.VERSION":
+		gc.MetaData.CreatedBy = strings.TrimSpace(gc.MetaData.CreatedBy + " " + v)
+	case "MINX", "PRINT.SIZE.MIN.X":
+		number(func(b *BoundingBox, f float64) { b.MinX = f })
+	case "MINY", "PRINT.SIZE.MIN.Y":
+		number(func(b *BoundingBox, f float64) { b.MinY = f })
+	case "MINZ", "PRINT.SIZE.MIN.Z":
+		number(func(b *BoundingBox, f float64) { b.MinZ = f })
+	case "MAXX", "PRINT.SIZE.MAX.X":
+		number(func(b *BoundingBox, f float64) { b.MaxX = f })
+	case "MAXY", "PRINT.SIZE.MAX.Y":
+		number(func(b *BoundingBox, f float64) { b.MaxY = f })
+	case "MAXZ", "PRINT.SIZE.MAX.Z":
+		number(func(b *BoundingBox, f float64) { b.MaxZ = f })
+	}
+}
+
+/*
+GetGCodeType tells the slicer from the first line of a file. Cura starts with its flavor, or a header for Griffin.
+*/
 func GetGCodeType(line string) string {
 	if strings.Contains(line, "PrusaSlicer") {
 		return "PRUSA"
+	} else if strings.HasPrefix(line, ";FLAVOR:") || strings.HasPrefix(line, ";START_OF_HEADER") {
+		return "CURA"
 	} else if strings.Contains(line, "Marlin") {
 		return "MARLIN"
 	} else {
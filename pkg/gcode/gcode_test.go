@@ -0,0 +1,72 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilamentMeters(t *testing.T) {
+	assert.Equal(t, 1.5, GCodeMetaData{GCodeType: "PRUSA", FilamentUsedM: "1000.0, 500.0"}.FilamentMeters())
+	assert.Equal(t, 2.25, GCodeMetaData{GCodeType: "MARLIN", FilamentUsedM: " 2.25m"}.FilamentMeters())
+	assert.Equal(t, 0.0, GCodeMetaData{}.FilamentMeters())
+}
+
+func TestParseGCode_WithoutSlicerComments(t *testing.T) {
+	g := NewGCode("testdata/plain.gcode")
+	assert.NoError(t, g.ParseGCode(false))
+	assert.Nil(t, g.MetaData.Stats, "only the comments are read")
+	assert.NoError(t, g.AnalyzeMoves())
+	m := g.MetaData
+	if assert.NotNil(t, m.Stats) {
+		assert.Equal(t, 2, m.Stats.Layers)
+	}
+	assert.NotEmpty(t, m.TotalTime)
+	assert.Equal(t, "0.2", m.LayerHeight)
+	assert.Equal(t, "0.04", m.FilamentUsedG)
+	assert.Equal(t, "0.01m", m.FilamentUsedM)
+	assert.Equal(t, 0.01, m.FilamentMeters())
+}
+
+func TestGetGCodeType(t *testing.T) {
+	assert.Equal(t, "PRUSA", GetGCodeType("; generated by PrusaSlicer 2.6.1+win64 on 2023-09-25 at 22:53:15 UTC"))
+	assert.Equal(t, "CURA", GetGCodeType(";FLAVOR:Marlin"))
+	assert.Equal(t, "CURA", GetGCodeType(";START_OF_HEADER"))
+	assert.Equal(t, "MARLIN", GetGCodeType("; Marlin"))
+	assert.Equal(t, "UNK", GetGCodeType("G28"))
+}
+
+func TestParseGCode_Cura(t *testing.T) {
+	g := NewGCode("testdata/cura.gcode")
+	assert.NoError(t, g.ParseGCode(false))
+	assert.NoError(t, g.AnalyzeMoves())
+	m := g.MetaData
+	assert.Equal(t, "CURA", m.GCodeType)
+	assert.Equal(t, "Marlin", m.Flavor)
+	assert.Equal(t, "1h15m21s", m.TotalTime, "the header's time, not the ones after it")
+	assert.Equal(t, "1.52345m", m.FilamentUsedM)
+	assert.Equal(t, 1.52345, m.FilamentMeters())
+	assert.Equal(t, "0.2", m.LayerHeight)
+	assert.Equal(t, "Creality Ender-3 Pro", m.PrinterType)
+	assert.Equal(t, "Cura_SteamEngine 5.4.0", m.CreatedBy)
+	assert.Equal(t, &BoundingBox{MinX: 95.2, MaxX: 124.8, MinY: 96.4, MaxY: 123.6, MinZ: 0.2, MaxZ: 0.4}, m.BoundingBox)
+	if assert.True(t, strings.HasPrefix(m.Thumbnail, "data:image/png;base64,")) {
+		img, err := B64ToImg(strings.TrimPrefix(m.Thumbnail, "data:image/png;base64,"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, img.Bounds().Dx())
+	}
+	if assert.NotNil(t, m.Stats) {
+		assert.Equal(t, 2, m.Stats.Layers)
+	}
+
+	g = NewGCode("testdata/griffin.gcode")
+	assert.NoError(t, g.ParseGCode(false))
+	m = g.MetaData
+	assert.Equal(t, "Griffin", m.Flavor)
+	assert.Equal(t, "1h0m0s", m.TotalTime)
+	assert.Equal(t, "Ultimaker S5", m.PrinterType)
+	assert.Equal(t, "0.4", m.NozzleDiameter)
+	assert.Equal(t, "Cura_SteamEngine 5.4.0", m.CreatedBy)
+	assert.Equal(t, &BoundingBox{MinX: 10, MaxX: 60, MinY: 20, MaxY: 70, MinZ: 0.27, MaxZ: 15}, m.BoundingBox)
+}
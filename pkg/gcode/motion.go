@@ -0,0 +1,462 @@
+package gcode
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ARC_SEGMENT_MM is how long the straight pieces G2/G3 arcs are followed in are, as firmware does
+	ARC_SEGMENT_MM = 1.0
+	// LAYER_EPSILON_MM is the smallest rise in Z taken as a new layer
+	LAYER_EPSILON_MM = 0.001
+	// _MAX_LINE is the longest line looked at, longer ones are skipped
+	_MAX_LINE = 1024 * 1024
+)
+
+/*
+Stats is what following the moves of a G-code file tells about the print, whatever sliced it.
+LayerHeights has the height of each layer over the one below it, the first over the bed.
+ExtrusionMM is the filament fed in, less what was retracted and not fed back.
+EstimatedSeconds leaves out heating, homing and bed leveling, which depend on the printer.
+*/
+type Stats struct {
+	Layers           int       `json:"layers"`
+	LayerHeights     []float64 `json:"layerHeights,omitempty"`
+	Extents          Extents   `json:"extents"`
+	ExtrusionMM      float64   `json:"extrusionMm"`
+	FilamentG        float64   `json:"filamentG"`
+	EstimatedSeconds float64   `json:"estimatedSeconds"`
+}
+
+/*
+EstimatedTime is EstimatedSeconds to the second
+*/
+func (s Stats) EstimatedTime() time.Duration {
+	return (time.Duration(s.EstimatedSeconds * float64(time.Second))).Round(time.Second)
+}
+
+/*
+LayerHeight is the most common layer height, which is the one the file was sliced with unless it varies
+*/
+func (s Stats) LayerHeight() float64 {
+	counts := map[float64]int{}
+	best := 0.0
+	for _, h := range s.LayerHeights {
+		counts[h]++
+		if counts[h] > counts[best] || (counts[h] == counts[best] && h < best) {
+			best = h
+		}
+	}
+	return best
+}
+
+/*
+FileStats follows the moves of the G-code file at filePath
+*/
+func FileStats(filePath string, config *GCodeConfig) (Stats, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer file.Close()
+	return Analyze(file, config)
+}
+
+/*
+Analyze interprets the G-code in r: G0/G1 moves, G2/G3 arcs, G4 dwells, G90/G91, M82/M83, G92, G20/G21 and the
+acceleration and jerk set by M204 and M205. Print time is worked out from trapezoid speed profiles, slowing for corners
+as the jerk allows. FilamentG is for config's default density.
+*/
+func Analyze(r io.Reader, config *GCodeConfig) (Stats, error) {
+	m := newMachine(config)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), _MAX_LINE)
+	scanner.Split(skipLongLines(_MAX_LINE))
+	for scanner.Scan() {
+		m.execute(scanner.Text())
+	}
+	m.flush()
+	stats := m.stats
+	if stats.Extents.Moves == 0 {
+		stats.Extents = Extents{}
+	}
+	stats.ExtrusionMM = math.Max(0, stats.ExtrusionMM)
+	stats.FilamentG = config.Grams(stats.ExtrusionMM, "")
+	return stats, scanner.Err()
+}
+
+/*
+skipLongLines splits like bufio.ScanLines but skips lines of max bytes or more instead of failing with
+bufio.ErrTooLong. Such lines are never moves, e.g. an image a slicer embedded in one line.
+*/
+func skipLongLines(max int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipping {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				skipping = false
+				return i + 1, nil, nil
+			}
+			return len(data), nil, nil
+		}
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance == 0 && token == nil && err == nil && len(data) >= max {
+			skipping = true
+			return len(data), nil, nil
+		}
+		return advance, token, err
+	}
+}
+
+/*
+segment is a straight move waiting to be timed until the one after it says how fast it can end
+*/
+type segment struct {
+	unit   [4]float64
+	length float64
+	speed  float64
+	accel  float64
+	entry  float64
+}
+
+/*
+machine is the state of the printer the G-code drives. pos is X, Y, Z and E in mm.
+*/
+type machine struct {
+	config      *GCodeConfig
+	pos         [4]float64
+	relative    bool
+	relativeE   bool
+	scale       float64
+	feedrate    float64
+	accel       float64
+	travelAccel float64
+	jerk        float64
+	pending     *segment
+	top         float64
+	stats       Stats
+}
+
+func newMachine(config *GCodeConfig) *machine {
+	return &machine{
+		config:      config,
+		scale:       1,
+		feedrate:    config.MaxFeedrate,
+		accel:       config.Acceleration,
+		travelAccel: config.TravelAcceleration,
+		jerk:        config.Jerk,
+		stats: Stats{
+			Extents: Extents{
+				MinX: math.Inf(1), MaxX: math.Inf(-1),
+				MinY: math.Inf(1), MaxY: math.Inf(-1),
+				MinZ: math.Inf(1), MaxZ: math.Inf(-1),
+			},
+			LayerHeights: []float64{},
+		},
+	}
+}
+
+/*
+words splits a line like "N12 G1X10 Y-2.5 E.4*71 ; infill" into its letters and numbers, leaving out line numbers,
+checksums and comments
+*/
+func words(line string) (code string, params map[byte]float64) {
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.IndexByte(line, '*'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.ToUpper(line)
+	params = map[byte]float64{}
+	for i := 0; i < len(line); {
+		letter := line[i]
+		i++
+		if letter < 'A' || letter > 'Z' {
+			continue
+		}
+		start := i
+		for i < len(line) && (line[i] == ' ' && i == start || strings.IndexByte("+-.0123456789", line[i]) >= 0) {
+			i++
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(line[start:i]), 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case letter == 'N' && code == "":
+		case code == "" && (letter == 'G' || letter == 'M' || letter == 'T'):
+			code = string(letter) + strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			params[letter] = v
+		}
+	}
+	return code, params
+}
+
+func (m *machine) execute(line string) {
+	code, params := words(line)
+	switch code {
+	case "G0", "G1":
+		target := m.target(params)
+		m.setFeedrate(params)
+		m.line(target)
+	case "G2", "G3":
+		m.setFeedrate(params)
+		m.arc(params, code == "G2")
+	case "G4":
+		m.flush()
+		m.stats.EstimatedSeconds += params['P']/1000 + params['S']
+	case "G20":
+		m.scale = 25.4
+	case "G21":
+		m.scale = 1
+	case "G28":
+		m.flush()
+		homeAll := true
+		for i, axis := range []byte{'X', 'Y', 'Z'} {
+			if _, ok := params[axis]; ok {
+				homeAll = false
+				m.pos[i] = 0
+			}
+		}
+		if homeAll {
+			m.pos[0], m.pos[1], m.pos[2] = 0, 0, 0
+		}
+	case "G90":
+		m.relative, m.relativeE = false, false
+	case "G91":
+		m.relative, m.relativeE = true, true
+	case "M82":
+		m.relativeE = false
+	case "M83":
+		m.relativeE = true
+	case "G92":
+		for i, axis := range []byte{'X', 'Y', 'Z', 'E'} {
+			if v, ok := params[axis]; ok {
+				m.pos[i] = v * m.scale
+			}
+		}
+	case "M204":
+		if v, ok := params['S']; ok {
+			m.accel, m.travelAccel = v, v
+		}
+		if v, ok := params['P']; ok {
+			m.accel = v
+		}
+		if v, ok := params['T']; ok {
+			m.travelAccel = v
+		}
+	case "M205":
+		if v, ok := params['X']; ok {
+			m.jerk = v
+		}
+	}
+}
+
+func (m *machine) setFeedrate(params map[byte]float64) {
+	if f, ok := params['F']; ok && f > 0 {
+		m.feedrate = f * m.scale / 60
+	}
+}
+
+/*
+target is where a G0/G1 with params ends up
+*/
+func (m *machine) target(params map[byte]float64) [4]float64 {
+	target := m.pos
+	for i, axis := range []byte{'X', 'Y', 'Z', 'E'} {
+		v, ok := params[axis]
+		if !ok {
+			continue
+		}
+		relative := m.relative
+		if axis == 'E' {
+			relative = m.relativeE
+		}
+		target[i] = move(m.pos[i], v*m.scale, relative)
+	}
+	return target
+}
+
+func move(current, v float64, relative bool) float64 {
+	if relative {
+		return current + v
+	}
+	return v
+}
+
+/*
+arc follows a G2 (clockwise) or G3 arc around the centre at I J from the start, or of radius R, in straight pieces
+*/
+func (m *machine) arc(params map[byte]float64, clockwise bool) {
+	target := m.target(params)
+	start := m.pos
+	cx, cy := start[0]+params['I']*m.scale, start[1]+params['J']*m.scale
+	if r, ok := params['R']; ok && r != 0 {
+		r *= m.scale
+		dx, dy := target[0]-start[0], target[1]-start[1]
+		d := math.Hypot(dx, dy)
+		if d == 0 {
+			m.line(target)
+			return
+		}
+		// the centre is on the perpendicular bisector, on the side that makes the arc turn the right way
+		side := 1.0
+		if clockwise != (r < 0) {
+			side = -1
+		}
+		h := math.Sqrt(math.Max(0, r*r-d*d/4))
+		cx = (start[0]+target[0])/2 - side*h*dy/d
+		cy = (start[1]+target[1])/2 + side*h*dx/d
+	}
+	radius := math.Hypot(start[0]-cx, start[1]-cy)
+	from := math.Atan2(start[1]-cy, start[0]-cx)
+	to := math.Atan2(target[1]-cy, target[0]-cx)
+	sweep := to - from
+	if clockwise {
+		sweep = from - to
+	}
+	if sweep <= 0 {
+		// ending where it started goes all the way round
+		sweep += 2 * math.Pi
+	}
+	pieces := int(math.Max(1, math.Ceil(sweep*radius/ARC_SEGMENT_MM)))
+	direction := 1.0
+	if clockwise {
+		direction = -1
+	}
+	for i := 1; i < pieces; i++ {
+		f := float64(i) / float64(pieces)
+		angle := from + direction*sweep*f
+		m.line([4]float64{
+			cx + radius*math.Cos(angle),
+			cy + radius*math.Sin(angle),
+			start[2] + (target[2]-start[2])*f,
+			start[3] + (target[3]-start[3])*f,
+		})
+	}
+	m.line(target)
+}
+
+/*
+line moves straight to target, adding to the extents, layers and extrusion when it extrudes, and queues it to be timed
+*/
+func (m *machine) line(target [4]float64) {
+	from := m.pos
+	m.pos = target
+	var delta [4]float64
+	for i := range delta {
+		delta[i] = target[i] - from[i]
+	}
+	m.stats.ExtrusionMM += delta[3]
+	extruding := delta[3] > 0
+	if extruding {
+		ext := &m.stats.Extents
+		ext.Moves++
+		// a line is straight, so its ends bound it
+		ext.MinX, ext.MaxX = math.Min(ext.MinX, math.Min(from[0], target[0])), math.Max(ext.MaxX, math.Max(from[0], target[0]))
+		ext.MinY, ext.MaxY = math.Min(ext.MinY, math.Min(from[1], target[1])), math.Max(ext.MaxY, math.Max(from[1], target[1]))
+		ext.MinZ, ext.MaxZ = math.Min(ext.MinZ, math.Min(from[2], target[2])), math.Max(ext.MaxZ, math.Max(from[2], target[2]))
+		// priming in place isn't printing a layer
+		if (delta[0] != 0 || delta[1] != 0) && target[2] > m.top+LAYER_EPSILON_MM {
+			m.stats.Layers++
+			m.stats.LayerHeights = append(m.stats.LayerHeights, math.Round((target[2]-m.top)*1000)/1000)
+			m.top = target[2]
+		}
+	}
+
+	length := math.Sqrt(delta[0]*delta[0] + delta[1]*delta[1] + delta[2]*delta[2])
+	if length == 0 {
+		// extruder only moves, like retractions, are timed along the filament
+		length = math.Abs(delta[3])
+		delta = [4]float64{0, 0, 0, delta[3]}
+	} else {
+		delta[3] = 0
+	}
+	if length == 0 {
+		return
+	}
+	s := &segment{length: length, speed: math.Min(m.feedrate, m.config.MaxFeedrate), accel: m.travelAccel}
+	if extruding {
+		s.accel = m.accel
+	}
+	for i := range delta {
+		s.unit[i] = delta[i] / length
+	}
+	m.plan(s)
+}
+
+/*
+plan times the waiting segment now that the next one, s, says how fast it can go into it, and makes s wait
+*/
+func (m *machine) plan(s *segment) {
+	if m.pending != nil {
+		p := m.pending
+		dot := 0.0
+		for i := range p.unit {
+			dot += p.unit[i] * s.unit[i]
+		}
+		// the fastest the corner can be taken while changing velocity by no more than the jerk
+		junction := math.Min(p.speed, s.speed)
+		if change := math.Sqrt(math.Max(0, 2-2*dot)); change > 0 {
+			junction = math.Min(junction, m.jerk/change)
+		}
+		junction = math.Min(junction, reachable(p.entry, p.accel, p.length))
+		m.stats.EstimatedSeconds += moveTime(p.length, p.entry, p.speed, junction, p.accel)
+		s.entry = junction
+	}
+	m.pending = s
+}
+
+/*
+flush times the waiting segment coming to a stop
+*/
+func (m *machine) flush() {
+	if m.pending == nil {
+		return
+	}
+	p := m.pending
+	m.stats.EstimatedSeconds += moveTime(p.length, p.entry, p.speed, 0, p.accel)
+	m.pending = nil
+}
+
+/*
+reachable is the fastest a move of length can end at, starting at entry
+*/
+func reachable(entry, accel, length float64) float64 {
+	if accel <= 0 {
+		return math.Inf(1)
+	}
+	return math.Sqrt(entry*entry + 2*accel*length)
+}
+
+/*
+moveTime is how long a move takes speeding up from entry to cruise and slowing down to exit, in seconds
+*/
+func moveTime(length, entry, cruise, exit, accel float64) float64 {
+	if cruise <= 0 {
+		return 0
+	}
+	if accel <= 0 {
+		return length / cruise
+	}
+	accelDist := (cruise*cruise - entry*entry) / (2 * accel)
+	decelDist := (cruise*cruise - exit*exit) / (2 * accel)
+	if accelDist+decelDist <= length {
+		return (cruise-entry)/accel + (cruise-exit)/accel + (length-accelDist-decelDist)/cruise
+	}
+	// too short to reach cruise, it peaks where speeding up meets slowing down
+	peak := math.Sqrt((2*accel*length + entry*entry + exit*exit) / 2)
+	if peak < math.Max(entry, exit) {
+		return 2 * length / (entry + exit)
+	}
+	return (peak-entry)/accel + (peak-exit)/accel
+}
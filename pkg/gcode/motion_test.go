@@ -0,0 +1,93 @@
+package gcode
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() *GCodeConfig {
+	return &GCodeConfig{Acceleration: 1000, TravelAcceleration: 1000, Jerk: 8, MaxFeedrate: 200, FilamentDiameter: 1.75, DefaultDensity: 1.24}
+}
+
+func TestWords(t *testing.T) {
+	code, params := words("N12 G1X10 Y-2.5 E.4*71 ; infill")
+	assert.Equal(t, "G1", code)
+	assert.Equal(t, map[byte]float64{'X': 10, 'Y': -2.5, 'E': 0.4}, params)
+
+	code, params = words("g01 x1")
+	assert.Equal(t, "G1", code)
+	assert.Equal(t, 1.0, params['X'])
+
+	code, _ = words("; just a comment")
+	assert.Empty(t, code)
+}
+
+func TestMoveTime(t *testing.T) {
+	// 5mm speeding up to 100mm/s, 90mm at it and 5mm slowing down
+	assert.InDelta(t, 1.1, moveTime(100, 0, 100, 0, 1000), 1e-9)
+	// never gets to cruise, peaks at 10mm/s
+	assert.InDelta(t, 0.02, moveTime(0.1, 0, 100, 0, 1000), 1e-9)
+	assert.InDelta(t, 1.0, moveTime(100, 100, 100, 100, 1000), 1e-9)
+}
+
+func TestAnalyze(t *testing.T) {
+	stats, err := FileStats("testdata/plain.gcode", testConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Layers)
+	assert.Equal(t, []float64{0.3, 0.2}, stats.LayerHeights)
+	assert.Equal(t, 0.2, stats.LayerHeight())
+	assert.Equal(t, Extents{MinX: 10, MaxX: 110, MinY: 10, MaxY: 110, MinZ: 0.3, MaxZ: 0.5, Moves: 4}, stats.Extents,
+		"the retraction and prime are in place")
+	assert.InDelta(t, 15.0, stats.ExtrusionMM, 1e-9, "retracting and priming cancel out")
+	assert.InDelta(t, 15*math.Pi*0.875*0.875/1000*1.24, stats.FilamentG, 1e-9)
+	// 300mm of extruding at 50mm/s, 114mm of travel at 100mm/s, the lifts at 10mm/s and the dwell
+	assert.InDelta(t, 9, stats.EstimatedSeconds, 0.5)
+	assert.Equal(t, "9s", stats.EstimatedTime().String())
+
+	slow := testConfig()
+	slow.Acceleration, slow.TravelAcceleration = 100, 100
+	slowStats, _ := FileStats("testdata/plain.gcode", slow)
+	assert.Equal(t, stats.EstimatedSeconds, slowStats.EstimatedSeconds, "the file's M204 wins")
+	slowStats, _ = Analyze(strings.NewReader("G1 X100 F6000\nG1 Y100\n"), slow)
+	stats, _ = Analyze(strings.NewReader("G1 X100 F6000\nG1 Y100\n"), testConfig())
+	assert.Greater(t, slowStats.EstimatedSeconds, stats.EstimatedSeconds)
+}
+
+func TestAnalyze_Arcs(t *testing.T) {
+	stats, err := Analyze(strings.NewReader(`G90
+M83
+G1 X10 Y0 Z0.2 F6000
+G2 X0 Y-10 I-10 J0 E1 ; quarter circle clockwise round the origin
+G3 X0 Y10 R10 E1 ; half circle counterclockwise, the short way round
+G1 X0 Y0 F600
+G3 X0 Y0 I5 J0 E1 ; a full circle to the right of the origin
+`), testConfig())
+	assert.NoError(t, err)
+	ext := stats.Extents
+	assert.InDelta(t, 0, ext.MinX, 0.01)
+	assert.InDelta(t, 10, ext.MaxX, 0.01)
+	assert.InDelta(t, -10, ext.MinY, 0.01)
+	assert.InDelta(t, 10, ext.MaxY, 0.01)
+	assert.InDelta(t, 3, stats.ExtrusionMM, 1e-9)
+	assert.Equal(t, 1, stats.Layers)
+}
+
+func TestAnalyze_Units(t *testing.T) {
+	stats, err := Analyze(strings.NewReader("G20\nG91\nG1 Z0.01\nG1 X1 E0.1 F10\n"), testConfig())
+	assert.NoError(t, err)
+	assert.InDelta(t, 25.4, stats.Extents.MaxX, 1e-9)
+	assert.InDelta(t, 2.54, stats.ExtrusionMM, 1e-9)
+	assert.Equal(t, []float64{0.254}, stats.LayerHeights)
+	// an inch at 10in/min
+	assert.InDelta(t, 6, stats.EstimatedSeconds, 0.1)
+}
+
+func TestAnalyze_LongLine(t *testing.T) {
+	gcode := "G1 Z0.2\nG1 X10 E1 F6000\n; " + strings.Repeat("A", 2*_MAX_LINE) + "\nG1 X20 E2\n"
+	stats, err := Analyze(strings.NewReader(gcode), testConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, stats.Extents.MaxX, "moves after the long line are followed")
+}
@@ -0,0 +1,41 @@
+;FLAVOR:Marlin
+;TIME:4521
+;Filament used: 1.52345m
+;Layer height: 0.2
+;MINX:95.2
+;MINY:96.4
+;MINZ:0.2
+;MAXX:124.8
+;MAXY:123.6
+;MAXZ:0.4
+;TARGET_MACHINE.NAME:Creality Ender-3 Pro
+;Generated with Cura_SteamEngine 5.4.0
+;
+; thumbnail begin 2x2 100
+; iVBORw0KGgoAAAANSUhEUgAAAAIAAAACCAIAAAD9
+; 1JpzAAAAEElEQVR4nGP4z8AARAwQCgAf7gP9i18U
+; 1AAAAABJRU5ErkJggg==
+; thumbnail end
+;
+M140 S60
+M105
+M190 S60
+M104 S200
+M109 S200
+M82 ;absolute extrusion mode
+G28 ;Home
+G92 E0
+;LAYER_COUNT:2
+;LAYER:0
+M107
+G0 F6000 X95.2 Y96.4 Z0.2
+;TYPE:WALL-OUTER
+G1 F1800 X124.8 Y96.4 E1.0
+G1 X124.8 Y123.6 E2.0
+;TIME_ELAPSED:10.5
+;LAYER:1
+G0 Z0.4
+G1 X95.2 Y123.6 E3.0
+;TIME:99
+;TIME_ELAPSED:20.1
+;End of Gcode
@@ -0,0 +1,26 @@
+;START_OF_HEADER
+;HEADER_VERSION:0.1
+;FLAVOR:Griffin
+;GENERATOR.NAME:Cura_SteamEngine
+;GENERATOR.VERSION:5.4.0
+;GENERATOR.BUILD_DATE:2023-06-21
+;TARGET_MACHINE.NAME:Ultimaker S5
+;EXTRUDER_TRAIN.0.INITIAL_TEMPERATURE:215
+;EXTRUDER_TRAIN.0.MATERIAL.VOLUME_USED:2211
+;EXTRUDER_TRAIN.0.NOZZLE.DIAMETER:0.4
+;EXTRUDER_TRAIN.0.NOZZLE.NAME:AA 0.4
+;BUILD_PLATE.INITIAL_TEMPERATURE:60
+;PRINT.GROUPS:1
+;PRINT.TIME:3600
+;PRINT.SIZE.MIN.X:10
+;PRINT.SIZE.MIN.Y:20
+;PRINT.SIZE.MIN.Z:0.27
+;PRINT.SIZE.MAX.X:60
+;PRINT.SIZE.MAX.Y:70
+;PRINT.SIZE.MAX.Z:15
+;END_OF_HEADER
+;Generated with Cura_SteamEngine 5.4.0
+;LAYER_COUNT:1
+;LAYER:0
+G0 F6000 X10 Y20 Z0.27
+G1 F1800 X60 Y20 E2
@@ -0,0 +1,18 @@
+G21
+G90
+M82
+M204 P1000 T1000
+M205 X8
+G28
+G92 E0
+G1 Z0.3 F600
+G1 X10 Y10 F6000
+G1 X110 Y10 E5 F3000
+G1 X110 Y110 E10
+G1 E9 F2400
+G1 Z0.5 F600
+G1 X10 Y110 F6000
+G1 E10 F2400
+G1 X10 Y10 E15 F3000
+G4 P500
+G1 Z10 F600
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	_NOTIFICATIONS = "notifications"
+)
+
+/*
+Webhook is an endpoint notified of print events. Events limits it to some of the EVENT_* names, all events are sent
+when it is empty. Template formats the payload for services that expect their own shape, see the TEMPLATE_* names.
+*/
+type Webhook struct {
+	URL      string   `toml:"url" json:"url"`
+	Secret   string   `toml:"secret" json:"-"`
+	Events   []string `toml:"events" json:"events"`
+	Template string   `toml:"template" json:"template"`
+}
+
+type NotificationsConfig struct {
+	// Retries is how many more times a failed delivery is attempted
+	Retries int `toml:"retries"`
+	// Backoff is the wait before the first retry in seconds, doubled for every retry after it
+	Backoff  int       `toml:"backoff"`
+	Webhooks []Webhook `toml:"webhooks"`
+}
+
+func NewNotificationsConfig() *NotificationsConfig {
+	c := &NotificationsConfig{
+		Retries:  5,
+		Backoff:  2,
+		Webhooks: []Webhook{},
+	}
+
+	h := viper.Sub(_NOTIFICATIONS)
+	if h != nil {
+		err := h.Unmarshal(c)
+		if err != nil {
+			log.Error(_NOTIFICATIONS, " config error: ", err.Error())
+		}
+	}
+	return c
+}
+
+func (c *NotificationsConfig) StringJSON() string {
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func (c *NotificationsConfig) StringToml() (config string) {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNotificationsConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		configFile string
+		want       *NotificationsConfig
+	}{
+		{
+			"No Config File",
+			"",
+			&NotificationsConfig{
+				Retries:  5,
+				Backoff:  2,
+				Webhooks: []Webhook{},
+			},
+		},
+		{
+			"With Good Config File",
+			"testdata/goodConfig.toml",
+			&NotificationsConfig{
+				Retries: 2,
+				Backoff: 2,
+				Webhooks: []Webhook{
+					{URL: "https://ntfy.sh/ymir-farm", Template: TEMPLATE_NTFY, Events: []string{EVENT_FINISHED, EVENT_FAILED}},
+					{URL: "https://example.com/hooks/ymir", Secret: "s3cret"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.configFile != "" {
+				//run viper first to get valid config
+				viper.SetConfigFile(tt.configFile)
+				if err := viper.ReadInConfig(); err != nil {
+					t.Errorf("Error reading config file %v: %v\n", tt.configFile, err)
+				}
+			}
+			assert.Equalf(t, tt.want, NewNotificationsConfig(), "Should Be Equal")
+		})
+	}
+}
@@ -0,0 +1,384 @@
+/*
+Package notify sends print events to outbound webhooks, either as Ymir's own JSON payload or formatted for ntfy,
+Discord or Slack.
+*/
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	jobtypes "ymir/pkg/api/job/types"
+	"ymir/pkg/api/printer/types"
+	bus "ymir/pkg/events"
+	"ymir/pkg/printer"
+)
+
+const (
+	EVENT_STARTED   = "print.started"
+	EVENT_FINISHED  = "print.finished"
+	EVENT_FAILED    = "print.failed"
+	EVENT_CANCELLED = "print.cancelled"
+	EVENT_OFFLINE   = "printer.offline"
+
+	// SIGNATURE_HEADER carries "sha256=" and the hex HMAC-SHA256 of the body when the webhook has a secret
+	SIGNATURE_HEADER = "X-Ymir-Signature"
+	EVENT_HEADER     = "X-Ymir-Event"
+	TIMEOUT          = 10 * time.Second
+)
+
+var (
+	EVENTS = []string{EVENT_STARTED, EVENT_FINISHED, EVENT_FAILED, EVENT_CANCELLED, EVENT_OFFLINE}
+
+	// finished maps how a job ended to the event sent for it. Jobs that ended unknown were never seen finishing.
+	finished = map[string]string{
+		jobtypes.JOB_STATE_COMPLETED: EVENT_FINISHED,
+		jobtypes.JOB_STATE_FAILED:    EVENT_FAILED,
+		jobtypes.JOB_STATE_CANCELLED: EVENT_CANCELLED,
+	}
+)
+
+/*
+Payload is the JSON body sent for an event. Duration is in seconds.
+*/
+type Payload struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Printer    Printer   `json:"printer"`
+	Model      *Model    `json:"model,omitempty"`
+	File       string    `json:"file,omitempty"`
+	Duration   float64   `json:"duration,omitempty"`
+	Completion float64   `json:"completion,omitempty"`
+	User       string    `json:"user,omitempty"`
+}
+
+type Printer struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Location string `json:"location,omitempty"`
+}
+
+type Model struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+/*
+Title is a one line summary for chat services
+*/
+func (p Payload) Title() string {
+	switch p.Event {
+	case EVENT_STARTED:
+		return fmt.Sprintf("Print started on %v", p.Printer.Name)
+	case EVENT_FINISHED:
+		return fmt.Sprintf("Print finished on %v", p.Printer.Name)
+	case EVENT_FAILED:
+		return fmt.Sprintf("Print failed on %v", p.Printer.Name)
+	case EVENT_CANCELLED:
+		return fmt.Sprintf("Print cancelled on %v", p.Printer.Name)
+	case EVENT_OFFLINE:
+		return fmt.Sprintf("%v is offline", p.Printer.Name)
+	}
+	return p.Event
+}
+
+/*
+Message describes the event for chat services
+*/
+func (p Payload) Message() string {
+	if p.Event == EVENT_OFFLINE {
+		if p.Printer.Location != "" {
+			return fmt.Sprintf("%v in %v stopped answering.", p.Printer.Name, p.Printer.Location)
+		}
+		return fmt.Sprintf("%v stopped answering.", p.Printer.Name)
+	}
+	msg := filepath.Base(p.File)
+	if p.Model != nil && p.Model.Name != "" {
+		msg = fmt.Sprintf("%v (%v)", msg, p.Model.Name)
+	}
+	switch p.Event {
+	case EVENT_STARTED:
+		return msg
+	case EVENT_FINISHED:
+		return fmt.Sprintf("%v took %v.", msg, duration(p.Duration))
+	}
+	return fmt.Sprintf("%v stopped at %.0f%% after %v.", msg, p.Completion, duration(p.Duration))
+}
+
+func duration(seconds float64) time.Duration {
+	return (time.Duration(seconds) * time.Second).Round(time.Second)
+}
+
+/*
+PrinterInspector is the part of the PrinterStore the notifier needs
+*/
+type PrinterInspector interface {
+	Inspect(id string) (types.Printer, error)
+}
+
+/*
+StatusError is returned when a webhook answers with anything but 2xx
+*/
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook returned %v", e.Status)
+}
+
+/*
+Notifier follows job and printer events and delivers them to the webhooks that want them. Deliveries run in the
+background and are retried with exponential backoff so a slow or failing endpoint does not hold up the others.
+*/
+type Notifier struct {
+	hooks    []Webhook
+	printers PrinterInspector
+	client   *http.Client
+	retries  int
+	backoff  time.Duration
+
+	lock        sync.Mutex
+	unsubscribe func()
+	done        chan struct{}
+	stop        chan struct{}
+	deliveries  sync.WaitGroup
+}
+
+/*
+NewNotifier keeps the configured webhooks that are valid and logs the others
+*/
+func NewNotifier(config *NotificationsConfig, printers PrinterInspector) *Notifier {
+	n := &Notifier{
+		printers: printers,
+		client:   &http.Client{Timeout: TIMEOUT},
+		retries:  config.Retries,
+		backoff:  time.Duration(config.Backoff) * time.Second,
+	}
+	for _, h := range config.Webhooks {
+		if err := h.validate(); err != nil {
+			log.Errorf("ignoring webhook %v: %v", h.URL, err)
+			continue
+		}
+		n.hooks = append(n.hooks, h)
+	}
+	return n
+}
+
+func (h Webhook) validate() error {
+	if h.URL == "" {
+		return fmt.Errorf("no url")
+	}
+	if h.Template != "" {
+		if _, ok := templates[h.Template]; !ok {
+			return fmt.Errorf("unknown template %q", h.Template)
+		}
+	}
+	for _, e := range h.Events {
+		known := false
+		for _, k := range EVENTS {
+			known = known || e == k
+		}
+		if !known {
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+func (h Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Start subscribes to events until Stop is called. It does nothing when no webhooks are configured.
+*/
+func (n *Notifier) Start() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.unsubscribe != nil || len(n.hooks) == 0 {
+		return
+	}
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	n.unsubscribe = unsubscribe
+	n.done = make(chan struct{})
+	n.stop = make(chan struct{})
+	go func() {
+		defer close(n.done)
+		for e := range events {
+			n.handle(e)
+		}
+	}()
+	log.Infof("sending print notifications to %v webhooks", len(n.hooks))
+}
+
+/*
+Stop ends event handling, gives up on deliveries waiting to be retried and waits for the ones in flight
+*/
+func (n *Notifier) Stop() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.unsubscribe == nil {
+		return
+	}
+	n.unsubscribe()
+	<-n.done
+	close(n.stop)
+	n.deliveries.Wait()
+	n.unsubscribe = nil
+}
+
+func (n *Notifier) handle(e bus.Event) {
+	p, ok := n.payload(e)
+	if !ok {
+		return
+	}
+	for _, h := range n.hooks {
+		if !h.wants(p.Event) {
+			continue
+		}
+		n.deliveries.Add(1)
+		go func(h Webhook) {
+			defer n.deliveries.Done()
+			n.deliver(h, p)
+		}(h)
+	}
+}
+
+/*
+payload turns a bus event into the webhook payload, if it is one webhooks are sent for
+*/
+func (n *Notifier) payload(e bus.Event) (Payload, bool) {
+	p := Payload{Time: e.Time, Printer: Printer{Id: e.PrinterId}}
+	switch e.Type {
+	case bus.JOB_STARTED, bus.JOB_FINISHED:
+		job, ok := e.Data.(jobtypes.Job)
+		if !ok {
+			return p, false
+		}
+		if e.Type == bus.JOB_STARTED {
+			p.Event = EVENT_STARTED
+		} else if p.Event, ok = finished[job.State]; !ok {
+			return p, false
+		}
+		p.Printer.Name = job.PrinterName
+		p.File = job.File
+		p.User = job.User
+		p.Completion = job.Completion
+		if job.ModelId != "" {
+			p.Model = &Model{Id: job.ModelId, Name: job.ModelName}
+		}
+		if job.EndTime != nil {
+			p.Duration = job.EndTime.Sub(job.StartTime).Round(time.Second).Seconds()
+		}
+	case bus.PRINTER_STATE:
+		change, ok := e.Data.(bus.StateChange)
+		// the first poll after starting has no previous state, a printer that was never seen can't go offline
+		if !ok || printer.State(change.State) != printer.STATE_OFFLINE || change.Previous == "" {
+			return p, false
+		}
+		p.Event = EVENT_OFFLINE
+		p.Printer.Name = change.PrinterName
+	default:
+		return p, false
+	}
+	if pr, err := n.printers.Inspect(e.PrinterId); err == nil && pr.Id != "" {
+		p.Printer.Name = pr.PrinterName
+		p.Printer.Location = pr.Location.Name
+	}
+	return p, true
+}
+
+func (n *Notifier) deliver(h Webhook, p Payload) {
+	format := templates[TEMPLATE_JSON]
+	if h.Template != "" {
+		format = templates[h.Template]
+	}
+	req, err := format(p)
+	if err != nil {
+		log.Errorf("could not format %v for webhook %v: %v", p.Event, h.URL, err)
+		return
+	}
+
+	wait := n.backoff
+	for attempt := 0; ; attempt++ {
+		err = n.send(h, p.Event, req)
+		if err == nil {
+			log.Debugf("sent %v to webhook %v", p.Event, h.URL)
+			return
+		}
+		if attempt >= n.retries || !retryable(err) {
+			break
+		}
+		log.Debugf("webhook %v failed, retrying in %v: %v", h.URL, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-n.stop:
+			return
+		}
+		wait *= 2
+	}
+	log.Errorf("could not send %v to webhook %v: %v", p.Event, h.URL, err)
+}
+
+func (n *Notifier) send(h Webhook, event string, req request) error {
+	r, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(req.body))
+	if err != nil {
+		return err
+	}
+	for k, v := range req.headers {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", req.contentType)
+	r.Header.Set("User-Agent", "Ymir")
+	r.Header.Set(EVENT_HEADER, event)
+	if h.Secret != "" {
+		r.Header.Set(SIGNATURE_HEADER, Sign(h.Secret, req.body))
+	}
+	res, err := n.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	return nil
+}
+
+/*
+Sign returns the SIGNATURE_HEADER value for body, so receivers can check it came from us
+*/
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+retryable is true for network errors, rate limits and server errors. Other client errors won't go away by retrying.
+*/
+func retryable(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	if !ok {
+		return true
+	}
+	return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
+	"ymir/pkg/api/printer/types"
+	bus "ymir/pkg/events"
+)
+
+type fakePrinters map[string]types.Printer
+
+func (f fakePrinters) Inspect(id string) (types.Printer, error) {
+	return f[id], nil
+}
+
+type received struct {
+	path    string
+	headers http.Header
+	body    []byte
+}
+
+type NotifyTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	lock     sync.Mutex
+	received []received
+	// failures is how many requests to a path fail before it succeeds, and with what status
+	failures map[string]int
+	status   map[string]int
+	start    time.Time
+}
+
+func (suite *NotifyTestSuite) SetupTest() {
+	suite.received = nil
+	suite.failures = map[string]int{}
+	suite.status = map[string]int{}
+	suite.start = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+		suite.received = append(suite.received, received{path: r.URL.Path, headers: r.Header, body: body})
+		if suite.failures[r.URL.Path] > 0 {
+			suite.failures[r.URL.Path]--
+			w.WriteHeader(suite.status[r.URL.Path])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func (suite *NotifyTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *NotifyTestSuite) notifier(hooks ...Webhook) *Notifier {
+	return NewNotifier(&NotificationsConfig{Retries: 3, Backoff: 0, Webhooks: hooks}, fakePrinters{
+		"p1": {Id: "p1", PrinterName: "mk3s", Location: types.Location{Name: "garage"}},
+	})
+}
+
+func (suite *NotifyTestSuite) finished(state string, completion float64) bus.Event {
+	end := suite.start.Add(90 * time.Minute)
+	return bus.Event{Type: bus.JOB_FINISHED, PrinterId: "p1", Time: end, Data: jobtypes.Job{
+		Id: "j1", ModelId: "m1", ModelName: "Benchy", File: "/models/benchy/benchy.gcode", PrinterId: "p1",
+		PrinterName: "mk3s", User: "admin", StartTime: suite.start, EndTime: &end, State: state, Completion: completion,
+	}}
+}
+
+func (suite *NotifyTestSuite) send(n *Notifier, events ...bus.Event) []received {
+	for _, e := range events {
+		n.handle(e)
+	}
+	n.deliveries.Wait()
+	suite.lock.Lock()
+	defer suite.lock.Unlock()
+	return suite.received
+}
+
+func (suite *NotifyTestSuite) TestPayload() {
+	n := suite.notifier(Webhook{URL: suite.server.URL + "/hook", Secret: "s3cret"})
+	got := suite.send(n, suite.finished(jobtypes.JOB_STATE_COMPLETED, 100))
+	assert.Len(suite.T(), got, 1)
+
+	r := got[0]
+	assert.Equal(suite.T(), "application/json", r.headers.Get("Content-Type"))
+	assert.Equal(suite.T(), EVENT_FINISHED, r.headers.Get(EVENT_HEADER))
+	assert.Equal(suite.T(), Sign("s3cret", r.body), r.headers.Get(SIGNATURE_HEADER))
+	var p Payload
+	assert.NoError(suite.T(), json.Unmarshal(r.body, &p))
+	assert.Equal(suite.T(), Printer{Id: "p1", Name: "mk3s", Location: "garage"}, p.Printer)
+	assert.Equal(suite.T(), &Model{Id: "m1", Name: "Benchy"}, p.Model)
+	assert.Equal(suite.T(), "/models/benchy/benchy.gcode", p.File)
+	assert.Equal(suite.T(), 5400.0, p.Duration)
+	assert.Equal(suite.T(), "benchy.gcode (Benchy) took 1h30m0s.", p.Message())
+}
+
+func (suite *NotifyTestSuite) TestEvents() {
+	n := suite.notifier(
+		Webhook{URL: suite.server.URL + "/all"},
+		Webhook{URL: suite.server.URL + "/failures", Events: []string{EVENT_FAILED, EVENT_OFFLINE}},
+	)
+	got := suite.send(n,
+		bus.Event{Type: bus.JOB_STARTED, PrinterId: "p1", Data: jobtypes.Job{Id: "j1", PrinterId: "p1", State: jobtypes.JOB_STATE_PRINTING}},
+		suite.finished(jobtypes.JOB_STATE_CANCELLED, 40),
+		suite.finished(jobtypes.JOB_STATE_UNKNOWN, 40),
+		suite.finished(jobtypes.JOB_STATE_FAILED, 40),
+		bus.Event{Type: bus.PRINTER_STATE, PrinterId: "p1", Data: bus.StateChange{Previous: "", State: "offline"}},
+		bus.Event{Type: bus.PRINTER_STATE, PrinterId: "p1", Data: bus.StateChange{Previous: "idle", State: "offline"}},
+		bus.Event{Type: bus.PRINTER_STATE, PrinterId: "p1", Data: bus.StateChange{Previous: "offline", State: "idle"}},
+		bus.Event{Type: bus.JOB_PROGRESS, PrinterId: "p1"},
+	)
+	events := map[string][]string{}
+	for _, r := range got {
+		events[r.path] = append(events[r.path], r.headers.Get(EVENT_HEADER))
+	}
+	assert.ElementsMatch(suite.T(), []string{EVENT_STARTED, EVENT_CANCELLED, EVENT_FAILED, EVENT_OFFLINE}, events["/all"])
+	assert.ElementsMatch(suite.T(), []string{EVENT_FAILED, EVENT_OFFLINE}, events["/failures"])
+}
+
+func (suite *NotifyTestSuite) TestRetry() {
+	suite.failures["/flaky"] = 2
+	suite.status["/flaky"] = http.StatusServiceUnavailable
+	suite.failures["/broken"] = 10
+	suite.status["/broken"] = http.StatusInternalServerError
+	suite.failures["/rejected"] = 1
+	suite.status["/rejected"] = http.StatusBadRequest
+	n := suite.notifier(
+		Webhook{URL: suite.server.URL + "/flaky"},
+		Webhook{URL: suite.server.URL + "/broken"},
+		Webhook{URL: suite.server.URL + "/rejected"},
+	)
+	got := suite.send(n, suite.finished(jobtypes.JOB_STATE_COMPLETED, 100))
+	attempts := map[string]int{}
+	for _, r := range got {
+		attempts[r.path]++
+	}
+	assert.Equal(suite.T(), 3, attempts["/flaky"], "retried until it succeeds")
+	assert.Equal(suite.T(), 4, attempts["/broken"], "retried Retries times")
+	assert.Equal(suite.T(), 1, attempts["/rejected"], "client errors are not retried")
+}
+
+func (suite *NotifyTestSuite) TestTemplates() {
+	n := suite.notifier(
+		Webhook{URL: suite.server.URL + "/ntfy", Template: TEMPLATE_NTFY},
+		Webhook{URL: suite.server.URL + "/discord", Template: TEMPLATE_DISCORD},
+		Webhook{URL: suite.server.URL + "/slack", Template: TEMPLATE_SLACK},
+	)
+	got := suite.send(n, suite.finished(jobtypes.JOB_STATE_FAILED, 37.4))
+	bodies := map[string]received{}
+	for _, r := range got {
+		bodies[r.path] = r
+	}
+
+	ntfy := bodies["/ntfy"]
+	assert.Equal(suite.T(), "Print failed on mk3s", ntfy.headers.Get("Title"))
+	assert.Equal(suite.T(), "high", ntfy.headers.Get("Priority"))
+	assert.Equal(suite.T(), "x", ntfy.headers.Get("Tags"))
+	assert.Equal(suite.T(), "benchy.gcode (Benchy) stopped at 37% after 1h30m0s.", string(ntfy.body))
+
+	var discord struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"embeds"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(bodies["/discord"].body, &discord))
+	assert.Equal(suite.T(), "Print failed on mk3s", discord.Embeds[0].Title)
+
+	var slack map[string]string
+	assert.NoError(suite.T(), json.Unmarshal(bodies["/slack"].body, &slack))
+	assert.Equal(suite.T(), "*Print failed on mk3s*\nbenchy.gcode (Benchy) stopped at 37% after 1h30m0s.", slack["text"])
+}
+
+func (suite *NotifyTestSuite) TestInvalidWebhooks() {
+	n := suite.notifier(
+		Webhook{},
+		Webhook{URL: suite.server.URL, Template: "teams"},
+		Webhook{URL: suite.server.URL, Events: []string{"print.paused"}},
+		Webhook{URL: suite.server.URL, Events: []string{EVENT_STARTED}},
+	)
+	assert.Len(suite.T(), n.hooks, 1)
+}
+
+func (suite *NotifyTestSuite) TestStartStop() {
+	n := suite.notifier(Webhook{URL: suite.server.URL + "/hook"})
+	n.Start()
+	bus.Publish(suite.finished(jobtypes.JOB_STATE_COMPLETED, 100))
+	assert.Eventually(suite.T(), func() bool {
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+		return len(suite.received) == 1
+	}, time.Second, 10*time.Millisecond)
+	n.Stop()
+	n.Stop()
+}
+
+func TestNotifyTestSuite(t *testing.T) {
+	suite.Run(t, new(NotifyTestSuite))
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// TEMPLATE_JSON posts the Payload as is
+	TEMPLATE_JSON = "json"
+	// TEMPLATE_NTFY posts the message as text to an ntfy topic url, e.g. https://ntfy.sh/my-farm
+	TEMPLATE_NTFY = "ntfy"
+	// TEMPLATE_DISCORD posts to a Discord channel webhook
+	TEMPLATE_DISCORD = "discord"
+	// TEMPLATE_SLACK posts to a Slack incoming webhook, or anything that takes the same {"text": ...} body like Mattermost
+	TEMPLATE_SLACK = "slack"
+)
+
+/*
+request is a formatted delivery: the body, its content type and any extra headers the service wants
+*/
+type request struct {
+	body        []byte
+	contentType string
+	headers     http.Header
+}
+
+type template func(p Payload) (request, error)
+
+var templates = map[string]template{
+	TEMPLATE_JSON:    jsonTemplate,
+	TEMPLATE_NTFY:    ntfyTemplate,
+	TEMPLATE_DISCORD: discordTemplate,
+	TEMPLATE_SLACK:   slackTemplate,
+}
+
+func jsonTemplate(p Payload) (request, error) {
+	b, err := json.Marshal(p)
+	return request{body: b, contentType: "application/json"}, err
+}
+
+/*
+ntfyTemplate uses ntfy's publish headers, see https://docs.ntfy.sh/publish/
+*/
+func ntfyTemplate(p Payload) (request, error) {
+	headers := http.Header{}
+	headers.Set("Title", p.Title())
+	headers.Set("Tags", ntfyTags[p.Event])
+	if p.Event == EVENT_FAILED || p.Event == EVENT_OFFLINE {
+		headers.Set("Priority", "high")
+	}
+	return request{body: []byte(p.Message()), contentType: "text/plain; charset=utf-8", headers: headers}, nil
+}
+
+var ntfyTags = map[string]string{
+	EVENT_STARTED:   "arrow_forward",
+	EVENT_FINISHED:  "white_check_mark",
+	EVENT_FAILED:    "x",
+	EVENT_CANCELLED: "stop_button",
+	EVENT_OFFLINE:   "warning",
+}
+
+func discordTemplate(p Payload) (request, error) {
+	b, err := json.Marshal(map[string]interface{}{
+		"username": "Ymir",
+		"embeds": []map[string]interface{}{{
+			"title":       p.Title(),
+			"description": p.Message(),
+			"color":       colors[p.Event],
+			"timestamp":   p.Time.Format(time.RFC3339),
+		}},
+	})
+	return request{body: b, contentType: "application/json"}, err
+}
+
+// colors are the Discord embed colors for each event
+var colors = map[string]int{
+	EVENT_STARTED:   0x3498db,
+	EVENT_FINISHED:  0x2ecc71,
+	EVENT_FAILED:    0xe74c3c,
+	EVENT_CANCELLED: 0x95a5a6,
+	EVENT_OFFLINE:   0xf1c40f,
+}
+
+func slackTemplate(p Payload) (request, error) {
+	b, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%v*\n%v", p.Title(), p.Message()),
+	})
+	return request{body: b, contentType: "application/json"}, err
+}
@@ -0,0 +1,272 @@
+/*
+Package dispatcher starts the next file of a printer's print queue once the poller reports the printer idle
+and someone has confirmed the bed is clear. The confirmation is dropped as soon as a print starts or the
+printer leaves idle, so it always refers to the bed as it is now.
+*/
+package dispatcher
+
+import (
+	"errors"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api/printer/store"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/events"
+	"ymir/pkg/printer"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/printer/preflight"
+)
+
+var (
+	errNothingToDispatch = errors.New("nothing to dispatch")
+
+	defaultLock = &sync.RWMutex{}
+	instance    *Dispatcher
+)
+
+/*
+PrinterInspector is the part of the PrinterStore the dispatcher needs
+*/
+type PrinterInspector interface {
+	Inspect(id string) (printer types.Printer, err error)
+}
+
+/*
+StatusSource is the part of the poller the dispatcher needs
+*/
+type StatusSource interface {
+	Status(id string) (poller.PrinterStatus, bool)
+}
+
+/*
+Uploader sends a print file to a printer and starts it, recording the job on behalf of user
+*/
+type Uploader func(filePath string, p types.Printer, print bool, override bool, user string) (preflight.Report, error)
+
+type Dispatcher struct {
+	printers PrinterInspector
+	queues   store.QueueStoreIFace
+	statuses StatusSource
+	upload   Uploader
+
+	lock        sync.Mutex
+	unsubscribe func()
+	done        chan struct{}
+	// dispatching counts the dispatches running in the background
+	dispatching sync.WaitGroup
+
+	uploadsLock sync.Mutex
+	uploading   map[string]bool
+	// started holds the printers a file was started on that the poller still reports idle
+	started map[string]bool
+}
+
+func NewDispatcher(printers PrinterInspector, queues store.QueueStoreIFace, statuses StatusSource, upload Uploader) *Dispatcher {
+	return &Dispatcher{
+		printers:  printers,
+		queues:    queues,
+		statuses:  statuses,
+		upload:    upload,
+		uploading: map[string]bool{},
+		started:   map[string]bool{},
+	}
+}
+
+/*
+SetDefault makes d the dispatcher returned by Default. The server sets it when it starts the dispatcher.
+*/
+func SetDefault(d *Dispatcher) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	instance = d
+}
+
+/*
+Default returns the dispatcher the server started, or nil if there is none
+*/
+func Default() *Dispatcher {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return instance
+}
+
+/*
+Start watches printer state and queue changes until Stop is called
+*/
+func (d *Dispatcher) Start() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.unsubscribe != nil {
+		return
+	}
+	subscription, unsubscribe := events.Subscribe(events.DEFAULT_BUFFER)
+	d.unsubscribe = unsubscribe
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		for e := range subscription {
+			d.handle(e)
+		}
+	}()
+}
+
+/*
+Stop ends event handling and waits for the uploads already started
+*/
+func (d *Dispatcher) Stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.unsubscribe == nil {
+		return
+	}
+	d.unsubscribe()
+	<-d.done
+	d.unsubscribe = nil
+	d.dispatching.Wait()
+}
+
+func (d *Dispatcher) handle(e events.Event) {
+	switch e.Type {
+	case events.QUEUE_CHANGED:
+		d.dispatchInBackground(e.PrinterId)
+	case events.JOB_STARTED:
+		d.ClearBed(e.PrinterId)
+	case events.PRINTER_STATE:
+		change, ok := e.Data.(events.StateChange)
+		if !ok {
+			return
+		}
+		if printer.State(change.State) == printer.STATE_IDLE {
+			d.dispatchInBackground(e.PrinterId)
+			return
+		}
+		d.uploadsLock.Lock()
+		delete(d.started, e.PrinterId)
+		d.uploadsLock.Unlock()
+		if printer.State(change.Previous) == printer.STATE_IDLE {
+			d.ClearBed(e.PrinterId)
+		}
+	}
+}
+
+/*
+dispatchInBackground dispatches without holding up the events behind it, an upload can take minutes
+*/
+func (d *Dispatcher) dispatchInBackground(printerId string) {
+	d.dispatching.Add(1)
+	go func() {
+		defer d.dispatching.Done()
+		d.Dispatch(printerId)
+	}()
+}
+
+/*
+Dispatched tells if a file is being uploaded to the printer, or was started on it and the poller hasn't
+seen the printer leave idle yet. Its idle status is stale until then.
+*/
+func (d *Dispatcher) Dispatched(printerId string) bool {
+	d.uploadsLock.Lock()
+	defer d.uploadsLock.Unlock()
+	return d.uploading[printerId] || d.started[printerId]
+}
+
+/*
+ClearBed withdraws the bed clear confirmation of the printer, if there is one
+*/
+func (d *Dispatcher) ClearBed(printerId string) {
+	q, err := d.queues.Modify(printerId, func(q *types.Queue) error {
+		if !q.BedClear {
+			return errNothingToDispatch
+		}
+		q.BedClear = false
+		return nil
+	})
+	if errors.Is(err, errNothingToDispatch) {
+		return
+	} else if err != nil {
+		log.Errorf("could not update queue of printer %v: %v", printerId, err)
+		return
+	}
+	events.Publish(events.Event{
+		Type:      events.QUEUE_CHANGED,
+		PrinterId: printerId,
+		Data:      q,
+	})
+}
+
+/*
+Dispatch starts the first queued file on the printer if it is idle and the bed has been confirmed clear.
+A file that fails to start stays at the front of the queue and the bed has to be confirmed again to retry it.
+Only one file is uploaded to a printer at a time, and none until the poller has seen the last one start.
+*/
+func (d *Dispatcher) Dispatch(printerId string) {
+	status, ok := d.statuses.Status(printerId)
+	if !ok || status.State != printer.STATE_IDLE {
+		return
+	}
+	d.uploadsLock.Lock()
+	if d.uploading[printerId] || d.started[printerId] {
+		d.uploadsLock.Unlock()
+		return
+	}
+	d.uploading[printerId] = true
+	d.uploadsLock.Unlock()
+	defer func() {
+		d.uploadsLock.Lock()
+		delete(d.uploading, printerId)
+		d.uploadsLock.Unlock()
+	}()
+	p, err := d.printers.Inspect(printerId)
+	if err != nil || p.Id == "" {
+		return
+	}
+
+	var item types.QueueItem
+	_, err = d.queues.Modify(printerId, func(q *types.Queue) error {
+		if !q.BedClear || len(q.Items) == 0 {
+			return errNothingToDispatch
+		}
+		item = q.Items[0]
+		q.Items = q.Items[1:]
+		q.BedClear = false
+		q.LastError = ""
+		return nil
+	})
+	if errors.Is(err, errNothingToDispatch) {
+		return
+	} else if err != nil {
+		log.Errorf("could not read queue of printer %v: %v", p.PrinterName, err)
+		return
+	}
+
+	log.Infof("starting queued file %v on printer %v", item.File, p.PrinterName)
+	_, uploadErr := d.upload(item.File, p, true, item.Override, item.User)
+	if uploadErr == nil {
+		// unless the poller has already seen the print, the printer looks idle until the next poll
+		if status, ok := d.statuses.Status(printerId); ok && status.State == printer.STATE_IDLE {
+			d.uploadsLock.Lock()
+			d.started[printerId] = true
+			d.uploadsLock.Unlock()
+		}
+	}
+	q, err := d.queues.Modify(printerId, func(q *types.Queue) error {
+		if uploadErr != nil {
+			q.Items = append([]types.QueueItem{item}, q.Items...)
+			q.LastError = uploadErr.Error()
+		}
+		return nil
+	})
+	if uploadErr != nil {
+		log.Errorf("could not start queued file %v on printer %v: %v", item.File, p.PrinterName, uploadErr)
+	}
+	if err != nil {
+		log.Errorf("could not update queue of printer %v: %v", p.PrinterName, err)
+		return
+	}
+	events.Publish(events.Event{
+		Type:      events.QUEUE_CHANGED,
+		PrinterId: printerId,
+		Data:      q,
+	})
+}
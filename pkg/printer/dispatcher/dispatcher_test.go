@@ -0,0 +1,250 @@
+package dispatcher
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/store"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/events"
+	"ymir/pkg/printer"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/printer/preflight"
+)
+
+type fakePrinters map[string]types.Printer
+
+func (f fakePrinters) Inspect(id string) (types.Printer, error) {
+	return f[id], nil
+}
+
+type fakeStatuses struct {
+	lock   sync.Mutex
+	states map[string]printer.State
+}
+
+func (f *fakeStatuses) Status(id string) (poller.PrinterStatus, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	state, ok := f.states[id]
+	return poller.PrinterStatus{Id: id, Status: printer.Status{State: state}}, ok
+}
+
+func (f *fakeStatuses) set(id string, state printer.State) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.states[id] = state
+}
+
+type memQueues struct {
+	store.QueueStore
+	lock   sync.Mutex
+	queues map[string]types.Queue
+}
+
+func (m *memQueues) Get(id string) (types.Queue, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.queues[id], nil
+}
+
+func (m *memQueues) Modify(id string, modify func(q *types.Queue) error) (types.Queue, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	q := m.queues[id]
+	q.Items = append([]types.QueueItem{}, q.Items...)
+	if err := modify(&q); err != nil {
+		return types.Queue{}, err
+	}
+	m.queues[id] = q
+	return q, nil
+}
+
+type DispatcherTestSuite struct {
+	suite.Suite
+	queues     *memQueues
+	statuses   *fakeStatuses
+	dispatcher *Dispatcher
+
+	lock      sync.Mutex
+	uploaded  []string
+	uploadErr error
+	// hold keeps uploads waiting until it is closed
+	hold chan struct{}
+}
+
+func (suite *DispatcherTestSuite) SetupTest() {
+	suite.queues = &memQueues{queues: map[string]types.Queue{
+		"p1": {PrinterId: "p1", Items: []types.QueueItem{{Id: "i1", File: "a.gcode", User: "bob"}, {Id: "i2", File: "b.gcode", Override: true}}},
+	}}
+	suite.statuses = &fakeStatuses{states: map[string]printer.State{"p1": printer.STATE_IDLE}}
+	suite.uploaded = nil
+	suite.uploadErr = nil
+	suite.hold = nil
+	suite.dispatcher = NewDispatcher(
+		fakePrinters{"p1": {Id: "p1", PrinterName: "mk3s"}},
+		suite.queues,
+		suite.statuses,
+		func(file string, p types.Printer, print bool, override bool, user string) (preflight.Report, error) {
+			if suite.hold != nil {
+				<-suite.hold
+			}
+			suite.lock.Lock()
+			defer suite.lock.Unlock()
+			if suite.uploadErr != nil {
+				return preflight.Report{}, suite.uploadErr
+			}
+			if override {
+				user += ":override"
+			}
+			suite.uploaded = append(suite.uploaded, file+":"+user)
+			return preflight.Report{}, nil
+		},
+	)
+}
+
+func (suite *DispatcherTestSuite) confirm() {
+	suite.queues.Modify("p1", func(q *types.Queue) error {
+		q.BedClear = true
+		return nil
+	})
+}
+
+func (suite *DispatcherTestSuite) TestDispatch_NeedsBedClear() {
+	suite.dispatcher.Dispatch("p1")
+	assert.Empty(suite.T(), suite.uploaded)
+
+	suite.confirm()
+	suite.dispatcher.Dispatch("p1")
+	assert.Equal(suite.T(), []string{"a.gcode:bob"}, suite.uploaded)
+	q, _ := suite.queues.Get("p1")
+	assert.Len(suite.T(), q.Items, 1)
+	assert.False(suite.T(), q.BedClear, "starting a print uses up the confirmation")
+
+	suite.dispatcher.Dispatch("p1")
+	assert.Len(suite.T(), suite.uploaded, 1)
+
+	// the first print starts and finishes
+	suite.dispatcher.handle(events.Event{Type: events.PRINTER_STATE, PrinterId: "p1", Data: events.StateChange{Previous: "idle", State: "printing"}})
+	suite.confirm()
+	suite.dispatcher.Dispatch("p1")
+	assert.Equal(suite.T(), []string{"a.gcode:bob", "b.gcode::override"}, suite.uploaded, "overrides are passed on")
+}
+
+func (suite *DispatcherTestSuite) TestDispatch_NeedsIdle() {
+	suite.confirm()
+	suite.statuses.set("p1", printer.STATE_PRINTING)
+	suite.dispatcher.Dispatch("p1")
+	suite.dispatcher.Dispatch("unknown")
+	assert.Empty(suite.T(), suite.uploaded)
+}
+
+func (suite *DispatcherTestSuite) TestDispatch_WaitsForThePrintToShow() {
+	suite.confirm()
+	suite.dispatcher.Dispatch("p1")
+	assert.True(suite.T(), suite.dispatcher.Dispatched("p1"), "the poller still reports idle")
+
+	suite.confirm()
+	suite.dispatcher.Dispatch("p1")
+	assert.Len(suite.T(), suite.uploaded, 1, "the printer is already printing")
+
+	suite.statuses.set("p1", printer.STATE_PRINTING)
+	suite.dispatcher.handle(events.Event{Type: events.PRINTER_STATE, PrinterId: "p1", Data: events.StateChange{Previous: "idle", State: "printing"}})
+	assert.False(suite.T(), suite.dispatcher.Dispatched("p1"))
+	suite.statuses.set("p1", printer.STATE_IDLE)
+	suite.confirm()
+	suite.dispatcher.Dispatch("p1")
+	assert.Len(suite.T(), suite.uploaded, 2)
+}
+
+func (suite *DispatcherTestSuite) TestDispatch_UploadError() {
+	suite.confirm()
+	suite.uploadErr = errors.New("printer said no")
+	suite.dispatcher.Dispatch("p1")
+
+	q, _ := suite.queues.Get("p1")
+	assert.Len(suite.T(), q.Items, 2)
+	assert.Equal(suite.T(), "i1", q.Items[0].Id, "failed item stays at the front")
+	assert.Equal(suite.T(), "printer said no", q.LastError)
+	assert.False(suite.T(), q.BedClear)
+}
+
+func (suite *DispatcherTestSuite) TestStart_DispatchesOnIdle() {
+	suite.confirm()
+	suite.statuses.set("p1", printer.STATE_PRINTING)
+	suite.dispatcher.Start()
+	defer suite.dispatcher.Stop()
+
+	suite.statuses.set("p1", printer.STATE_IDLE)
+	events.Publish(events.Event{Type: events.PRINTER_STATE, PrinterId: "p1", Data: events.StateChange{Previous: "printing", State: "idle"}})
+	assert.Eventually(suite.T(), func() bool {
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+		return len(suite.uploaded) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *DispatcherTestSuite) TestStart_WithdrawsBedClear() {
+	suite.dispatcher.Start()
+	defer suite.dispatcher.Stop()
+	bedClear := func() bool {
+		q, _ := suite.queues.Get("p1")
+		return q.BedClear
+	}
+
+	suite.confirm()
+	events.Publish(events.Event{Type: events.JOB_STARTED, PrinterId: "p1"})
+	assert.Eventually(suite.T(), func() bool { return !bedClear() }, time.Second, 10*time.Millisecond, "a print started outside the queue")
+
+	suite.confirm()
+	events.Publish(events.Event{Type: events.PRINTER_STATE, PrinterId: "p1", Data: events.StateChange{Previous: "idle", State: "offline"}})
+	assert.Eventually(suite.T(), func() bool { return !bedClear() }, time.Second, 10*time.Millisecond, "the printer left idle")
+	assert.Empty(suite.T(), suite.uploaded)
+}
+
+func (suite *DispatcherTestSuite) TestStart_UploadsInBackground() {
+	suite.hold = make(chan struct{})
+	suite.queues.queues["p2"] = types.Queue{PrinterId: "p2"}
+	suite.dispatcher.Start()
+	defer suite.dispatcher.Stop()
+
+	suite.confirm()
+	events.Publish(events.Event{Type: events.QUEUE_CHANGED, PrinterId: "p1"})
+	assert.Eventually(suite.T(), func() bool {
+		q, _ := suite.queues.Get("p1")
+		return len(q.Items) == 1
+	}, time.Second, 10*time.Millisecond, "the first item is being uploaded")
+
+	// events are still handled while the upload runs, and the printer isn't dispatched to twice
+	suite.confirm()
+	events.Publish(events.Event{Type: events.QUEUE_CHANGED, PrinterId: "p1"})
+	suite.queues.Modify("p2", func(q *types.Queue) error {
+		q.BedClear = true
+		return nil
+	})
+	events.Publish(events.Event{Type: events.JOB_STARTED, PrinterId: "p2"})
+	assert.Eventually(suite.T(), func() bool {
+		q, _ := suite.queues.Get("p2")
+		return !q.BedClear
+	}, time.Second, 10*time.Millisecond)
+	q, _ := suite.queues.Get("p1")
+	assert.Len(suite.T(), q.Items, 1)
+
+	suite.statuses.set("p1", printer.STATE_PRINTING)
+	close(suite.hold)
+	assert.Eventually(suite.T(), func() bool {
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+		return len(suite.uploaded) > 0
+	}, time.Second, 10*time.Millisecond)
+	suite.dispatcher.Stop()
+	assert.Equal(suite.T(), []string{"a.gcode:bob"}, suite.uploaded)
+}
+
+func TestDispatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(DispatcherTestSuite))
+}
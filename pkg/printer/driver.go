@@ -0,0 +1,174 @@
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ymir/pkg/api/printer/types"
+)
+
+/*
+State is the backend independent state of a printer
+*/
+type State string
+
+const (
+	STATE_OFFLINE  State = "offline"
+	STATE_IDLE     State = "idle"
+	STATE_PRINTING State = "printing"
+	STATE_PAUSED   State = "paused"
+	STATE_BUSY     State = "busy"
+	STATE_ERROR    State = "error"
+)
+
+var (
+	ErrUnknownAPIType = errors.New("unknown printer api type")
+	ErrNotSupported   = errors.New("not supported by this printer driver")
+	ErrUnknownStorage = errors.New("unknown printer storage location")
+)
+
+/*
+PrinterDriver is implemented by every printer backend (OctoPrint, Moonraker, ...).
+Drivers register themselves with Register and are created by NewDriver from the Printer's APIType.
+*/
+type PrinterDriver interface {
+	// Connect makes sure the backend is connected to the printer
+	Connect() error
+	// Status returns the current state, temperatures and job progress
+	Status() (Status, error)
+	// Upload sends a print file to the printer and optionally starts printing it
+	Upload(filename string, r io.Reader, print bool) error
+	// Start prints a file that is already on the printer. An empty location is the storage Upload puts files in.
+	Start(location string, path string) error
+	Pause() error
+	Resume() error
+	Cancel() error
+	// ListFiles lists the print files stored on the printer
+	ListFiles() ([]File, error)
+	// DeleteFile deletes a file from the printer's storage. An empty location is the storage Upload puts files in.
+	DeleteFile(location string, path string) error
+	// SendGCode sends raw G-code lines to the printer
+	SendGCode(commands ...string) error
+	// Info identifies the printer's firmware and the server in front of it. It checks the credentials on the way.
+	Info() (Info, error)
+}
+
+/*
+DriverFactory creates a PrinterDriver for a stored Printer
+*/
+type DriverFactory func(p types.Printer) (PrinterDriver, error)
+
+/*
+StatusError is implemented by driver errors that carry the HTTP status the printer answered with
+*/
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
+type Status struct {
+	State        State                  `json:"state"`
+	Text         string                 `json:"text"`
+	Temperatures map[string]Temperature `json:"temperatures"`
+	Job          *Job                   `json:"job,omitempty"`
+}
+
+/*
+Info is what a backend reports about itself and the printer. Fields it doesn't report are empty.
+*/
+type Info struct {
+	Server          string `json:"server,omitempty"`
+	ServerVersion   string `json:"serverVersion,omitempty"`
+	Firmware        string `json:"firmware,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion,omitempty"`
+	Make            string `json:"make,omitempty"`
+	Model           string `json:"model,omitempty"`
+}
+
+type Temperature struct {
+	Actual float64 `json:"actual"`
+	Target float64 `json:"target"`
+}
+
+/*
+Job is the progress of the current print. Times are in seconds and Completion is in percent.
+*/
+type Job struct {
+	File               string  `json:"file"`
+	Completion         float64 `json:"completion"`
+	PrintTime          float64 `json:"printTime"`
+	PrintTimeLeft      float64 `json:"printTimeLeft"`
+	EstimatedPrintTime float64 `json:"estimatedPrintTime"`
+}
+
+type File struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Location string    `json:"location,omitempty"`
+	Size     int64     `json:"size"`
+	Date     time.Time `json:"date"`
+}
+
+type registration struct {
+	apiType string
+	factory DriverFactory
+}
+
+var (
+	driversLock = &sync.RWMutex{}
+	drivers     = map[string]registration{}
+)
+
+/*
+Register makes a driver available for printers with the given APIType.
+It panics if called twice for the same APIType.
+*/
+func Register(apiType string, factory DriverFactory) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+	key := strings.ToLower(apiType)
+	if factory == nil {
+		panic("printer: Register driver is nil")
+	}
+	if _, dup := drivers[key]; dup {
+		panic("printer: Register called twice for driver " + apiType)
+	}
+	drivers[key] = registration{apiType, factory}
+}
+
+/*
+NewDriver returns the driver registered for the printer's APIType.
+Printers without an APIType are treated as OctoPrint since that was the only backend before drivers.
+*/
+func NewDriver(p types.Printer) (PrinterDriver, error) {
+	apiType := p.APIType
+	if apiType == "" {
+		apiType = types.API_TYPE_OCTOPRINT
+	}
+	driversLock.RLock()
+	reg, ok := drivers[strings.ToLower(apiType)]
+	driversLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAPIType, p.APIType)
+	}
+	return reg.factory(p)
+}
+
+/*
+Drivers returns the sorted list of registered APITypes
+*/
+func Drivers() []string {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+	list := make([]string, 0, len(drivers))
+	for _, reg := range drivers {
+		list = append(list, reg.apiType)
+	}
+	sort.Strings(list)
+	return list
+}
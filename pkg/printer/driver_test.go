@@ -0,0 +1,51 @@
+package printer
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ymir/pkg/api/printer/types"
+)
+
+type fakeDriver struct {
+	PrinterDriver
+	url string
+}
+
+func newFakeDriver(p types.Printer) (PrinterDriver, error) {
+	return &fakeDriver{url: p.URL}, nil
+}
+
+func (f *fakeDriver) Upload(filename string, r io.Reader, print bool) error {
+	return ErrNotSupported
+}
+
+func TestRegistry(t *testing.T) {
+	Register("Fake", newFakeDriver)
+	assert.Contains(t, Drivers(), "Fake")
+	assert.Panics(t, func() { Register("fake", newFakeDriver) }, "should panic on duplicate api type")
+
+	tests := []struct {
+		name    string
+		apiType string
+		wantErr error
+	}{
+		{"Registered", "Fake", nil},
+		{"Case Insensitive", "FAKE", nil},
+		{"Unknown", "Bambu", ErrUnknownAPIType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDriver(types.Printer{APIType: tt.apiType, URL: "http://fake"})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "http://fake", d.(*fakeDriver).url)
+			assert.ErrorIs(t, d.Upload("test.gcode", nil, false), ErrNotSupported)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+/*
+Package drivers links the printer backends into the binary.
+Each backend registers itself with printer.Register when imported.
+*/
+package drivers
+
+import (
+	_ "ymir/pkg/printer/duet"
+	_ "ymir/pkg/printer/marlin"
+	_ "ymir/pkg/printer/moonraker"
+	_ "ymir/pkg/printer/octoprint"
+	_ "ymir/pkg/printer/prusalink"
+)
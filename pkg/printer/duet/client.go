@@ -0,0 +1,340 @@
+package duet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_SESSION_KEY_HEADER = "X-Session-Key"
+	_DEFAULT_TIMEOUT    = 10 * time.Second
+	_TIME_FORMAT        = "2006-01-02T15:04:05"
+
+	// DEFAULT_PASSWORD is what RepRapFirmware expects when no M551 password is set
+	DEFAULT_PASSWORD = "reprap"
+	// GCODES_DIR is where RepRapFirmware keeps print files
+	GCODES_DIR = "0:/gcodes"
+)
+
+/*
+Client is a small client for the RepRapFirmware HTTP API served by Duet boards in standalone mode.
+The client logs in with rr_connect on first use and again when the session has expired. Requests may run
+side by side in the one session, so a long upload doesn't hold up status polls.
+https://github.com/Duet3D/RepRapFirmware/wiki/HTTP-requests
+*/
+type Client struct {
+	Endpoint string
+	Password string
+	Timeout  time.Duration
+	c        *http.Client
+
+	// lock protects the session, not the requests made in it
+	lock       sync.Mutex
+	connected  bool
+	sessionKey uint32
+}
+
+/*
+APIError is returned when RepRapFirmware answers with a status code we did not expect,
+or reports an error in an otherwise successful response
+*/
+type APIError struct {
+	StatusCode int    `json:"statusCode"`
+	Status     string `json:"status"`
+	Body       string `json:"body,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("duet: %v: %v", e.Status, strings.TrimSpace(e.Body))
+	}
+	return fmt.Sprintf("duet: %v", e.Status)
+}
+
+/*
+HTTPStatus satisfies printer.StatusError
+*/
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func NewClient(endpoint string, password string) *Client {
+	if password == "" {
+		password = DEFAULT_PASSWORD
+	}
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Password: password,
+		Timeout:  _DEFAULT_TIMEOUT,
+		c: &http.Client{
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+/*
+Connect logs in with rr_connect. The clock of the board is set to ours while we are at it.
+A session we already have is given back first, the board only has a few of them.
+*/
+func (c *Client) Connect() (resp ConnectResponse, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.connected {
+		if err := c.disconnect(); err != nil {
+			log.Debugf("duet: could not end session %v: %v", c.sessionKey, err)
+		}
+	}
+	return c.connect()
+}
+
+func (c *Client) connect() (resp ConnectResponse, err error) {
+	query := url.Values{}
+	query.Set("password", c.Password)
+	query.Set("time", time.Now().Format(_TIME_FORMAT))
+	req, cancel, err := c.newRequest(c.Timeout, http.MethodGet, "/rr_connect", query, nil)
+	if err != nil {
+		return
+	}
+	defer cancel()
+	if err = c.send(req, 0, &resp); err != nil {
+		return
+	}
+	switch resp.Err {
+	case 0:
+	case 1:
+		return resp, &APIError{StatusCode: http.StatusForbidden, Status: "403 Forbidden", Body: "invalid password"}
+	case 2:
+		return resp, &APIError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: "no more sessions available"}
+	default:
+		return resp, &APIError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: fmt.Sprintf("rr_connect error %d", resp.Err)}
+	}
+	c.connected = true
+	c.sessionKey = resp.SessionKey
+	return resp, nil
+}
+
+/*
+Disconnect ends the session with rr_disconnect
+*/
+func (c *Client) Disconnect() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.connected {
+		return nil
+	}
+	return c.disconnect()
+}
+
+func (c *Client) disconnect() error {
+	key := c.sessionKey
+	c.connected = false
+	c.sessionKey = 0
+	req, cancel, err := c.newRequest(c.Timeout, http.MethodGet, "/rr_disconnect", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return c.send(req, key, nil)
+}
+
+/*
+session logs in if needed and returns the session key to send
+*/
+func (c *Client) session() (uint32, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.connected {
+		if _, err := c.connect(); err != nil {
+			return 0, err
+		}
+	}
+	return c.sessionKey, nil
+}
+
+/*
+expire forgets the session the board no longer knows, unless another request has already logged in again
+*/
+func (c *Client) expire(key uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.connected && c.sessionKey == key {
+		c.connected = false
+	}
+}
+
+/*
+newRequest builds a request that is cancelled after timeout, or never if timeout is 0
+*/
+func (c *Client) newRequest(timeout time.Duration, method string, uri string, query url.Values, body []byte) (*http.Request, context.CancelFunc, error) {
+	u := c.Endpoint + uri
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return req, cancel, nil
+}
+
+/*
+call logs in if needed, sends the request and decodes the JSON response into out (if not nil).
+A 401 means the session timed out, so we log in again and retry once.
+*/
+func (c *Client) call(method string, uri string, query url.Values, body []byte, out interface{}) error {
+	return c.callWithTimeout(c.Timeout, method, uri, query, body, out)
+}
+
+func (c *Client) callWithTimeout(timeout time.Duration, method string, uri string, query url.Values, body []byte, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		key, err := c.session()
+		if err != nil {
+			return err
+		}
+		req, cancel, err := c.newRequest(timeout, method, uri, query, body)
+		if err != nil {
+			return err
+		}
+		err = c.send(req, key, out)
+		cancel()
+		if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			c.expire(key)
+			continue
+		}
+		return err
+	}
+}
+
+/*
+send sends the request in the session with key, if it is not 0
+*/
+func (c *Client) send(req *http.Request, key uint32, out interface{}) error {
+	if key != 0 {
+		req.Header.Set(_SESSION_KEY_HEADER, fmt.Sprint(key))
+	}
+	log.Debugf("duet request: %v %v", req.Method, req.URL.Path)
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+/*
+Model returns one key of the RepRapFirmware object model decoded into out
+*/
+func (c *Client) Model(key string, out interface{}) error {
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("flags", "d99vn")
+	return c.call(http.MethodGet, "/rr_model", query, nil, &ModelResponse{Result: out})
+}
+
+/*
+GCode queues one or more lines of G-code. The reply, if any, is available from rr_reply.
+*/
+func (c *Client) GCode(gcode string) error {
+	query := url.Values{}
+	query.Set("gcode", gcode)
+	return c.call(http.MethodGet, "/rr_gcode", query, nil, &GCodeResponse{})
+}
+
+/*
+Upload stores a file on the board's SD card. name is the full path e.g. 0:/gcodes/ymir/test.gcode.
+The whole file is buffered so the request can be replayed after logging in again. Uploads are not bound by the client timeout.
+*/
+func (c *Client) Upload(name string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("time", time.Now().Format(_TIME_FORMAT))
+
+	resp := ErrResponse{}
+	if err := c.callWithTimeout(0, http.MethodPost, "/rr_upload", query, b, &resp); err != nil {
+		return err
+	}
+	if resp.Err != 0 {
+		return &APIError{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error", Body: "upload of " + name + " failed"}
+	}
+	return nil
+}
+
+/*
+ListFiles lists a directory on the board's SD card, following rr_filelist's paging
+*/
+func (c *Client) ListFiles(dir string) ([]FileEntry, error) {
+	files := []FileEntry{}
+	first := 0
+	for {
+		query := url.Values{}
+		query.Set("dir", dir)
+		query.Set("first", fmt.Sprint(first))
+		list := FileList{}
+		if err := c.call(http.MethodGet, "/rr_filelist", query, nil, &list); err != nil {
+			return nil, err
+		}
+		if list.Err != 0 {
+			return nil, &APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: "no such directory " + dir}
+		}
+		files = append(files, list.Files...)
+		if list.Next == 0 {
+			return files, nil
+		}
+		first = list.Next
+	}
+}
+
+func (c *Client) DeleteFile(name string) error {
+	query := url.Values{}
+	query.Set("name", name)
+	resp := ErrResponse{}
+	if err := c.call(http.MethodGet, "/rr_delete", query, nil, &resp); err != nil {
+		return err
+	}
+	if resp.Err != 0 {
+		return &APIError{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: "could not delete " + name}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package duet
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	// UPLOAD_FOLDER is the folder below GCODES_DIR that Ymir uploads to
+	UPLOAD_FOLDER = "ymir"
+	LOCATION_SD   = "sdcard"
+)
+
+func init() {
+	printer.Register(types.API_TYPE_DUET, NewDriver)
+}
+
+/*
+Driver adapts the RepRapFirmware Client to the printer.PrinterDriver interface
+*/
+type Driver struct {
+	client *Client
+}
+
+var (
+	clientsLock = sync.Mutex{}
+	// clients holds one client per board, keyed by its url
+	clients = map[string]*Client{}
+)
+
+/*
+NewDriver reuses the client of the board so a driver made for every request doesn't log in every time.
+RepRapFirmware only has a handful of sessions and keeps them until they time out.
+*/
+func NewDriver(p types.Printer) (printer.PrinterDriver, error) {
+	fresh := NewClient(p.URL, p.Password)
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+	c, ok := clients[fresh.Endpoint]
+	if !ok || c.Password != fresh.Password {
+		c = fresh
+		clients[fresh.Endpoint] = c
+	}
+	return &Driver{
+		client: c,
+	}, nil
+}
+
+/*
+Connect logs in to the board. RepRapFirmware is always connected to its printer.
+*/
+func (d *Driver) Connect() error {
+	_, err := d.client.Connect()
+	return err
+}
+
+/*
+Info reports the firmware of the main board. RepRapFirmware serves its api itself, so there is no separate server.
+*/
+func (d *Driver) Info() (info printer.Info, err error) {
+	boards := []Board{}
+	if err = d.client.Model("boards", &boards); err != nil {
+		return
+	}
+	if len(boards) == 0 {
+		return info, nil
+	}
+	return printer.Info{
+		Firmware:        boards[0].FirmwareName,
+		FirmwareVersion: boards[0].FirmwareVersion,
+	}, nil
+}
+
+func (d *Driver) Status() (status printer.Status, err error) {
+	state := State{}
+	if err = d.client.Model("state", &state); err != nil {
+		return
+	}
+	heat := Heat{}
+	if err = d.client.Model("heat", &heat); err != nil {
+		return
+	}
+	tools := []Tool{}
+	if err = d.client.Model("tools", &tools); err != nil {
+		return
+	}
+
+	status = printer.Status{
+		State:        stateFromStatus(state.Status),
+		Text:         state.Status,
+		Temperatures: map[string]printer.Temperature{},
+	}
+	if len(tools) > 0 && len(tools[0].Heaters) > 0 {
+		if t, ok := heaterTemperature(heat, tools[0].Heaters[0]); ok {
+			status.Temperatures["tool0"] = t
+		}
+	}
+	if len(heat.BedHeaters) > 0 {
+		if t, ok := heaterTemperature(heat, heat.BedHeaters[0]); ok {
+			status.Temperatures["bed"] = t
+		}
+	}
+
+	if status.State == printer.STATE_PRINTING || status.State == printer.STATE_PAUSED {
+		job := Job{}
+		if err := d.client.Model("job", &job); err != nil {
+			return status, err
+		}
+		status.Job = jobFromModel(job)
+	}
+	return status, nil
+}
+
+func stateFromStatus(status string) printer.State {
+	switch status {
+	case STATUS_IDLE:
+		return printer.STATE_IDLE
+	case STATUS_PROCESSING, STATUS_SIMULATING, STATUS_RESUMING, STATUS_CANCELLING:
+		return printer.STATE_PRINTING
+	case STATUS_PAUSED, STATUS_PAUSING:
+		return printer.STATE_PAUSED
+	case STATUS_BUSY, STATUS_CHANGING_TOOL, STATUS_STARTING, STATUS_UPDATING:
+		return printer.STATE_BUSY
+	case STATUS_HALTED:
+		return printer.STATE_ERROR
+	default:
+		return printer.STATE_OFFLINE
+	}
+}
+
+/*
+heaterTemperature looks up a heater by number. Heater numbers of -1 mean "none".
+*/
+func heaterTemperature(heat Heat, number int) (printer.Temperature, bool) {
+	if number < 0 || number >= len(heat.Heaters) {
+		return printer.Temperature{}, false
+	}
+	h := heat.Heaters[number]
+	t := printer.Temperature{Actual: h.Current}
+	switch h.State {
+	case HEATER_ACTIVE:
+		t.Target = h.Active
+	case HEATER_STANDBY:
+		t.Target = h.Standby
+	}
+	return t, true
+}
+
+func jobFromModel(job Job) *printer.Job {
+	j := &printer.Job{}
+	if job.Duration != nil {
+		j.PrintTime = *job.Duration
+	}
+	if job.File != nil {
+		j.File = strings.TrimPrefix(job.File.FileName, GCODES_DIR+"/")
+		if job.File.Size > 0 {
+			j.Completion = float64(job.FilePosition) / float64(job.File.Size) * 100
+		}
+		j.EstimatedPrintTime = job.File.PrintTime
+	}
+	// prefer the estimate based on file progress, fall back to the slicer's
+	for _, left := range []*float64{job.TimesLeft.File, job.TimesLeft.Slicer, job.TimesLeft.Filament} {
+		if left != nil {
+			j.PrintTimeLeft = *left
+			break
+		}
+	}
+	if j.EstimatedPrintTime == 0 {
+		j.EstimatedPrintTime = j.PrintTime + j.PrintTimeLeft
+	}
+	return j
+}
+
+func (d *Driver) Upload(filename string, r io.Reader, print bool) error {
+	path := gcodePath(UPLOAD_FOLDER + "/" + filename)
+	if err := d.client.Upload(path, r); err != nil {
+		return err
+	}
+	if !print {
+		return nil
+	}
+	return d.client.GCode(startGCode(path))
+}
+
+func (d *Driver) Start(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.GCode(startGCode(gcodePath(path)))
+}
+
+/*
+storage checks the location is the board's SD card
+*/
+func storage(location string) error {
+	if location != "" && location != LOCATION_SD {
+		return fmt.Errorf("%w: %v", printer.ErrUnknownStorage, location)
+	}
+	return nil
+}
+
+/*
+gcodePath turns a path relative to the gcodes folder into the full SD card path RepRapFirmware wants
+*/
+func gcodePath(path string) string {
+	if strings.Contains(path, ":/") {
+		return path
+	}
+	return GCODES_DIR + "/" + strings.TrimLeft(path, "/")
+}
+
+func startGCode(path string) string {
+	return fmt.Sprintf(`M32 "%s"`, path)
+}
+
+func (d *Driver) Pause() error {
+	return d.client.GCode("M25")
+}
+
+func (d *Driver) Resume() error {
+	return d.client.GCode("M24")
+}
+
+/*
+Cancel pauses the print first since RepRapFirmware only cancels paused prints with M0
+*/
+func (d *Driver) Cancel() error {
+	state := State{}
+	if err := d.client.Model("state", &state); err != nil {
+		return err
+	}
+	if state.Status == STATUS_PAUSED {
+		return d.client.GCode("M0")
+	}
+	return d.client.GCode("M25\nM0")
+}
+
+func (d *Driver) ListFiles() ([]printer.File, error) {
+	return d.listFiles(GCODES_DIR, []printer.File{})
+}
+
+func (d *Driver) DeleteFile(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.DeleteFile(gcodePath(path))
+}
+
+func (d *Driver) listFiles(dir string, list []printer.File) ([]printer.File, error) {
+	entries, err := d.client.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		path := dir + "/" + e.Name
+		if e.Type == "d" {
+			if list, err = d.listFiles(path, list); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		date, _ := time.ParseInLocation(_TIME_FORMAT, e.Date, time.Local)
+		list = append(list, printer.File{
+			Name:     e.Name,
+			Path:     strings.TrimPrefix(path, GCODES_DIR+"/"),
+			Location: LOCATION_SD,
+			Size:     e.Size,
+			Date:     date,
+		})
+	}
+	return list, nil
+}
+
+func (d *Driver) SendGCode(commands ...string) error {
+	return d.client.GCode(strings.Join(commands, "\n"))
+}
@@ -0,0 +1,219 @@
+package duet
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	TEST_PASSWORD    = "secret"
+	TEST_SESSION_KEY = 4242
+)
+
+/*
+duetStandIn emulates the RepRapFirmware HTTP API including session keys
+*/
+type duetStandIn struct {
+	status   string
+	logins   int
+	logouts  int
+	expired  bool
+	uploaded map[string]string
+	gcodes   []string
+}
+
+func (d *duetStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enc := json.NewEncoder(w)
+	if r.URL.Path == "/rr_connect" {
+		if r.URL.Query().Get("password") != TEST_PASSWORD {
+			enc.Encode(ConnectResponse{Err: 1})
+			return
+		}
+		d.logins++
+		d.expired = false
+		enc.Encode(ConnectResponse{SessionTimeout: 8000, BoardType: "duet3mb6hc", APILevel: 1, SessionKey: TEST_SESSION_KEY})
+		return
+	}
+	if d.expired || r.Header.Get(_SESSION_KEY_HEADER) != "4242" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	switch r.URL.Path {
+	case "/rr_disconnect":
+		d.logouts++
+		enc.Encode(ErrResponse{})
+	case "/rr_model":
+		var result interface{}
+		switch q.Get("key") {
+		case "state":
+			result = State{Status: d.status}
+		case "heat":
+			result = Heat{BedHeaters: []int{0, -1}, Heaters: []Heater{
+				{Current: 59.8, Active: 60, State: HEATER_ACTIVE},
+				{Current: 150, Active: 215, Standby: 150, State: HEATER_STANDBY},
+			}}
+		case "tools":
+			result = []Tool{{Number: 0, Heaters: []int{1}}}
+		case "boards":
+			result = []Board{{FirmwareName: "RepRapFirmware for Duet 3 MB6HC", FirmwareVersion: "3.5.1", Name: "Duet 3 MB6HC"}}
+		case "job":
+			duration, left := 600.0, 1800.0
+			result = Job{
+				Duration:     &duration,
+				FilePosition: 250,
+				File:         &FileInfo{FileName: "0:/gcodes/ymir/test.gcode", Size: 1000, PrintTime: 2400},
+				TimesLeft:    TimesLeft{File: &left},
+			}
+		}
+		enc.Encode(ModelResponse{Key: q.Get("key"), Flags: q.Get("flags"), Result: result})
+	case "/rr_gcode":
+		d.gcodes = append(d.gcodes, q.Get("gcode"))
+		enc.Encode(GCodeResponse{Buff: 200})
+	case "/rr_upload":
+		b, _ := io.ReadAll(r.Body)
+		d.uploaded[q.Get("name")] = string(b)
+		enc.Encode(ErrResponse{})
+	case "/rr_filelist":
+		switch q.Get("dir") {
+		case GCODES_DIR:
+			if q.Get("first") == "0" {
+				enc.Encode(FileList{Dir: GCODES_DIR, Files: []FileEntry{{Type: "f", Name: "cube.gcode", Size: 1024, Date: "2024-03-01T10:00:00"}}, Next: 1})
+				return
+			}
+			enc.Encode(FileList{Dir: GCODES_DIR, First: 1, Files: []FileEntry{{Type: "d", Name: "ymir"}}})
+		case GCODES_DIR + "/ymir":
+			enc.Encode(FileList{Dir: q.Get("dir"), Files: []FileEntry{{Type: "f", Name: "test.gcode", Size: 2048}}})
+		default:
+			enc.Encode(FileList{Err: 2})
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type DuetDriverTestSuite struct {
+	suite.Suite
+	standIn *duetStandIn
+	server  *httptest.Server
+	driver  printer.PrinterDriver
+}
+
+func (suite *DuetDriverTestSuite) SetupTest() {
+	suite.standIn = &duetStandIn{status: STATUS_IDLE, uploaded: map[string]string{}}
+	suite.server = httptest.NewServer(suite.standIn)
+	d, err := printer.NewDriver(types.Printer{URL: suite.server.URL, APIType: types.API_TYPE_DUET, Password: TEST_PASSWORD})
+	assert.NoError(suite.T(), err)
+	suite.driver = d
+}
+
+func (suite *DuetDriverTestSuite) TearDownTest() {
+	suite.server.Close()
+	clientsLock.Lock()
+	delete(clients, suite.server.URL)
+	clientsLock.Unlock()
+}
+
+func (suite *DuetDriverTestSuite) TestConnect_BadPassword() {
+	d, _ := NewDriver(types.Printer{URL: suite.server.URL})
+	err := d.Connect()
+	var statusErr printer.StatusError
+	assert.ErrorAs(suite.T(), err, &statusErr)
+	assert.Equal(suite.T(), http.StatusForbidden, statusErr.HTTPStatus())
+}
+
+func (suite *DuetDriverTestSuite) TestSessionExpired() {
+	assert.NoError(suite.T(), suite.driver.Pause())
+	suite.standIn.expired = true
+	assert.NoError(suite.T(), suite.driver.Resume())
+	assert.Equal(suite.T(), 2, suite.standIn.logins, "should log in again after a 401")
+	assert.Equal(suite.T(), []string{"M25", "M24"}, suite.standIn.gcodes)
+}
+
+func (suite *DuetDriverTestSuite) TestSharedSession() {
+	p := types.Printer{URL: suite.server.URL, APIType: types.API_TYPE_DUET, Password: TEST_PASSWORD}
+	for i := 0; i < 3; i++ {
+		d, err := printer.NewDriver(p)
+		assert.NoError(suite.T(), err)
+		_, err = d.Status()
+		assert.NoError(suite.T(), err)
+	}
+	assert.Equal(suite.T(), 1, suite.standIn.logins, "drivers for the same board share its session")
+
+	assert.NoError(suite.T(), suite.driver.Connect())
+	assert.Equal(suite.T(), 2, suite.standIn.logins)
+	assert.Equal(suite.T(), 1, suite.standIn.logouts, "connecting again gives the old session back")
+}
+
+func (suite *DuetDriverTestSuite) TestInfo() {
+	info, err := suite.driver.Info()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.Info{Firmware: "RepRapFirmware for Duet 3 MB6HC", FirmwareVersion: "3.5.1"}, info)
+}
+
+func (suite *DuetDriverTestSuite) TestStatus_Idle() {
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+	assert.Equal(suite.T(), printer.Temperature{Actual: 150, Target: 150}, status.Temperatures["tool0"])
+	assert.Equal(suite.T(), printer.Temperature{Actual: 59.8, Target: 60}, status.Temperatures["bed"])
+	assert.Nil(suite.T(), status.Job)
+}
+
+func (suite *DuetDriverTestSuite) TestStatus_Printing() {
+	suite.standIn.status = STATUS_PROCESSING
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_PRINTING, status.State)
+	assert.Equal(suite.T(), "ymir/test.gcode", status.Job.File)
+	assert.Equal(suite.T(), 25.0, status.Job.Completion)
+	assert.Equal(suite.T(), 600.0, status.Job.PrintTime)
+	assert.Equal(suite.T(), 1800.0, status.Job.PrintTimeLeft)
+	assert.Equal(suite.T(), 2400.0, status.Job.EstimatedPrintTime)
+}
+
+func (suite *DuetDriverTestSuite) TestUploadAndPrint() {
+	assert.NoError(suite.T(), suite.driver.Upload("test.gcode", strings.NewReader("G28"), true))
+	assert.Equal(suite.T(), "G28", suite.standIn.uploaded["0:/gcodes/ymir/test.gcode"])
+	assert.Equal(suite.T(), []string{`M32 "0:/gcodes/ymir/test.gcode"`}, suite.standIn.gcodes)
+}
+
+func (suite *DuetDriverTestSuite) TestCancel() {
+	suite.standIn.status = STATUS_PROCESSING
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	suite.standIn.status = STATUS_PAUSED
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	assert.Equal(suite.T(), []string{"M25\nM0", "M0"}, suite.standIn.gcodes)
+}
+
+func (suite *DuetDriverTestSuite) TestListFiles() {
+	files, err := suite.driver.ListFiles()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), files, 2)
+	assert.Equal(suite.T(), "cube.gcode", files[0].Path)
+	assert.Equal(suite.T(), 2024, files[0].Date.Year())
+	assert.Equal(suite.T(), "ymir/test.gcode", files[1].Path)
+	assert.Equal(suite.T(), LOCATION_SD, files[1].Location)
+
+	assert.NoError(suite.T(), suite.driver.Start(files[1].Location, files[1].Path))
+	assert.Equal(suite.T(), []string{`M32 "0:/gcodes/ymir/test.gcode"`}, suite.standIn.gcodes)
+}
+
+func (suite *DuetDriverTestSuite) TestSendGCode() {
+	assert.NoError(suite.T(), suite.driver.SendGCode("M104 S0", "M140 S0"))
+	assert.Equal(suite.T(), []string{"M104 S0\nM140 S0"}, suite.standIn.gcodes)
+}
+
+func TestDuetDriverTestSuite(t *testing.T) {
+	suite.Run(t, new(DuetDriverTestSuite))
+}
@@ -0,0 +1,128 @@
+package duet
+
+/*
+Data model for the parts of the RepRapFirmware HTTP API and object model that Ymir uses
+*/
+
+const (
+	STATUS_IDLE          = "idle"
+	STATUS_BUSY          = "busy"
+	STATUS_PROCESSING    = "processing"
+	STATUS_SIMULATING    = "simulating"
+	STATUS_PAUSED        = "paused"
+	STATUS_PAUSING       = "pausing"
+	STATUS_RESUMING      = "resuming"
+	STATUS_CANCELLING    = "cancelling"
+	STATUS_CHANGING_TOOL = "changingTool"
+	STATUS_STARTING      = "starting"
+	STATUS_UPDATING      = "updating"
+	STATUS_HALTED        = "halted"
+	STATUS_OFF           = "off"
+
+	HEATER_OFF     = "off"
+	HEATER_STANDBY = "standby"
+	HEATER_ACTIVE  = "active"
+)
+
+// ConnectResponse is returned by rr_connect
+type ConnectResponse struct {
+	Err            int    `json:"err"`
+	SessionTimeout int    `json:"sessionTimeout"`
+	BoardType      string `json:"boardType"`
+	APILevel       int    `json:"apiLevel"`
+	SessionKey     uint32 `json:"sessionKey"`
+}
+
+// ErrResponse is returned by rr_upload, rr_delete and friends
+type ErrResponse struct {
+	Err int `json:"err"`
+}
+
+// GCodeResponse is returned by rr_gcode
+type GCodeResponse struct {
+	Buff int `json:"buff"`
+}
+
+// ModelResponse wraps every rr_model answer
+type ModelResponse struct {
+	Key    string      `json:"key"`
+	Flags  string      `json:"flags"`
+	Result interface{} `json:"result"`
+}
+
+// State is the "state" key of the object model
+type State struct {
+	Status         string `json:"status"`
+	CurrentTool    int    `json:"currentTool"`
+	DisplayMessage string `json:"displayMessage"`
+	UpTime         int64  `json:"upTime"`
+}
+
+// Board is an entry of the "boards" key of the object model
+type Board struct {
+	FirmwareName    string `json:"firmwareName"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	Name            string `json:"name"`
+	ShortName       string `json:"shortName"`
+}
+
+// Heat is the "heat" key of the object model
+type Heat struct {
+	BedHeaters []int    `json:"bedHeaters"`
+	Heaters    []Heater `json:"heaters"`
+}
+
+type Heater struct {
+	Current float64 `json:"current"`
+	Active  float64 `json:"active"`
+	Standby float64 `json:"standby"`
+	State   string  `json:"state"`
+}
+
+// Tool is an entry of the "tools" key of the object model
+type Tool struct {
+	Number  int       `json:"number"`
+	Name    string    `json:"name"`
+	Heaters []int     `json:"heaters"`
+	Active  []float64 `json:"active"`
+	Standby []float64 `json:"standby"`
+	State   string    `json:"state"`
+}
+
+// Job is the "job" key of the object model
+type Job struct {
+	Duration     *float64  `json:"duration"`
+	FilePosition int64     `json:"filePosition"`
+	File         *FileInfo `json:"file"`
+	TimesLeft    TimesLeft `json:"timesLeft"`
+}
+
+type TimesLeft struct {
+	File     *float64 `json:"file"`
+	Filament *float64 `json:"filament"`
+	Slicer   *float64 `json:"slicer"`
+}
+
+// FileInfo is the parsed information RepRapFirmware keeps about a print file
+type FileInfo struct {
+	FileName  string  `json:"fileName"`
+	Size      int64   `json:"size"`
+	PrintTime float64 `json:"printTime"`
+	Height    float64 `json:"height"`
+}
+
+// FileList is returned by rr_filelist
+type FileList struct {
+	Dir   string      `json:"dir"`
+	First int         `json:"first"`
+	Files []FileEntry `json:"files"`
+	Next  int         `json:"next"`
+	Err   int         `json:"err"`
+}
+
+type FileEntry struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Date string `json:"date"`
+}
@@ -0,0 +1,329 @@
+package marlin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/printer"
+)
+
+const (
+	// _OK_TIMEOUT is how long we wait for an ok. busy: and temperature lines restart the wait
+	_OK_TIMEOUT = 10 * time.Second
+	// _MAX_RESENDS stops us looping forever on a broken line
+	_MAX_RESENDS = 10
+)
+
+var (
+	ErrClosed  = errors.New("marlin: connection closed")
+	ErrTimeout = errors.New("marlin: timed out waiting for ok")
+	ErrHalted  = errors.New("marlin: printer halted")
+
+	tempPattern = regexp.MustCompile(`\b([TB]\d*):\s*(-?[\d.]+)\s*/\s*(-?[\d.]+)`)
+	sdPattern   = regexp.MustCompile(`SD printing byte (\d+)/(\d+)`)
+)
+
+/*
+Conn speaks the Marlin serial protocol: every line is numbered and checksummed,
+is acknowledged with ok and may be asked for again with Resend.
+One command is in flight at a time.
+*/
+type Conn struct {
+	port io.ReadWriteCloser
+
+	// lock serializes commands
+	lock       sync.Mutex
+	lineNumber int
+
+	replies chan reply
+	alive   chan struct{}
+	done    chan struct{}
+
+	// mu protects the state the reader updates
+	mu       sync.Mutex
+	temps    map[string]printer.Temperature
+	lastTemp time.Time
+	halted   string
+	err      error
+}
+
+type reply struct {
+	lines  []string
+	resend int
+}
+
+func NewConn(port io.ReadWriteCloser) *Conn {
+	c := &Conn{
+		port:    port,
+		replies: make(chan reply, 8),
+		alive:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		temps:   map[string]printer.Temperature{},
+	}
+	go c.read()
+	return c
+}
+
+/*
+read dispatches the lines coming from the printer until the port is closed
+*/
+func (c *Conn) read() {
+	defer close(c.done)
+	scanner := bufio.NewScanner(c.port)
+	lines := []string{}
+	resend := -1
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		log.Debugf("marlin recv: %v", line)
+
+		if c.parseTemperatures(line) {
+			c.keepAlive()
+		}
+		switch {
+		case strings.HasPrefix(line, "ok"):
+			c.replies <- reply{lines: append(lines, line), resend: resend}
+			lines = []string{}
+			resend = -1
+		case strings.HasPrefix(line, "Resend:") || strings.HasPrefix(line, "rs "):
+			fields := strings.Fields(strings.TrimPrefix(strings.TrimPrefix(line, "Resend:"), "rs "))
+			if len(fields) > 0 {
+				resend, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "N"))
+			}
+		case strings.HasPrefix(line, "echo:busy:") || strings.HasPrefix(line, "busy:"):
+			c.keepAlive()
+		case strings.HasPrefix(line, "Error:") && (strings.Contains(line, "halted") || strings.Contains(line, "Kill")):
+			c.mu.Lock()
+			c.halted = strings.TrimPrefix(line, "Error:")
+			c.mu.Unlock()
+		case strings.HasPrefix(line, "start"):
+			// the printer was reset
+			lines = []string{}
+			resend = -1
+		case strings.HasPrefix(line, " T:") || strings.HasPrefix(line, "T:"):
+			// temperature auto report, not part of a reply
+		default:
+			lines = append(lines, line)
+		}
+	}
+	c.mu.Lock()
+	c.err = scanner.Err()
+	c.mu.Unlock()
+}
+
+func (c *Conn) keepAlive() {
+	select {
+	case c.alive <- struct{}{}:
+	default:
+	}
+}
+
+/*
+parseTemperatures stores T:210.0 /210.0 B:60.0 /60.0 style reports. T is the active tool, T0..Tn are the tools.
+*/
+func (c *Conn) parseTemperatures(line string) bool {
+	matches := tempPattern.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hasTools := strings.Contains(line, "T0:")
+	for _, m := range matches {
+		actual, _ := strconv.ParseFloat(m[2], 64)
+		target, _ := strconv.ParseFloat(m[3], 64)
+		key := ""
+		switch {
+		case m[1] == "B":
+			key = "bed"
+		case m[1] == "T":
+			if hasTools {
+				continue
+			}
+			key = "tool0"
+		case strings.HasPrefix(m[1], "T"):
+			key = "tool" + m[1][1:]
+		default:
+			continue
+		}
+		c.temps[key] = printer.Temperature{Actual: actual, Target: target}
+	}
+	c.lastTemp = time.Now()
+	return true
+}
+
+/*
+Temperatures returns the last reported temperatures and when they were reported
+*/
+func (c *Conn) Temperatures() (map[string]printer.Temperature, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	temps := make(map[string]printer.Temperature, len(c.temps))
+	for k, v := range c.temps {
+		temps[k] = v
+	}
+	return temps, c.lastTemp
+}
+
+/*
+Halted returns the error the printer halted with, or "" if it did not
+*/
+func (c *Conn) Halted() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.halted
+}
+
+/*
+Closed reports whether the port has gone away
+*/
+func (c *Conn) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func checksum(line string) int {
+	cs := 0
+	for i := 0; i < len(line); i++ {
+		cs ^= int(line[i])
+	}
+	return cs
+}
+
+/*
+formatLine numbers and checksums a command e.g. N12 G1 X10*85
+*/
+func formatLine(n int, cmd string) string {
+	line := fmt.Sprintf("N%d %s", n, cmd)
+	return fmt.Sprintf("%s*%d\n", line, checksum(line))
+}
+
+/*
+StripComment removes ; comments and surrounding white space from a G-code line
+*/
+func StripComment(line string) string {
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+/*
+ResetLineNumber sends M110 so the printer and us agree on the next line number
+*/
+func (c *Conn) ResetLineNumber() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lineNumber = 0
+	_, err := c.send("M110 N0")
+	return err
+}
+
+/*
+Command sends one command and returns the lines the printer answered with, including the ok
+*/
+func (c *Conn) Command(cmd string) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.send(cmd)
+}
+
+func (c *Conn) send(cmd string) ([]string, error) {
+	if c.Halted() != "" {
+		return nil, ErrHalted
+	}
+	n := c.lineNumber
+	c.lineNumber++
+	line := formatLine(n, cmd)
+
+	for resends := 0; ; resends++ {
+		c.drain()
+		log.Debugf("marlin send: %v", strings.TrimSpace(line))
+		if _, err := io.WriteString(c.port, line); err != nil {
+			return nil, err
+		}
+		r, err := c.waitForOk()
+		if err != nil {
+			return nil, err
+		}
+		if r.resend < 0 {
+			return r.lines, nil
+		}
+		if r.resend != n {
+			return nil, fmt.Errorf("marlin: printer asked for line %d, last sent %d", r.resend, n)
+		}
+		if resends == _MAX_RESENDS {
+			return nil, fmt.Errorf("marlin: line %d resent %d times", n, resends)
+		}
+	}
+}
+
+/*
+drain drops replies to lines we stopped waiting for, e.g. after ErrTimeout. Left in replies they would be
+taken for the answer to the next line and every line after would be answered one reply late.
+*/
+func (c *Conn) drain() {
+	for {
+		select {
+		case r := <-c.replies:
+			log.Debugf("marlin: dropped late reply %v", r.lines)
+		default:
+			return
+		}
+	}
+}
+
+func (c *Conn) waitForOk() (reply, error) {
+	timer := time.NewTimer(_OK_TIMEOUT)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-c.replies:
+			return r, nil
+		case <-c.alive:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(_OK_TIMEOUT)
+		case <-c.done:
+			return reply{}, ErrClosed
+		case <-timer.C:
+			if halted := c.Halted(); halted != "" {
+				return reply{}, fmt.Errorf("%w: %v", ErrHalted, halted)
+			}
+			return reply{}, ErrTimeout
+		}
+	}
+}
+
+/*
+SDProgress parses the answer to M27. ok is false when the printer is not printing from SD.
+*/
+func SDProgress(lines []string) (pos int64, size int64, ok bool) {
+	for _, l := range lines {
+		if m := sdPattern.FindStringSubmatch(l); m != nil {
+			pos, _ = strconv.ParseInt(m[1], 10, 64)
+			size, _ = strconv.ParseInt(m[2], 10, 64)
+			return pos, size, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (c *Conn) Close() error {
+	return c.port.Close()
+}
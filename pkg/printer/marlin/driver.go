@@ -0,0 +1,624 @@
+package marlin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	DEFAULT_BAUD_RATE = 115200
+	LOCATION_SD       = "sdcard"
+	// TEMPERATURE_INTERVAL is the M155 auto report interval, or how often M105 is sent if auto report is not supported
+	TEMPERATURE_INTERVAL = 2 * time.Second
+)
+
+var (
+	ErrNoDevice     = errors.New("marlin: printer has no device path")
+	ErrNotConnected = &statusError{"marlin: printer is not connected", 409}
+	ErrBusy         = &statusError{"marlin: printer is busy with another job", 409}
+
+	sessionsLock = &sync.Mutex{}
+	sessions     = map[string]*session{}
+	// connecting serializes Connect per device so opening one port doesn't hold up the others
+	connecting = map[string]*sync.Mutex{}
+
+	// m115Pattern matches the KEY: names of an M115 report
+	m115Pattern = regexp.MustCompile(`\b([A-Z_]+):`)
+)
+
+/*
+statusError lets the sentinel errors carry an HTTP status like the other drivers' APIErrors
+*/
+type statusError struct {
+	msg    string
+	status int
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func (e *statusError) HTTPStatus() int {
+	return e.status
+}
+
+func init() {
+	printer.Register(types.API_TYPE_MARLIN, NewDriver)
+}
+
+/*
+session is the open serial connection to one device. Drivers are created per request
+so the connection and the job being streamed live here, shared by device path.
+*/
+type session struct {
+	conn *Conn
+	info printer.Info
+
+	lock     sync.Mutex
+	job      *job
+	sdPaused bool
+
+	// sdWrite is held for reading by every command and for writing while a file is written to the SD card,
+	// since Marlin saves whatever it is sent between M28 and M29 into the file
+	sdWrite sync.RWMutex
+}
+
+/*
+Driver streams G-code to a Marlin printer connected over USB serial
+*/
+type Driver struct {
+	p types.Printer
+}
+
+func NewDriver(p types.Printer) (printer.PrinterDriver, error) {
+	if p.DevicePath == "" {
+		return nil, ErrNoDevice
+	}
+	if p.BaudRate == 0 {
+		p.BaudRate = DEFAULT_BAUD_RATE
+	}
+	return &Driver{p: p}, nil
+}
+
+func (d *Driver) session() *session {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+	s, ok := sessions[d.p.DevicePath]
+	if !ok || s.conn.Closed() {
+		return nil
+	}
+	return s
+}
+
+func (d *Driver) connected() (*session, error) {
+	s := d.session()
+	if s == nil {
+		return nil, ErrNotConnected
+	}
+	return s, nil
+}
+
+/*
+command returns the session for sending commands and the func to call when done.
+It fails with ErrBusy rather than wait while a file is written to the SD card.
+*/
+func (d *Driver) command() (*session, func(), error) {
+	s, err := d.connected()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.sdWrite.TryRLock() {
+		return nil, nil, ErrBusy
+	}
+	return s, s.sdWrite.RUnlock, nil
+}
+
+/*
+Connect opens the serial port unless it is already open. A halted printer is reopened,
+which resets it on most boards. The handshake runs outside sessionsLock so a slow board only holds up Connects to itself.
+*/
+func (d *Driver) Connect() error {
+	sessionsLock.Lock()
+	l, ok := connecting[d.p.DevicePath]
+	if !ok {
+		l = &sync.Mutex{}
+		connecting[d.p.DevicePath] = l
+	}
+	sessionsLock.Unlock()
+	l.Lock()
+	defer l.Unlock()
+
+	sessionsLock.Lock()
+	old, ok := sessions[d.p.DevicePath]
+	if ok && !old.conn.Closed() && old.conn.Halted() == "" {
+		sessionsLock.Unlock()
+		return nil
+	}
+	delete(sessions, d.p.DevicePath)
+	sessionsLock.Unlock()
+	if ok {
+		old.conn.Close()
+	}
+
+	port, err := OpenPort(d.p.DevicePath, d.p.BaudRate)
+	if err != nil {
+		return err
+	}
+	conn := NewConn(port)
+
+	// the board may still be booting after the port was opened, so try a few times
+	for attempt := 0; ; attempt++ {
+		if err = conn.ResetLineNumber(); err == nil || attempt == 2 {
+			break
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	s := &session{conn: conn}
+	if lines, err := conn.Command("M115"); err == nil {
+		s.info = firmwareInfo(lines)
+	}
+	lines, err := conn.Command("M155 S" + strconv.Itoa(int(TEMPERATURE_INTERVAL.Seconds())))
+	if err != nil || unknownCommand(lines) {
+		go pollTemperatures(s)
+	}
+
+	sessionsLock.Lock()
+	sessions[d.p.DevicePath] = s
+	sessionsLock.Unlock()
+	return nil
+}
+
+/*
+firmwareInfo parses the M115 report, e.g.
+FIRMWARE_NAME:Marlin 2.1.2.1 (Jun 30 2023) SOURCE_CODE_URL:github.com/MarlinFirmware/Marlin PROTOCOL_VERSION:1.0 MACHINE_TYPE:Ender-3 V2
+*/
+func firmwareInfo(lines []string) printer.Info {
+	info := printer.Info{}
+	for _, l := range lines {
+		if !strings.Contains(l, "FIRMWARE_NAME:") {
+			continue
+		}
+		fields := map[string]string{}
+		keys := m115Pattern.FindAllStringSubmatchIndex(l, -1)
+		for n, k := range keys {
+			end := len(l)
+			if n+1 < len(keys) {
+				end = keys[n+1][0]
+			}
+			fields[l[k[2]:k[3]]] = strings.TrimSpace(l[k[1]:end])
+		}
+		name := strings.Fields(fields["FIRMWARE_NAME"])
+		if len(name) > 0 {
+			info.Firmware = name[0]
+		}
+		if len(name) > 1 {
+			info.FirmwareVersion = name[1]
+		}
+		info.Model = fields["MACHINE_TYPE"]
+		break
+	}
+	return info
+}
+
+/*
+Info reports what the firmware said in its M115 report when the port was opened. The port is opened if it isn't yet.
+*/
+func (d *Driver) Info() (printer.Info, error) {
+	s := d.session()
+	if s == nil {
+		if err := d.Connect(); err != nil {
+			return printer.Info{}, err
+		}
+		if s = d.session(); s == nil {
+			return printer.Info{}, ErrNotConnected
+		}
+	}
+	return s.info, nil
+}
+
+func unknownCommand(lines []string) bool {
+	for _, l := range lines {
+		if strings.Contains(l, "Unknown command") {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+pollTemperatures sends M105 for printers built without AUTO_REPORT_TEMPERATURES, except while writing to the SD card
+*/
+func pollTemperatures(s *session) {
+	ticker := time.NewTicker(TEMPERATURE_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		if s.conn.Closed() {
+			return
+		}
+		if !s.sdWrite.TryRLock() {
+			continue
+		}
+		if _, err := s.conn.Command("M105"); err != nil {
+			log.Debugf("marlin M105: %v", err)
+		}
+		s.sdWrite.RUnlock()
+	}
+}
+
+func (d *Driver) Status() (status printer.Status, err error) {
+	s := d.session()
+	if s == nil {
+		return printer.Status{
+			State:        printer.STATE_OFFLINE,
+			Text:         "Closed",
+			Temperatures: map[string]printer.Temperature{},
+		}, nil
+	}
+	temps, _ := s.conn.Temperatures()
+	status = printer.Status{
+		State:        printer.STATE_IDLE,
+		Text:         "Operational",
+		Temperatures: temps,
+	}
+	if halted := s.conn.Halted(); halted != "" {
+		status.State = printer.STATE_ERROR
+		status.Text = halted
+		return status, nil
+	}
+	if !s.sdWrite.TryRLock() {
+		status.State = printer.STATE_BUSY
+		status.Text = "Writing to SD card"
+		return status, nil
+	}
+	defer s.sdWrite.RUnlock()
+
+	s.lock.Lock()
+	j := s.job
+	s.lock.Unlock()
+	if j != nil && j.finished() {
+		if err := j.failed(); err != nil {
+			status.State = printer.STATE_ERROR
+			status.Text = fmt.Sprintf("Streaming %v stopped: %v", j.name, err)
+			status.Job = j.progress()
+			return status, nil
+		}
+	}
+	if j != nil && !j.finished() {
+		status.Job = j.progress()
+		status.State = printer.STATE_PRINTING
+		status.Text = "Printing"
+		if j.isPaused() {
+			status.State = printer.STATE_PAUSED
+			status.Text = "Paused"
+		}
+		return status, nil
+	}
+
+	lines, err := s.conn.Command("M27")
+	if err != nil {
+		return status, err
+	}
+	pos, size, printing := SDProgress(lines)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !printing {
+		s.sdPaused = false
+		return status, nil
+	}
+	status.State = printer.STATE_PRINTING
+	status.Text = "Printing from SD"
+	if s.sdPaused {
+		status.State = printer.STATE_PAUSED
+		status.Text = "Paused"
+	}
+	status.Job = &printer.Job{}
+	if size > 0 {
+		status.Job.Completion = float64(pos) / float64(size) * 100
+	}
+	return status, nil
+}
+
+/*
+Upload streams the file to the printer when print is set, otherwise it is written to the SD card with M28/M29.
+Other commands fail with ErrBusy while the file is written.
+*/
+func (d *Driver) Upload(filename string, r io.Reader, print bool) error {
+	if !print {
+		return d.writeSD(filename, r)
+	}
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.job != nil && !s.job.finished() {
+		return ErrBusy
+	}
+
+	f, err := os.CreateTemp("", "ymir-*.gcode")
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	s.job = newJob(filename, size)
+	go s.job.stream(s.conn, f)
+	return nil
+}
+
+func (d *Driver) writeSD(filename string, r io.Reader) (err error) {
+	s, err := d.connected()
+	if err != nil {
+		return err
+	}
+	// wait for the commands in flight, new ones fail with ErrBusy meanwhile
+	s.sdWrite.Lock()
+	defer s.sdWrite.Unlock()
+	s.lock.Lock()
+	busy := s.job != nil && !s.job.finished()
+	s.lock.Unlock()
+	if busy {
+		return ErrBusy
+	}
+
+	// the file has to be closed whatever happens, or everything sent afterwards ends up in it
+	defer func() {
+		if _, closeErr := s.conn.Command("M29"); err == nil {
+			err = closeErr
+		}
+	}()
+	if _, err := s.conn.Command("M28 " + dosName(filename)); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cmd := StripComment(scanner.Text())
+		if cmd == "" {
+			continue
+		}
+		if _, err := s.conn.Command(cmd); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+/*
+dosName makes an 8.3 file name since Marlin can only create those on the SD card
+*/
+func dosName(filename string) string {
+	base := strings.ToUpper(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	name := []rune{}
+	for _, r := range base {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			name = append(name, r)
+		}
+		if len(name) == 8 {
+			break
+		}
+	}
+	if len(name) == 0 {
+		name = []rune("YMIR")
+	}
+	return string(name) + ".GCO"
+}
+
+/*
+Start prints a file from the SD card
+*/
+func (d *Driver) Start(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.job != nil && !s.job.finished() {
+		return ErrBusy
+	}
+	if _, err := s.conn.Command("M23 " + path); err != nil {
+		return err
+	}
+	s.sdPaused = false
+	_, err = s.conn.Command("M24")
+	return err
+}
+
+/*
+hostJob returns the job being streamed, or nil when the printer is idle or printing from SD
+*/
+func (s *session) hostJob() *job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.job != nil && !s.job.finished() {
+		return s.job
+	}
+	return nil
+}
+
+func (d *Driver) Pause() error {
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	if j := s.hostJob(); j != nil {
+		j.pause()
+		return nil
+	}
+	if _, err := s.conn.Command("M25"); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.sdPaused = true
+	s.lock.Unlock()
+	return nil
+}
+
+func (d *Driver) Resume() error {
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	if j := s.hostJob(); j != nil {
+		j.resume()
+		return nil
+	}
+	if _, err := s.conn.Command("M24"); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.sdPaused = false
+	s.lock.Unlock()
+	return nil
+}
+
+/*
+Cancel stops streaming and turns the heaters off, or aborts the SD print with M524 and clears a failed stream
+*/
+func (d *Driver) Cancel() error {
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	if j := s.hostJob(); j != nil {
+		j.cancel()
+		<-j.done
+		for _, cmd := range []string{"M104 S0", "M140 S0", "M107"} {
+			if _, err := s.conn.Command(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err = s.conn.Command("M524")
+	s.lock.Lock()
+	s.sdPaused = false
+	// a stream that failed is done with, the printer is no longer in error
+	s.job = nil
+	s.lock.Unlock()
+	return err
+}
+
+/*
+ListFiles lists the SD card with M20 L. Path is the DOS name M23 needs, Name the long name if there is one.
+*/
+func (d *Driver) ListFiles() ([]printer.File, error) {
+	s, done, err := d.command()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	lines, err := s.conn.Command("M20 L")
+	if err != nil {
+		return nil, err
+	}
+	files := []printer.File{}
+	listing := false
+	for _, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "Begin file list"):
+			listing = true
+		case strings.HasPrefix(l, "End file list"):
+			listing = false
+		case listing:
+			fields := strings.Fields(l)
+			if len(fields) == 0 {
+				continue
+			}
+			f := printer.File{Name: fields[0], Path: fields[0], Location: LOCATION_SD}
+			if len(fields) > 1 {
+				f.Size, _ = strconv.ParseInt(fields[1], 10, 64)
+			}
+			if len(fields) > 2 {
+				f.Name = strings.Join(fields[2:], " ")
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+/*
+DeleteFile removes a file from the SD card with M30. Marlin answers ok either way, so the reply is checked for a failure.
+*/
+func (d *Driver) DeleteFile(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	lines, err := s.conn.Command("M30 " + path)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "Deletion failed") {
+			return fmt.Errorf("%w: %v", os.ErrNotExist, path)
+		}
+	}
+	return nil
+}
+
+/*
+storage checks the location is the SD card, the only storage Marlin has
+*/
+func storage(location string) error {
+	if location != "" && location != LOCATION_SD {
+		return fmt.Errorf("%w: %v", printer.ErrUnknownStorage, location)
+	}
+	return nil
+}
+
+func (d *Driver) SendGCode(commands ...string) error {
+	s, done, err := d.command()
+	if err != nil {
+		return err
+	}
+	defer done()
+	for _, c := range commands {
+		cmd := StripComment(c)
+		if cmd == "" {
+			continue
+		}
+		if _, err := s.conn.Command(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
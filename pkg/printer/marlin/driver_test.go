@@ -0,0 +1,438 @@
+//go:build linux
+
+package marlin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+/*
+marlinEmulator answers on the master side of a pty the way Marlin does on a USB serial port
+*/
+type marlinEmulator struct {
+	port       *os.File
+	autoReport bool
+
+	mu          sync.Mutex
+	commands    []string
+	expected    int
+	corruptNext bool
+	resends     int
+	sdPrinting  bool
+	hold        map[string]chan struct{}
+}
+
+func newMarlinEmulator(port *os.File) *marlinEmulator {
+	return &marlinEmulator{port: port, autoReport: true, hold: map[string]chan struct{}{}}
+}
+
+func (m *marlinEmulator) write(lines ...string) {
+	for _, l := range lines {
+		io.WriteString(m.port, l+"\n")
+	}
+}
+
+func (m *marlinEmulator) run() {
+	scanner := bufio.NewScanner(m.port)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		star := strings.LastIndexByte(line, '*')
+		if !strings.HasPrefix(line, "N") || star < 0 {
+			m.write("Error:No Line Number with checksum, Last Line: " + strconv.Itoa(m.expected-1))
+			continue
+		}
+		body := line[:star]
+		cs, _ := strconv.Atoi(line[star+1:])
+		fields := strings.SplitN(body, " ", 2)
+		n, _ := strconv.Atoi(fields[0][1:])
+		cmd := fields[1]
+
+		m.mu.Lock()
+		corrupt := m.corruptNext || cs != checksum(body)
+		m.corruptNext = false
+		if strings.HasPrefix(cmd, "M110") {
+			m.expected = n
+		}
+		if corrupt || n != m.expected {
+			m.resends++
+			expected := m.expected
+			m.mu.Unlock()
+			m.write(fmt.Sprintf("Error:checksum mismatch, Last Line: %d", expected-1), fmt.Sprintf("Resend: %d", expected), "ok")
+			continue
+		}
+		m.expected++
+		m.commands = append(m.commands, cmd)
+		hold := m.hold[cmd]
+		m.mu.Unlock()
+
+		if hold != nil {
+			m.write("echo:busy: processing")
+			<-hold
+		}
+		m.respond(cmd)
+	}
+}
+
+func (m *marlinEmulator) respond(cmd string) {
+	word := strings.Fields(cmd)[0]
+	switch word {
+	case "M115":
+		m.write("FIRMWARE_NAME:Marlin 2.1.2.1 (Ymir) SOURCE_CODE_URL:github.com/MarlinFirmware/Marlin PROTOCOL_VERSION:1.0 MACHINE_TYPE:Ender-3 V2 EXTRUDER_COUNT:1", "Cap:AUTOREPORT_TEMP:1", "ok")
+	case "M155":
+		if !m.autoReport {
+			m.write(`echo:Unknown command: "`+cmd+`"`, "ok")
+			return
+		}
+		m.write("ok", " T:20.5 /0.0 B:21.0 /0.0 @:0 B@:0")
+	case "M105":
+		m.write("ok T:210.0 /215.0 B:60.0 /60.0 @:127 B@:0")
+	case "M27":
+		m.mu.Lock()
+		printing := m.sdPrinting
+		m.mu.Unlock()
+		if printing {
+			m.write("SD printing byte 50/200", "ok")
+		} else {
+			m.write("Not SD printing", "ok")
+		}
+	case "M20":
+		m.write("Begin file list", "CUBE.GCO 1234 calibration cube.gcode", "BENCHY.GCO 5678", "End file list", "ok")
+	case "M23":
+		m.write("File opened: "+strings.TrimPrefix(cmd, "M23 ")+" Size: 200", "File selected", "ok")
+	case "M30":
+		name := strings.TrimPrefix(cmd, "M30 ")
+		if name == "CUBE.GCO" || name == "BENCHY.GCO" {
+			m.write("File deleted:"+name, "ok")
+		} else {
+			m.write("Deletion failed, File: "+name+".", "ok")
+		}
+	case "M24":
+		m.mu.Lock()
+		m.sdPrinting = true
+		m.mu.Unlock()
+		m.write("ok")
+	case "M112":
+		m.write("Error:Printer halted. kill() called!")
+	default:
+		m.write("ok")
+	}
+}
+
+func (m *marlinEmulator) received() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.commands...)
+}
+
+/*
+holdOn makes the emulator keep the printer busy on cmd until the returned channel is closed
+*/
+func (m *marlinEmulator) holdOn(cmd string) chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := make(chan struct{})
+	m.hold[cmd] = c
+	return c
+}
+
+type MarlinDriverTestSuite struct {
+	suite.Suite
+	master   *os.File
+	emulator *marlinEmulator
+	p        types.Printer
+	driver   printer.PrinterDriver
+}
+
+func (suite *MarlinDriverTestSuite) SetupTest() {
+	master, slave, err := openPTY()
+	if err != nil {
+		suite.T().Skipf("no pseudo-terminals available: %v", err)
+	}
+	suite.master = master
+	suite.emulator = newMarlinEmulator(master)
+	go suite.emulator.run()
+
+	suite.p = types.Printer{APIType: types.API_TYPE_MARLIN, DevicePath: slave, BaudRate: 250000}
+	suite.driver, err = printer.NewDriver(suite.p)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *MarlinDriverTestSuite) TearDownTest() {
+	sessionsLock.Lock()
+	if s, ok := sessions[suite.p.DevicePath]; ok {
+		s.conn.Close()
+		delete(sessions, suite.p.DevicePath)
+	}
+	sessionsLock.Unlock()
+	suite.master.Close()
+}
+
+func (suite *MarlinDriverTestSuite) connect() {
+	assert.NoError(suite.T(), suite.driver.Connect())
+}
+
+/*
+waitFor polls the driver's status until cond holds
+*/
+func (suite *MarlinDriverTestSuite) waitFor(cond func(printer.Status) bool) printer.Status {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := suite.driver.Status()
+		assert.NoError(suite.T(), err)
+		if cond(status) || time.Now().After(deadline) {
+			return status
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+/*
+waitForCommand waits until the emulator has received cmd
+*/
+func (suite *MarlinDriverTestSuite) waitForCommand(cmd string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, c := range suite.emulator.received() {
+			if c == cmd {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.T().Errorf("%v was never sent", cmd)
+}
+
+func (suite *MarlinDriverTestSuite) TestConnect() {
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_OFFLINE, status.State)
+
+	suite.connect()
+	assert.Equal(suite.T(), []string{"M110 N0", "M115", "M155 S2"}, suite.emulator.received())
+	info, err := suite.driver.Info()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.Info{Firmware: "Marlin", FirmwareVersion: "2.1.2.1", Model: "Ender-3 V2"}, info)
+
+	status = suite.waitFor(func(s printer.Status) bool { return len(s.Temperatures) > 0 })
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+	assert.Equal(suite.T(), printer.Temperature{Actual: 20.5, Target: 0}, status.Temperatures["tool0"])
+	assert.Equal(suite.T(), printer.Temperature{Actual: 21.0, Target: 0}, status.Temperatures["bed"])
+
+	// connecting again keeps the open session
+	suite.connect()
+	assert.Len(suite.T(), suite.emulator.received(), 4, "only the M27 from Status should have been sent since")
+}
+
+func (suite *MarlinDriverTestSuite) TestConnectHandshake() {
+	release := suite.emulator.holdOn("M115")
+	connected := make(chan error)
+	go func() { connected <- suite.driver.Connect() }()
+	suite.waitForCommand("M115")
+
+	// other printers can be looked up while this one is handshaking
+	other, _ := printer.NewDriver(types.Printer{APIType: types.API_TYPE_MARLIN, DevicePath: "/dev/ttyOther"})
+	status, err := other.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_OFFLINE, status.State)
+
+	close(release)
+	assert.NoError(suite.T(), <-connected)
+	status, _ = suite.driver.Status()
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+}
+
+func (suite *MarlinDriverTestSuite) TestTemperaturePolling() {
+	suite.emulator.autoReport = false
+	suite.connect()
+	status := suite.waitFor(func(s printer.Status) bool { return len(s.Temperatures) > 0 })
+	assert.Equal(suite.T(), printer.Temperature{Actual: 210, Target: 215}, status.Temperatures["tool0"])
+	assert.Contains(suite.T(), suite.emulator.received(), "M105")
+}
+
+func (suite *MarlinDriverTestSuite) TestResend() {
+	suite.connect()
+	suite.emulator.corruptNext = true
+	assert.NoError(suite.T(), suite.driver.SendGCode("G28 ; home", "G1 X10"))
+	assert.Equal(suite.T(), 1, suite.emulator.resends)
+	assert.Equal(suite.T(), []string{"G28", "G1 X10"}, suite.emulator.received()[3:])
+}
+
+func (suite *MarlinDriverTestSuite) TestStreamJob() {
+	suite.connect()
+	gcode := "; generated by test\nG28\n\nG1 X10 Y10 ; move\nM104 S0\n"
+	assert.NoError(suite.T(), suite.driver.Upload("cube.gcode", strings.NewReader(gcode), true))
+	assert.ErrorIs(suite.T(), suite.driver.Upload("other.gcode", strings.NewReader("G28\n"), true), ErrBusy)
+
+	status := suite.waitFor(func(s printer.Status) bool { return s.State == printer.STATE_IDLE })
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+	assert.Equal(suite.T(), []string{"G28", "G1 X10 Y10", "M104 S0"}, suite.emulator.received()[3:6])
+}
+
+func (suite *MarlinDriverTestSuite) TestPauseResume() {
+	suite.connect()
+	release := suite.emulator.holdOn("G28")
+	assert.NoError(suite.T(), suite.driver.Upload("cube.gcode", strings.NewReader("G28\nG1 X10\nG1 X20\n"), true))
+	suite.waitForCommand("G28")
+	assert.NoError(suite.T(), suite.driver.Pause())
+	close(release)
+
+	status := suite.waitFor(func(s printer.Status) bool { return s.State == printer.STATE_PAUSED })
+	assert.Equal(suite.T(), printer.STATE_PAUSED, status.State)
+	assert.Equal(suite.T(), "cube.gcode", status.Job.File)
+	assert.InDelta(suite.T(), 4.0/18*100, status.Job.Completion, 0.01, "only G28 has been sent")
+	assert.NotContains(suite.T(), suite.emulator.received(), "G1 X20")
+
+	assert.NoError(suite.T(), suite.driver.Resume())
+	suite.waitFor(func(s printer.Status) bool { return s.State == printer.STATE_IDLE })
+	assert.Contains(suite.T(), suite.emulator.received(), "G1 X20")
+}
+
+func (suite *MarlinDriverTestSuite) TestCancel() {
+	suite.connect()
+	release := suite.emulator.holdOn("G28")
+	assert.NoError(suite.T(), suite.driver.Upload("cube.gcode", strings.NewReader("G28\nG1 X10\n"), true))
+	suite.waitForCommand("G28")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	assert.Equal(suite.T(), []string{"G28", "M104 S0", "M140 S0", "M107"}, suite.emulator.received()[3:])
+}
+
+func (suite *MarlinDriverTestSuite) TestSDCard() {
+	suite.connect()
+	files, err := suite.driver.ListFiles()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []printer.File{
+		{Name: "calibration cube.gcode", Path: "CUBE.GCO", Location: LOCATION_SD, Size: 1234},
+		{Name: "BENCHY.GCO", Path: "BENCHY.GCO", Location: LOCATION_SD, Size: 5678},
+	}, files)
+
+	assert.NoError(suite.T(), suite.driver.Start("", "CUBE.GCO"))
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_PRINTING, status.State)
+	assert.Equal(suite.T(), 25.0, status.Job.Completion)
+
+	assert.NoError(suite.T(), suite.driver.Pause())
+	status, _ = suite.driver.Status()
+	assert.Equal(suite.T(), printer.STATE_PAUSED, status.State)
+
+	assert.NoError(suite.T(), suite.driver.DeleteFile(LOCATION_SD, "BENCHY.GCO"))
+	assert.ErrorIs(suite.T(), suite.driver.DeleteFile("", "MISSING.GCO"), os.ErrNotExist)
+	assert.ErrorIs(suite.T(), suite.driver.DeleteFile("local", "BENCHY.GCO"), printer.ErrUnknownStorage)
+}
+
+func (suite *MarlinDriverTestSuite) TestUploadToSD() {
+	suite.connect()
+	assert.NoError(suite.T(), suite.driver.Upload("my cube (v2).gcode", strings.NewReader("G28\n; comment\nG1 X1\n"), false))
+	assert.Equal(suite.T(), []string{"M28 MYCUBEV2.GCO", "G28", "G1 X1", "M29"}, suite.emulator.received()[3:])
+}
+
+func (suite *MarlinDriverTestSuite) TestWritingToSD() {
+	suite.emulator.autoReport = false
+	suite.connect()
+	release := suite.emulator.holdOn("G28")
+	uploaded := make(chan error)
+	go func() {
+		uploaded <- suite.driver.Upload("cube.gcode", strings.NewReader("G28\nG1 X1\n"), false)
+	}()
+	suite.waitForCommand("G28")
+
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_BUSY, status.State)
+	assert.ErrorIs(suite.T(), suite.driver.SendGCode("G1 X5"), ErrBusy)
+	assert.ErrorIs(suite.T(), suite.driver.Pause(), ErrBusy)
+	time.Sleep(TEMPERATURE_INTERVAL + 100*time.Millisecond)
+	close(release)
+	assert.NoError(suite.T(), <-uploaded)
+
+	received := suite.emulator.received()
+	assert.Equal(suite.T(), []string{"M28 CUBE.GCO", "G28", "G1 X1", "M29"}, received[3:7], "nothing else may end up in the file")
+	status, _ = suite.driver.Status()
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+}
+
+func (suite *MarlinDriverTestSuite) TestWritingToSDFails() {
+	suite.connect()
+	err := suite.driver.Upload("cube.gcode", io.MultiReader(strings.NewReader("G28\n"), iotest.ErrReader(io.ErrUnexpectedEOF)), false)
+	assert.ErrorIs(suite.T(), err, io.ErrUnexpectedEOF)
+	assert.Equal(suite.T(), []string{"M28 CUBE.GCO", "G28", "M29"}, suite.emulator.received()[3:])
+}
+
+func (suite *MarlinDriverTestSuite) TestHalted() {
+	suite.connect()
+	go suite.driver.SendGCode("M112")
+	status := suite.waitFor(func(s printer.Status) bool { return s.State == printer.STATE_ERROR })
+	assert.Equal(suite.T(), printer.STATE_ERROR, status.State)
+	assert.Contains(suite.T(), status.Text, "halted")
+}
+
+func (suite *MarlinDriverTestSuite) TestLateOk() {
+	suite.connect()
+	// the ok to a line we gave up waiting for
+	suite.emulator.write("ok")
+	time.Sleep(50 * time.Millisecond)
+	sessionsLock.Lock()
+	conn := sessions[suite.p.DevicePath].conn
+	sessionsLock.Unlock()
+	lines, err := conn.Command("M105")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"ok T:210.0 /215.0 B:60.0 /60.0 @:127 B@:0"}, lines)
+}
+
+func (suite *MarlinDriverTestSuite) TestStreamError() {
+	suite.connect()
+	j := newJob("cube.gcode", 100)
+	j.err = ErrTimeout
+	close(j.done)
+	sessionsLock.Lock()
+	sessions[suite.p.DevicePath].job = j
+	sessionsLock.Unlock()
+
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_ERROR, status.State)
+	assert.Contains(suite.T(), status.Text, "timed out")
+
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	status, _ = suite.driver.Status()
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State, "cancel clears the failed stream")
+}
+
+func (suite *MarlinDriverTestSuite) TestNotConnected() {
+	err := suite.driver.Pause()
+	assert.ErrorIs(suite.T(), err, ErrNotConnected)
+	var statusErr printer.StatusError
+	assert.ErrorAs(suite.T(), err, &statusErr)
+	assert.Equal(suite.T(), 409, statusErr.HTTPStatus())
+
+	_, err = printer.NewDriver(types.Printer{APIType: types.API_TYPE_MARLIN})
+	assert.ErrorIs(suite.T(), err, ErrNoDevice)
+}
+
+func TestFormatLine(t *testing.T) {
+	assert.Equal(t, "N0 M110 N0*125\n", formatLine(0, "M110 N0"))
+	assert.Equal(t, "G1 X10", StripComment("  G1 X10 ; move "))
+	assert.Equal(t, "", StripComment("; only a comment"))
+}
+
+func TestMarlinDriverTestSuite(t *testing.T) {
+	suite.Run(t, new(MarlinDriverTestSuite))
+}
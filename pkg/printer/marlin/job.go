@@ -0,0 +1,156 @@
+package marlin
+
+import (
+	"bufio"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/printer"
+)
+
+/*
+job streams a G-code file from the host to the printer line by line
+*/
+type job struct {
+	name    string
+	size    int64
+	started time.Time
+	done    chan struct{}
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	pos       int64
+	paused    bool
+	pausedFor time.Duration
+	pausedAt  time.Time
+	cancelled bool
+	err       error
+}
+
+func newJob(name string, size int64) *job {
+	j := &job{
+		name:    name,
+		size:    size,
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	j.cond = sync.NewCond(&j.mu)
+	return j
+}
+
+/*
+stream sends the file and removes it when done. Pausing simply stops sending lines.
+*/
+func (j *job) stream(conn *Conn, f *os.File) {
+	defer close(j.done)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		j.mu.Lock()
+		for j.paused && !j.cancelled {
+			j.cond.Wait()
+		}
+		cancelled := j.cancelled
+		j.pos += int64(len(line)) + 1
+		j.mu.Unlock()
+		if cancelled {
+			return
+		}
+
+		cmd := StripComment(line)
+		if cmd == "" {
+			continue
+		}
+		if _, err := conn.Command(cmd); err != nil {
+			log.Errorf("marlin: streaming %v stopped: %v", j.name, err)
+			j.mu.Lock()
+			j.err = err
+			j.mu.Unlock()
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		j.mu.Lock()
+		j.err = err
+		j.mu.Unlock()
+	}
+}
+
+func (j *job) finished() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *job) pause() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.paused {
+		j.paused = true
+		j.pausedAt = time.Now()
+	}
+}
+
+func (j *job) resume() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.paused {
+		j.paused = false
+		j.pausedFor += time.Since(j.pausedAt)
+	}
+	j.cond.Broadcast()
+}
+
+func (j *job) cancel() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cancelled = true
+	j.cond.Broadcast()
+}
+
+/*
+failed returns why the stream stopped before the end of the file, or nil
+*/
+func (j *job) failed() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+func (j *job) isPaused() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.paused
+}
+
+/*
+progress estimates the time left from how fast the file has been going so far
+*/
+func (j *job) progress() *printer.Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	elapsed := time.Since(j.started) - j.pausedFor
+	if j.paused {
+		elapsed -= time.Since(j.pausedAt)
+	}
+	p := &printer.Job{
+		File:      j.name,
+		PrintTime: elapsed.Seconds(),
+	}
+	if j.size > 0 {
+		p.Completion = float64(j.pos) / float64(j.size) * 100
+	}
+	if j.pos > 0 {
+		p.PrintTimeLeft = elapsed.Seconds() * float64(j.size-j.pos) / float64(j.pos)
+		p.EstimatedPrintTime = p.PrintTime + p.PrintTimeLeft
+	}
+	return p
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package marlin
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+/*
+OpenPort opens a tty in raw mode at the given baud rate
+*/
+func OpenPort(path string, baud int) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := configure(int(f.Fd()), path, baud); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+/*
+makeRaw does what cfmakeraw does, and sets 8N1 without flow control
+*/
+func makeRaw(t *unix.Termios) {
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package marlin
+
+import (
+	"io"
+
+	"ymir/pkg/printer"
+)
+
+/*
+OpenPort is only implemented for linux
+*/
+func OpenPort(path string, baud int) (io.ReadWriteCloser, error) {
+	return nil, printer.ErrNotSupported
+}
@@ -0,0 +1,28 @@
+//go:build linux && !ppc64 && !ppc64le
+
+package marlin
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+/*
+configure sets raw mode and the baud rate with termios2.
+Any baud rate is accepted (Marlin likes 250000) since the rate is set with BOTHER.
+*/
+func configure(fd int, path string, baud int) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	if err != nil {
+		return &os.PathError{Op: "tcgets", Path: path, Err: err}
+	}
+	makeRaw(t)
+	t.Cflag |= unix.BOTHER
+	t.Ispeed = uint32(baud)
+	t.Ospeed = uint32(baud)
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS2, t); err != nil {
+		return &os.PathError{Op: "tcsets", Path: path, Err: err}
+	}
+	return nil
+}
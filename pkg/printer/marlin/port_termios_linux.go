@@ -0,0 +1,49 @@
+//go:build linux && (ppc64 || ppc64le)
+
+package marlin
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+/*
+bauds are the standard rates, the only ones these arches can set without termios2
+*/
+var bauds = map[int]uint32{
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	500000:  unix.B500000,
+	576000:  unix.B576000,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+}
+
+/*
+configure sets raw mode and one of the standard baud rates
+*/
+func configure(fd int, path string, baud int) error {
+	speed, ok := bauds[baud]
+	if !ok {
+		return fmt.Errorf("marlin: baud rate %d is not supported on this platform", baud)
+	}
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return &os.PathError{Op: "tcgets", Path: path, Err: err}
+	}
+	makeRaw(t)
+	t.Cflag |= speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return &os.PathError{Op: "tcsets", Path: path, Err: err}
+	}
+	return nil
+}
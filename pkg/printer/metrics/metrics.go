@@ -0,0 +1,187 @@
+/*
+Package metrics exports printer telemetry from the poller's cache and the job history to Prometheus, so the farm can be
+charted from /v1/metrics without a separate exporter.
+*/
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	jobtypes "ymir/pkg/api/job/types"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+	"ymir/pkg/printer/poller"
+)
+
+const (
+	NAMESPACE = "ymir"
+	SUBSYSTEM = "printer"
+)
+
+var (
+	// labels identifies a printer. The id keeps two printers with the same name apart.
+	labels = []string{"id", "printer", "location"}
+	states = []printer.State{
+		printer.STATE_OFFLINE,
+		printer.STATE_IDLE,
+		printer.STATE_PRINTING,
+		printer.STATE_PAUSED,
+		printer.STATE_BUSY,
+		printer.STATE_ERROR,
+	}
+
+	temperatureDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "temperature_celsius"),
+		"Current temperature of a heater, e.g. tool0 or bed.",
+		append(labels, "heater"), nil,
+	)
+	targetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "target_temperature_celsius"),
+		"Target temperature of a heater, 0 when it is off.",
+		append(labels, "heater"), nil,
+	)
+	stateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "state"),
+		"1 for the state the printer is in, 0 for the others.",
+		append(labels, "state"), nil,
+	)
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "up"),
+		"1 if the printer answered the last poll.",
+		labels, nil,
+	)
+	progressDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "job_progress_percent"),
+		"Completion of the current print job.",
+		labels, nil,
+	)
+	timeLeftDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "job_time_left_seconds"),
+		"Estimated time left on the current print job.",
+		labels, nil,
+	)
+	completedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "prints_completed_total"),
+		"Print jobs sent by Ymir that completed.",
+		labels, nil,
+	)
+	failedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(NAMESPACE, SUBSYSTEM, "prints_failed_total"),
+		"Print jobs sent by Ymir that failed.",
+		labels, nil,
+	)
+)
+
+/*
+PrinterLister is the part of the PrinterStore the collector needs
+*/
+type PrinterLister interface {
+	List() (printers map[string]types.Printer, err error)
+}
+
+/*
+JobLister is the part of the JobStore the collector needs
+*/
+type JobLister interface {
+	List() (map[string]jobtypes.Job, error)
+}
+
+/*
+StatusCache is the part of the poller the collector needs
+*/
+type StatusCache interface {
+	Statuses() map[string]poller.PrinterStatus
+}
+
+/*
+Collector reads the cached printer statuses at scrape time rather than keeping its own gauges up to date,
+so deleted printers stop being reported and a scrape never queries the printers themselves.
+*/
+type Collector struct {
+	printers PrinterLister
+	jobs     JobLister
+	statuses StatusCache
+}
+
+func NewCollector(printers PrinterLister, jobs JobLister, statuses StatusCache) *Collector {
+	return &Collector{
+		printers: printers,
+		jobs:     jobs,
+		statuses: statuses,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- temperatureDesc
+	ch <- targetDesc
+	ch <- stateDesc
+	ch <- upDesc
+	ch <- progressDesc
+	ch <- timeLeftDesc
+	ch <- completedDesc
+	ch <- failedDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	printers, err := c.printers.List()
+	if err != nil {
+		log.Errorf("metrics could not list printers: %v", err)
+		return
+	}
+	completed, failed := c.countJobs()
+	statuses := c.statuses.Statuses()
+
+	for id, p := range printers {
+		values := []string{id, p.PrinterName, p.Location.Name}
+		ch <- prometheus.MustNewConstMetric(completedDesc, prometheus.CounterValue, completed[id], values...)
+		ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.CounterValue, failed[id], values...)
+
+		s, ok := statuses[id]
+		if !ok {
+			// not polled yet
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, boolValue(s.Error == ""), values...)
+		for _, state := range states {
+			ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, boolValue(s.State == state),
+				append(values, string(state))...)
+		}
+		for heater, t := range s.Temperatures {
+			ch <- prometheus.MustNewConstMetric(temperatureDesc, prometheus.GaugeValue, t.Actual, append(values, heater)...)
+			ch <- prometheus.MustNewConstMetric(targetDesc, prometheus.GaugeValue, t.Target, append(values, heater)...)
+		}
+		if s.Job != nil {
+			ch <- prometheus.MustNewConstMetric(progressDesc, prometheus.GaugeValue, s.Job.Completion, values...)
+			ch <- prometheus.MustNewConstMetric(timeLeftDesc, prometheus.GaugeValue, s.Job.PrintTimeLeft, values...)
+		}
+	}
+}
+
+/*
+countJobs counts finished jobs by printer id. Counting the job history keeps the counters across restarts.
+*/
+func (c *Collector) countJobs() (completed map[string]float64, failed map[string]float64) {
+	completed = map[string]float64{}
+	failed = map[string]float64{}
+	jobs, err := c.jobs.List()
+	if err != nil {
+		log.Errorf("metrics could not list jobs: %v", err)
+		return
+	}
+	for _, j := range jobs {
+		switch j.State {
+		case jobtypes.JOB_STATE_COMPLETED:
+			completed[j.PrinterId]++
+		case jobtypes.JOB_STATE_FAILED:
+			failed[j.PrinterId]++
+		}
+	}
+	return
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
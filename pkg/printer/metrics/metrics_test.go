@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+	"ymir/pkg/printer/poller"
+)
+
+type fakePrinters map[string]types.Printer
+
+func (f fakePrinters) List() (map[string]types.Printer, error) {
+	return f, nil
+}
+
+type fakeJobs map[string]jobtypes.Job
+
+func (f fakeJobs) List() (map[string]jobtypes.Job, error) {
+	return f, nil
+}
+
+type fakeStatuses map[string]poller.PrinterStatus
+
+func (f fakeStatuses) Statuses() map[string]poller.PrinterStatus {
+	return f
+}
+
+type MetricsTestSuite struct {
+	suite.Suite
+	collector *Collector
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.collector = NewCollector(
+		fakePrinters{
+			"p1": {Id: "p1", PrinterName: "mk3s", Location: types.Location{Name: "garage"}},
+			"p2": {Id: "p2", PrinterName: "voron", Location: types.Location{Name: "office"}},
+			"p3": {Id: "p3", PrinterName: "new", Location: types.Location{Name: "office"}},
+		},
+		fakeJobs{
+			"j1": {Id: "j1", PrinterId: "p1", State: jobtypes.JOB_STATE_COMPLETED},
+			"j2": {Id: "j2", PrinterId: "p1", State: jobtypes.JOB_STATE_COMPLETED},
+			"j3": {Id: "j3", PrinterId: "p1", State: jobtypes.JOB_STATE_FAILED},
+			"j4": {Id: "j4", PrinterId: "p2", State: jobtypes.JOB_STATE_CANCELLED},
+			"j5": {Id: "j5", PrinterId: "p2", State: jobtypes.JOB_STATE_PRINTING},
+		},
+		fakeStatuses{
+			"p1": {Id: "p1", Status: printer.Status{
+				State: printer.STATE_PRINTING,
+				Temperatures: map[string]printer.Temperature{
+					"tool0": {Actual: 214.8, Target: 215},
+					"bed":   {Actual: 60.1, Target: 60},
+				},
+				Job: &printer.Job{File: "benchy.gcode", Completion: 42.5, PrintTimeLeft: 1800},
+			}},
+			"p2": {Id: "p2", Status: printer.Status{State: printer.STATE_OFFLINE}, Error: "connection refused"},
+		},
+	)
+}
+
+func (suite *MetricsTestSuite) TestCollect() {
+	expected := `
+# HELP ymir_printer_job_progress_percent Completion of the current print job.
+# TYPE ymir_printer_job_progress_percent gauge
+ymir_printer_job_progress_percent{id="p1",location="garage",printer="mk3s"} 42.5
+# HELP ymir_printer_prints_completed_total Print jobs sent by Ymir that completed.
+# TYPE ymir_printer_prints_completed_total counter
+ymir_printer_prints_completed_total{id="p1",location="garage",printer="mk3s"} 2
+ymir_printer_prints_completed_total{id="p2",location="office",printer="voron"} 0
+ymir_printer_prints_completed_total{id="p3",location="office",printer="new"} 0
+# HELP ymir_printer_prints_failed_total Print jobs sent by Ymir that failed.
+# TYPE ymir_printer_prints_failed_total counter
+ymir_printer_prints_failed_total{id="p1",location="garage",printer="mk3s"} 1
+ymir_printer_prints_failed_total{id="p2",location="office",printer="voron"} 0
+ymir_printer_prints_failed_total{id="p3",location="office",printer="new"} 0
+# HELP ymir_printer_target_temperature_celsius Target temperature of a heater, 0 when it is off.
+# TYPE ymir_printer_target_temperature_celsius gauge
+ymir_printer_target_temperature_celsius{heater="bed",id="p1",location="garage",printer="mk3s"} 60
+ymir_printer_target_temperature_celsius{heater="tool0",id="p1",location="garage",printer="mk3s"} 215
+# HELP ymir_printer_temperature_celsius Current temperature of a heater, e.g. tool0 or bed.
+# TYPE ymir_printer_temperature_celsius gauge
+ymir_printer_temperature_celsius{heater="bed",id="p1",location="garage",printer="mk3s"} 60.1
+ymir_printer_temperature_celsius{heater="tool0",id="p1",location="garage",printer="mk3s"} 214.8
+# HELP ymir_printer_up 1 if the printer answered the last poll.
+# TYPE ymir_printer_up gauge
+ymir_printer_up{id="p1",location="garage",printer="mk3s"} 1
+ymir_printer_up{id="p2",location="office",printer="voron"} 0
+`
+	err := testutil.CollectAndCompare(suite.collector, strings.NewReader(expected),
+		"ymir_printer_job_progress_percent",
+		"ymir_printer_prints_completed_total",
+		"ymir_printer_prints_failed_total",
+		"ymir_printer_target_temperature_celsius",
+		"ymir_printer_temperature_celsius",
+		"ymir_printer_up",
+	)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *MetricsTestSuite) TestState() {
+	expected := `
+# HELP ymir_printer_state 1 for the state the printer is in, 0 for the others.
+# TYPE ymir_printer_state gauge
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="busy"} 0
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="error"} 0
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="idle"} 0
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="offline"} 0
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="paused"} 0
+ymir_printer_state{id="p1",location="garage",printer="mk3s",state="printing"} 1
+ymir_printer_state{id="p2",location="office",printer="voron",state="busy"} 0
+ymir_printer_state{id="p2",location="office",printer="voron",state="error"} 0
+ymir_printer_state{id="p2",location="office",printer="voron",state="idle"} 0
+ymir_printer_state{id="p2",location="office",printer="voron",state="offline"} 1
+ymir_printer_state{id="p2",location="office",printer="voron",state="paused"} 0
+ymir_printer_state{id="p2",location="office",printer="voron",state="printing"} 0
+`
+	assert.NoError(suite.T(), testutil.CollectAndCompare(suite.collector, strings.NewReader(expected), "ymir_printer_state"))
+}
+
+func (suite *MetricsTestSuite) TestLint() {
+	problems, err := testutil.CollectAndLint(suite.collector)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), problems)
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,237 @@
+package moonraker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_API_KEY_HEADER  = "X-Api-Key"
+	_DEFAULT_TIMEOUT = 10 * time.Second
+
+	ROOT_GCODES = "gcodes"
+)
+
+/*
+Client is a small client for the Moonraker API in front of Klipper.
+https://moonraker.readthedocs.io/en/latest/web_api/
+*/
+type Client struct {
+	Endpoint string
+	APIKey   string
+	Timeout  time.Duration
+	c        *http.Client
+}
+
+/*
+APIError is returned when Moonraker answers with an error status
+*/
+type APIError struct {
+	StatusCode int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("moonraker: %v %v", e.StatusCode, e.Message)
+}
+
+/*
+HTTPStatus satisfies printer.StatusError
+*/
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func NewClient(endpoint string, apiKey string) *Client {
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		APIKey:   apiKey,
+		Timeout:  _DEFAULT_TIMEOUT,
+		c: &http.Client{
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+/*
+call sends a request with the given query params and decodes the "result" field of the answer into out (if not nil)
+*/
+func (c *Client) call(method string, uri string, params url.Values, out interface{}) error {
+	return c.callWithTimeout(c.Timeout, method, uri, params, out)
+}
+
+/*
+callWithTimeout is call cancelled after timeout, or never if timeout is 0
+*/
+func (c *Client) callWithTimeout(timeout time.Duration, method string, uri string, params url.Values, out interface{}) error {
+	if len(params) > 0 {
+		uri = uri + "?" + params.Encode()
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+uri, nil)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return c.do(req, nil)
+	}
+	return c.do(req, &result{Result: out})
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	if c.APIKey != "" {
+		req.Header.Set(_API_KEY_HEADER, c.APIKey)
+	}
+	req.Header.Set("Accept", "application/json")
+	log.Debugf("moonraker request: %v %v", req.Method, req.URL)
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := errorResponse{}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &e) != nil || e.Error.Message == "" {
+			e.Error.Message = strings.TrimSpace(string(body))
+		}
+		e.Error.StatusCode = resp.StatusCode
+		return &e.Error
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+/*
+ServerInfo returns the Moonraker server and klippy connection state
+*/
+func (c *Client) ServerInfo() (info ServerInfo, err error) {
+	err = c.call(http.MethodGet, "/server/info", nil, &info)
+	return
+}
+
+/*
+PrinterInfo returns the Klipper state and version
+*/
+func (c *Client) PrinterInfo() (info PrinterInfo, err error) {
+	err = c.call(http.MethodGet, "/printer/info", nil, &info)
+	return
+}
+
+func (c *Client) FirmwareRestart() error {
+	return c.call(http.MethodPost, "/printer/firmware_restart", nil, nil)
+}
+
+/*
+QueryObjects returns the status of the Klipper printer objects we care about
+*/
+func (c *Client) QueryObjects() (status ObjectStatus, err error) {
+	params := url.Values{}
+	for _, o := range []string{"print_stats", "heater_bed", "extruder", "virtual_sdcard", "webhooks"} {
+		params.Set(o, "")
+	}
+	resp := QueryResult{}
+	err = c.call(http.MethodGet, "/printer/objects/query", params, &resp)
+	return resp.Status, err
+}
+
+/*
+FileMetadata returns the slicer metadata Moonraker parsed from a file in the gcodes root
+*/
+func (c *Client) FileMetadata(filename string) (meta FileMetadata, err error) {
+	err = c.call(http.MethodGet, "/server/files/metadata", url.Values{"filename": {filename}}, &meta)
+	return
+}
+
+func (c *Client) ListFiles(root string) (files []FileInfo, err error) {
+	err = c.call(http.MethodGet, "/server/files/list", url.Values{"root": {root}}, &files)
+	return
+}
+
+func (c *Client) DeleteFile(root string, path string) error {
+	segments := strings.Split(strings.TrimLeft(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return c.call(http.MethodDelete, fmt.Sprintf("/server/files/%s/%s", url.PathEscape(root), strings.Join(segments, "/")), nil, nil)
+}
+
+/*
+UploadFile uploads a file to the gcodes root, optionally into a sub folder, and optionally prints it.
+Uploads are not bound by the client timeout.
+*/
+func (c *Client) UploadFile(path string, filename string, r io.Reader, print bool) (item UploadResult, err error) {
+	b := bytes.NewBuffer(nil)
+	w := multipart.NewWriter(b)
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(fw, r); err != nil {
+		return
+	}
+	_ = w.WriteField("root", ROOT_GCODES)
+	if path != "" {
+		_ = w.WriteField("path", path)
+	}
+	_ = w.WriteField("print", fmt.Sprintf("%t", print))
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.Endpoint+"/server/files/upload", b)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	err = c.do(req, &item)
+	return
+}
+
+func (c *Client) StartPrint(filename string) error {
+	return c.call(http.MethodPost, "/printer/print/start", url.Values{"filename": {filename}}, nil)
+}
+
+func (c *Client) PausePrint() error {
+	return c.call(http.MethodPost, "/printer/print/pause", nil, nil)
+}
+
+func (c *Client) ResumePrint() error {
+	return c.call(http.MethodPost, "/printer/print/resume", nil, nil)
+}
+
+func (c *Client) CancelPrint() error {
+	return c.call(http.MethodPost, "/printer/print/cancel", nil, nil)
+}
+
+/*
+RunGCode runs a G-code script. Multiple lines are separated by newlines.
+Moonraker only answers once the script has run, homing or heating takes minutes, so it is not bound by the client timeout.
+*/
+func (c *Client) RunGCode(script string) error {
+	return c.callWithTimeout(0, http.MethodPost, "/printer/gcode/script", url.Values{"script": {script}}, nil)
+}
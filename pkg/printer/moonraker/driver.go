@@ -0,0 +1,187 @@
+package moonraker
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	// UPLOAD_FOLDER is the folder inside the gcodes root that Ymir uploads to
+	UPLOAD_FOLDER = "ymir"
+)
+
+func init() {
+	printer.Register(types.API_TYPE_MOONRAKER, NewDriver)
+}
+
+/*
+Driver adapts the Moonraker Client to the printer.PrinterDriver interface
+*/
+type Driver struct {
+	client *Client
+}
+
+func NewDriver(p types.Printer) (printer.PrinterDriver, error) {
+	return &Driver{
+		client: NewClient(p.URL, p.APIKey),
+	}, nil
+}
+
+/*
+Connect makes sure Klipper is ready. A Klipper in shutdown or error is firmware restarted.
+*/
+func (d *Driver) Connect() error {
+	info, err := d.client.PrinterInfo()
+	if err != nil {
+		return err
+	}
+	if info.State == "shutdown" || info.State == "error" {
+		return d.client.FirmwareRestart()
+	}
+	return nil
+}
+
+/*
+Info reports the Moonraker and Klipper versions. Klipper doesn't know what printer it drives.
+*/
+func (d *Driver) Info() (info printer.Info, err error) {
+	server, err := d.client.ServerInfo()
+	if err != nil {
+		return
+	}
+	klipper, err := d.client.PrinterInfo()
+	if err != nil {
+		return
+	}
+	return printer.Info{
+		Server:          "Moonraker",
+		ServerVersion:   server.MoonrakerVersion,
+		Firmware:        "Klipper",
+		FirmwareVersion: klipper.SoftwareVer,
+	}, nil
+}
+
+func (d *Driver) Status() (status printer.Status, err error) {
+	objects, err := d.client.QueryObjects()
+	if err != nil {
+		return
+	}
+
+	status = printer.Status{
+		State: stateFromObjects(objects),
+		Text:  objects.PrintStats.State,
+		Temperatures: map[string]printer.Temperature{
+			"tool0": {Actual: objects.Extruder.Temperature, Target: objects.Extruder.Target},
+			"bed":   {Actual: objects.HeaterBed.Temperature, Target: objects.HeaterBed.Target},
+		},
+	}
+	if objects.Webhooks.State != "ready" {
+		status.Text = objects.Webhooks.StateMessage
+	}
+
+	if status.State == printer.STATE_PRINTING || status.State == printer.STATE_PAUSED {
+		stats := objects.PrintStats
+		job := &printer.Job{
+			File:       stats.Filename,
+			Completion: objects.VirtualSDCard.Progress * 100,
+			PrintTime:  stats.PrintDuration,
+		}
+		if meta, err := d.client.FileMetadata(stats.Filename); err == nil {
+			job.EstimatedPrintTime = meta.EstimatedTime
+		}
+		if p := objects.VirtualSDCard.Progress; p > 0 {
+			job.PrintTimeLeft = stats.PrintDuration/p - stats.PrintDuration
+		}
+		status.Job = job
+	}
+	return status, nil
+}
+
+func stateFromObjects(objects ObjectStatus) printer.State {
+	switch objects.Webhooks.State {
+	case "ready":
+	case "startup":
+		return printer.STATE_BUSY
+	default:
+		return printer.STATE_ERROR
+	}
+
+	switch objects.PrintStats.State {
+	case "printing":
+		return printer.STATE_PRINTING
+	case "paused":
+		return printer.STATE_PAUSED
+	case "error":
+		return printer.STATE_ERROR
+	default: // standby, complete, cancelled
+		return printer.STATE_IDLE
+	}
+}
+
+func (d *Driver) Upload(filename string, r io.Reader, print bool) error {
+	_, err := d.client.UploadFile(UPLOAD_FOLDER, filename, r, print)
+	return err
+}
+
+func (d *Driver) Start(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.StartPrint(path)
+}
+
+/*
+storage checks the location is the gcodes root, the only one Klipper prints from
+*/
+func storage(location string) error {
+	if location != "" && location != ROOT_GCODES {
+		return fmt.Errorf("%w: %v", printer.ErrUnknownStorage, location)
+	}
+	return nil
+}
+
+func (d *Driver) Pause() error {
+	return d.client.PausePrint()
+}
+
+func (d *Driver) Resume() error {
+	return d.client.ResumePrint()
+}
+
+func (d *Driver) Cancel() error {
+	return d.client.CancelPrint()
+}
+
+func (d *Driver) ListFiles() ([]printer.File, error) {
+	files, err := d.client.ListFiles(ROOT_GCODES)
+	if err != nil {
+		return nil, err
+	}
+	list := []printer.File{}
+	for _, f := range files {
+		list = append(list, printer.File{
+			Name:     f.Path[strings.LastIndex(f.Path, "/")+1:],
+			Path:     f.Path,
+			Location: ROOT_GCODES,
+			Size:     f.Size,
+			Date:     time.Unix(int64(f.Modified), 0),
+		})
+	}
+	return list, nil
+}
+
+func (d *Driver) DeleteFile(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.DeleteFile(ROOT_GCODES, path)
+}
+
+func (d *Driver) SendGCode(commands ...string) error {
+	return d.client.RunGCode(strings.Join(commands, "\n"))
+}
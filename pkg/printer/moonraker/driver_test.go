@@ -0,0 +1,204 @@
+package moonraker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+/*
+moonrakerStandIn emulates the bits of Moonraker the driver talks to
+*/
+type moonrakerStandIn struct {
+	printState   string
+	klippyState  string
+	uploaded     map[string]string
+	printed      string
+	scripts      []string
+	restarted    bool
+	lastPrintCmd string
+	deleted      string
+	scriptDelay  time.Duration
+}
+
+func (m *moonrakerStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ok := func(v interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": v})
+	}
+	if r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/server/files/") {
+		m.deleted = r.URL.EscapedPath()
+		ok("ok")
+		return
+	}
+	switch r.URL.Path {
+	case "/printer/info":
+		ok(PrinterInfo{State: m.klippyState, SoftwareVer: "v0.12.0-85"})
+	case "/server/info":
+		ok(ServerInfo{KlippyConnected: true, MoonrakerVersion: "v0.8.0-138"})
+	case "/printer/firmware_restart":
+		m.restarted = true
+		ok("ok")
+	case "/printer/objects/query":
+		ok(QueryResult{Status: ObjectStatus{
+			PrintStats:    PrintStats{Filename: "ymir/test.gcode", PrintDuration: 600, State: m.printState},
+			HeaterBed:     Heater{Temperature: 60, Target: 60},
+			Extruder:      Heater{Temperature: 210.5, Target: 215},
+			VirtualSDCard: VirtualSDCard{Progress: 0.25},
+			Webhooks:      Webhooks{State: m.klippyState},
+		}})
+	case "/server/files/metadata":
+		ok(FileMetadata{Filename: r.URL.Query().Get("filename"), EstimatedTime: 2400})
+	case "/server/files/list":
+		ok([]FileInfo{{Path: "ymir/test.gcode", Size: 1024, Modified: 1700000000}})
+	case "/server/files/upload":
+		file, header, err := r.FormFile("file")
+		if err != nil || r.FormValue("root") != ROOT_GCODES {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": 400, "message": "bad upload"}})
+			return
+		}
+		b, _ := io.ReadAll(file)
+		m.uploaded[r.FormValue("path")+"/"+header.Filename] = string(b)
+		if r.FormValue("print") == "true" {
+			m.printed = r.FormValue("path") + "/" + header.Filename
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(UploadResult{PrintStarted: r.FormValue("print") == "true"})
+	case "/printer/print/start":
+		m.printed = r.URL.Query().Get("filename")
+		ok("ok")
+	case "/printer/print/pause", "/printer/print/resume", "/printer/print/cancel":
+		m.lastPrintCmd = strings.TrimPrefix(r.URL.Path, "/printer/print/")
+		ok("ok")
+	case "/printer/gcode/script":
+		time.Sleep(m.scriptDelay)
+		m.scripts = append(m.scripts, r.URL.Query().Get("script"))
+		ok("ok")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": 404, "message": "Not Found"}})
+	}
+}
+
+type MoonrakerDriverTestSuite struct {
+	suite.Suite
+	standIn *moonrakerStandIn
+	server  *httptest.Server
+	driver  printer.PrinterDriver
+}
+
+func (suite *MoonrakerDriverTestSuite) SetupTest() {
+	suite.standIn = &moonrakerStandIn{
+		printState:  "standby",
+		klippyState: "ready",
+		uploaded:    map[string]string{},
+	}
+	suite.server = httptest.NewServer(suite.standIn)
+	d, err := printer.NewDriver(types.Printer{URL: suite.server.URL, APIType: types.API_TYPE_MOONRAKER})
+	assert.NoError(suite.T(), err)
+	suite.driver = d
+}
+
+func (suite *MoonrakerDriverTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *MoonrakerDriverTestSuite) TestStatus_Idle() {
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+	assert.Equal(suite.T(), 210.5, status.Temperatures["tool0"].Actual)
+	assert.Equal(suite.T(), 60.0, status.Temperatures["bed"].Target)
+	assert.Nil(suite.T(), status.Job)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestStatus_Printing() {
+	suite.standIn.printState = "printing"
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_PRINTING, status.State)
+	assert.Equal(suite.T(), "ymir/test.gcode", status.Job.File)
+	assert.Equal(suite.T(), 25.0, status.Job.Completion)
+	assert.Equal(suite.T(), 1800.0, status.Job.PrintTimeLeft)
+	assert.Equal(suite.T(), 2400.0, status.Job.EstimatedPrintTime)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestStatus_KlippyShutdown() {
+	suite.standIn.klippyState = "shutdown"
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_ERROR, status.State)
+
+	assert.NoError(suite.T(), suite.driver.Connect())
+	assert.True(suite.T(), suite.standIn.restarted, "connect should firmware restart a shutdown klipper")
+}
+
+func (suite *MoonrakerDriverTestSuite) TestInfo() {
+	info, err := suite.driver.Info()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.Info{Server: "Moonraker", ServerVersion: "v0.8.0-138", Firmware: "Klipper", FirmwareVersion: "v0.12.0-85"}, info)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestUploadAndPrint() {
+	err := suite.driver.Upload("test.gcode", strings.NewReader("G28"), true)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "G28", suite.standIn.uploaded[UPLOAD_FOLDER+"/test.gcode"])
+	assert.Equal(suite.T(), UPLOAD_FOLDER+"/test.gcode", suite.standIn.printed)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestJobControl() {
+	assert.NoError(suite.T(), suite.driver.Start("", "ymir/other.gcode"))
+	assert.Equal(suite.T(), "ymir/other.gcode", suite.standIn.printed)
+	assert.NoError(suite.T(), suite.driver.Pause())
+	assert.Equal(suite.T(), "pause", suite.standIn.lastPrintCmd)
+	assert.NoError(suite.T(), suite.driver.Resume())
+	assert.Equal(suite.T(), "resume", suite.standIn.lastPrintCmd)
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	assert.Equal(suite.T(), "cancel", suite.standIn.lastPrintCmd)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestListFiles() {
+	files, err := suite.driver.ListFiles()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), files, 1)
+	assert.Equal(suite.T(), "test.gcode", files[0].Name)
+	assert.Equal(suite.T(), ROOT_GCODES, files[0].Location)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestSendGCode() {
+	assert.NoError(suite.T(), suite.driver.SendGCode("M104 S0", "M140 S0"))
+	assert.Equal(suite.T(), []string{"M104 S0\nM140 S0"}, suite.standIn.scripts)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestSendGCode_Slow() {
+	suite.standIn.scriptDelay = 100 * time.Millisecond
+	suite.driver.(*Driver).client.Timeout = 10 * time.Millisecond
+	assert.NoError(suite.T(), suite.driver.SendGCode("G28"), "scripts run as long as they take")
+	assert.Equal(suite.T(), []string{"G28"}, suite.standIn.scripts)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestDeleteFile() {
+	assert.NoError(suite.T(), suite.driver.DeleteFile("", "ymir/my cube #2.gcode"))
+	assert.Equal(suite.T(), "/server/files/gcodes/ymir/my%20cube%20%232.gcode", suite.standIn.deleted)
+}
+
+func (suite *MoonrakerDriverTestSuite) TestAPIError() {
+	err := NewClient(suite.server.URL, "").call(http.MethodGet, "/nope", nil, nil)
+	var statusErr printer.StatusError
+	assert.ErrorAs(suite.T(), err, &statusErr)
+	assert.Equal(suite.T(), http.StatusNotFound, statusErr.HTTPStatus())
+	assert.Contains(suite.T(), err.Error(), "Not Found")
+}
+
+func TestMoonrakerDriverTestSuite(t *testing.T) {
+	suite.Run(t, new(MoonrakerDriverTestSuite))
+}
@@ -0,0 +1,90 @@
+package moonraker
+
+/*
+Data model for the parts of the Moonraker API that Ymir uses.
+Every successful answer is wrapped in {"result": ...} and every error in {"error": ...}
+*/
+
+type result struct {
+	Result interface{} `json:"result"`
+}
+
+type errorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type ServerInfo struct {
+	KlippyConnected  bool   `json:"klippy_connected"`
+	KlippyState      string `json:"klippy_state"`
+	MoonrakerVersion string `json:"moonraker_version"`
+	APIVersionString string `json:"api_version_string"`
+}
+
+type PrinterInfo struct {
+	State        string `json:"state"`
+	StateMessage string `json:"state_message"`
+	Hostname     string `json:"hostname"`
+	SoftwareVer  string `json:"software_version"`
+}
+
+// QueryResult is returned by GET /printer/objects/query
+type QueryResult struct {
+	EventTime float64      `json:"eventtime"`
+	Status    ObjectStatus `json:"status"`
+}
+
+type ObjectStatus struct {
+	PrintStats    PrintStats    `json:"print_stats"`
+	HeaterBed     Heater        `json:"heater_bed"`
+	Extruder      Heater        `json:"extruder"`
+	VirtualSDCard VirtualSDCard `json:"virtual_sdcard"`
+	Webhooks      Webhooks      `json:"webhooks"`
+}
+
+type PrintStats struct {
+	Filename      string  `json:"filename"`
+	TotalDuration float64 `json:"total_duration"`
+	PrintDuration float64 `json:"print_duration"`
+	FilamentUsed  float64 `json:"filament_used"`
+	State         string  `json:"state"`
+	Message       string  `json:"message"`
+}
+
+type Heater struct {
+	Temperature float64 `json:"temperature"`
+	Target      float64 `json:"target"`
+	Power       float64 `json:"power"`
+}
+
+type VirtualSDCard struct {
+	Progress float64 `json:"progress"`
+	IsActive bool    `json:"is_active"`
+}
+
+type Webhooks struct {
+	State        string `json:"state"`
+	StateMessage string `json:"state_message"`
+}
+
+type FileInfo struct {
+	Path     string  `json:"path"`
+	Modified float64 `json:"modified"`
+	Size     int64   `json:"size"`
+}
+
+type FileMetadata struct {
+	Filename      string  `json:"filename"`
+	Slicer        string  `json:"slicer"`
+	EstimatedTime float64 `json:"estimated_time"`
+	FilamentTotal float64 `json:"filament_total"`
+}
+
+// UploadResult is returned by POST /server/files/upload
+type UploadResult struct {
+	Item struct {
+		Path string `json:"path"`
+		Root string `json:"root"`
+	} `json:"item"`
+	PrintStarted bool   `json:"print_started"`
+	Action       string `json:"action"`
+}
@@ -0,0 +1,133 @@
+package octoprint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_API_KEY_HEADER  = "X-Api-Key"
+	_DEFAULT_TIMEOUT = 10 * time.Second
+)
+
+/*
+Client is a small client for the OctoPrint REST API.
+https://docs.octoprint.org/en/master/api/index.html
+*/
+type Client struct {
+	Endpoint string
+	APIKey   string
+	Timeout  time.Duration
+	c        *http.Client
+}
+
+/*
+APIError is returned when OctoPrint answers with a status code we did not expect.
+*/
+type APIError struct {
+	StatusCode int    `json:"statusCode"`
+	Status     string `json:"status"`
+	Body       string `json:"body,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("octoprint: %v: %v", e.Status, strings.TrimSpace(e.Body))
+	}
+	return fmt.Sprintf("octoprint: %v", e.Status)
+}
+
+/*
+HTTPStatus satisfies printer.StatusError so handlers can pass OctoPrint's status code through
+*/
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func NewClient(endpoint string, apiKey string) *Client {
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		APIKey:   apiKey,
+		Timeout:  _DEFAULT_TIMEOUT,
+		c: &http.Client{
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+/*
+doJSONRequest sends a JSON body (if any) and decodes a JSON response into out (if not nil)
+*/
+func (c *Client) doJSONRequest(method string, uri string, body interface{}, out interface{}, expected ...int) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+uri, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.do(req, out, expected...)
+}
+
+/*
+do adds the auth headers, sends the request and decodes the response into out (if not nil)
+*/
+func (c *Client) do(req *http.Request, out interface{}, expected ...int) error {
+	req.Header.Set(_API_KEY_HEADER, c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	log.Debugf("octoprint request: %v %v", req.Method, req.URL)
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !isExpected(resp.StatusCode, expected) {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+		}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func isExpected(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code == http.StatusOK
+	}
+	for _, e := range expected {
+		if code == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package octoprint
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	TEST_API_KEY = "ABC123"
+)
+
+type OctoPrintClientTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	client   *Client
+	commands []string
+	uploaded string
+}
+
+func (suite *OctoPrintClientTestSuite) SetupSuite() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/job", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			cmd := JobCommand{}
+			_ = json.NewDecoder(r.Body).Decode(&cmd)
+			if cmd.Command != JOB_CANCEL {
+				http.Error(w, "bad command", http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"job":{"file":{"name":"test.gcode","origin":"local"},"estimatedPrintTime":120},"progress":{"completion":42.5,"printTime":60,"printTimeLeft":60},"state":"Printing"}`))
+	})
+	mux.HandleFunc("/api/printer", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"temperature":{"tool0":{"actual":214.8,"target":215},"bed":{"actual":60.1,"target":60}},"sd":{"ready":true},"state":{"text":"Operational","flags":{"operational":true,"ready":true}}}`))
+	})
+	mux.HandleFunc("/api/printer/command", func(w http.ResponseWriter, r *http.Request) {
+		cmd := CommandRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&cmd)
+		suite.commands = cmd.Commands
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/files/local", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"files":[{"name":"ymir","path":"ymir","type":"folder","children":[{"name":"test.gcode","path":"ymir/test.gcode","type":"machinecode"}]}]}`))
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(file)
+		suite.uploaded = r.FormValue("path") + "/" + header.Filename + ":" + string(b)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"files":{"local":{"name":"test.gcode","origin":"local"}},"done":true}`))
+	})
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(_API_KEY_HEADER) != TEST_API_KEY {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	suite.client = NewClient(suite.server.URL+"/", TEST_API_KEY)
+}
+
+func (suite *OctoPrintClientTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *OctoPrintClientTestSuite) TestNewClient() {
+	assert.Equal(suite.T(), suite.server.URL, suite.client.Endpoint, "trailing slash should be trimmed")
+	assert.Equal(suite.T(), _DEFAULT_TIMEOUT, suite.client.Timeout)
+}
+
+func (suite *OctoPrintClientTestSuite) TestGetJob() {
+	job, err := suite.client.GetJob()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Printing", job.State)
+	assert.Equal(suite.T(), "test.gcode", job.Job.File.Name)
+	assert.Equal(suite.T(), 42.5, job.Progress.Completion)
+}
+
+func (suite *OctoPrintClientTestSuite) TestCancelJob() {
+	assert.NoError(suite.T(), suite.client.CancelJob())
+	assert.Error(suite.T(), suite.client.StartJob(), "stand-in only accepts cancel")
+}
+
+func (suite *OctoPrintClientTestSuite) TestGetPrinterState() {
+	state, err := suite.client.GetPrinterState()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Operational", state.State.Text)
+	assert.True(suite.T(), state.State.Flags.Ready)
+	assert.Equal(suite.T(), 215.0, state.Temperature["tool0"].Target)
+}
+
+func (suite *OctoPrintClientTestSuite) TestIssueCommands() {
+	err := suite.client.IssueCommands("M104 S0", "M140 S0")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"M104 S0", "M140 S0"}, suite.commands)
+}
+
+func (suite *OctoPrintClientTestSuite) TestListFiles() {
+	files, err := suite.client.ListFiles(LOCATION_LOCAL, true)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), files.Files, 1)
+	assert.Len(suite.T(), files.Files[0].Children, 1)
+}
+
+func (suite *OctoPrintClientTestSuite) TestUploadFile() {
+	ur := &UploadFileRequest{Path: "ymir"}
+	err := ur.AddFile("test.gcode", strings.NewReader("G28"))
+	assert.NoError(suite.T(), err)
+	resp, err := suite.client.UploadFile(ur)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), resp.Done)
+	assert.Equal(suite.T(), "ymir/test.gcode:G28", suite.uploaded)
+}
+
+func (suite *OctoPrintClientTestSuite) TestBadAPIKey() {
+	c := NewClient(suite.server.URL, "wrong")
+	_, err := c.GetPrinterState()
+	var apiErr *APIError
+	assert.True(suite.T(), errors.As(err, &apiErr), "should be an APIError")
+	assert.Equal(suite.T(), http.StatusForbidden, apiErr.StatusCode)
+}
+
+func TestFileURI(t *testing.T) {
+	assert.Equal(t, "/api/files/local/ymir/my%20file.gcode", fileURI(LOCATION_LOCAL, "/ymir/my file.gcode"))
+}
+
+func TestOctoPrintClientTestSuite(t *testing.T) {
+	suite.Run(t, new(OctoPrintClientTestSuite))
+}
@@ -0,0 +1,32 @@
+package octoprint
+
+import (
+	"net/http"
+)
+
+const (
+	_CONNECTION_URI = "/api/connection"
+
+	CONNECTION_CONNECT    = "connect"
+	CONNECTION_DISCONNECT = "disconnect"
+)
+
+/*
+GetConnection retrieves the current connection settings and the available options
+*/
+func (c *Client) GetConnection() (conn ConnectionResponse, err error) {
+	err = c.doJSONRequest(http.MethodGet, _CONNECTION_URI, nil, &conn)
+	return
+}
+
+/*
+Connect asks OctoPrint to connect to the printer. Empty values use OctoPrint's preferences.
+*/
+func (c *Client) Connect(req ConnectionRequest) error {
+	req.Command = CONNECTION_CONNECT
+	return c.doJSONRequest(http.MethodPost, _CONNECTION_URI, req, nil, http.StatusNoContent)
+}
+
+func (c *Client) Disconnect() error {
+	return c.doJSONRequest(http.MethodPost, _CONNECTION_URI, ConnectionRequest{Command: CONNECTION_DISCONNECT}, nil, http.StatusNoContent)
+}
@@ -0,0 +1,226 @@
+package octoprint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	// UPLOAD_FOLDER is the folder on the OctoPrint server that Ymir uploads to
+	UPLOAD_FOLDER = "ymir"
+)
+
+func init() {
+	printer.Register(types.API_TYPE_OCTOPRINT, NewDriver)
+}
+
+/*
+Driver adapts the OctoPrint Client to the printer.PrinterDriver interface
+*/
+type Driver struct {
+	client *Client
+}
+
+func NewDriver(p types.Printer) (printer.PrinterDriver, error) {
+	return &Driver{
+		client: NewClient(p.URL, p.APIKey),
+	}, nil
+}
+
+/*
+Connect asks OctoPrint to connect to the printer using its saved preferences, unless it already is
+*/
+func (d *Driver) Connect() error {
+	conn, err := d.client.GetConnection()
+	if err != nil {
+		return err
+	}
+	if conn.Current.State != "Closed" && conn.Current.State != "" {
+		return nil
+	}
+	return d.client.Connect(ConnectionRequest{})
+}
+
+/*
+Info reports the OctoPrint version and the model from the printer profile in use, unless that is OctoPrint's default profile
+*/
+func (d *Driver) Info() (info printer.Info, err error) {
+	version, err := d.client.GetVersion()
+	if err != nil {
+		return
+	}
+	info = printer.Info{
+		Server:        "OctoPrint",
+		ServerVersion: version.Server,
+	}
+	conn, err := d.client.GetConnection()
+	if err != nil {
+		return info, nil
+	}
+	profileId := conn.Current.PrinterProfile
+	if profileId == "" {
+		profileId = conn.Options.PrinterProfilePreference
+	}
+	if profileId == "" || profileId == DEFAULT_PROFILE {
+		return info, nil
+	}
+	if profile, err := d.client.GetPrinterProfile(profileId); err == nil {
+		info.Model = profile.Model
+		if info.Model == "" {
+			info.Model = profile.Name
+		}
+	}
+	return info, nil
+}
+
+func (d *Driver) Status() (status printer.Status, err error) {
+	state, err := d.client.GetPrinterState()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		// OctoPrint is up but not connected to the printer
+		return printer.Status{
+			State:        printer.STATE_OFFLINE,
+			Text:         "Closed",
+			Temperatures: map[string]printer.Temperature{},
+		}, nil
+	} else if err != nil {
+		return
+	}
+
+	status = printer.Status{
+		State:        stateFromFlags(state.State.Flags),
+		Text:         state.State.Text,
+		Temperatures: map[string]printer.Temperature{},
+	}
+	for k, v := range state.Temperature {
+		status.Temperatures[k] = printer.Temperature{Actual: v.Actual, Target: v.Target}
+	}
+
+	if status.State == printer.STATE_PRINTING || status.State == printer.STATE_PAUSED {
+		job, err := d.client.GetJob()
+		if err != nil {
+			return status, err
+		}
+		status.Job = &printer.Job{
+			File:               job.Job.File.Path,
+			Completion:         job.Progress.Completion,
+			PrintTime:          job.Progress.PrintTime,
+			PrintTimeLeft:      job.Progress.PrintTimeLeft,
+			EstimatedPrintTime: job.Job.EstimatedPrintTime,
+		}
+	}
+	return status, nil
+}
+
+func stateFromFlags(flags StateFlags) printer.State {
+	switch {
+	case flags.Error:
+		return printer.STATE_ERROR
+	case flags.Paused || flags.Pausing:
+		return printer.STATE_PAUSED
+	case flags.Printing || flags.Cancelling || flags.Finishing || flags.Resuming:
+		return printer.STATE_PRINTING
+	case flags.Ready:
+		return printer.STATE_IDLE
+	case flags.Operational:
+		return printer.STATE_BUSY
+	default:
+		return printer.STATE_OFFLINE
+	}
+}
+
+func (d *Driver) Upload(filename string, r io.Reader, print bool) error {
+	ur := &UploadFileRequest{
+		Location: LOCATION_LOCAL,
+		Path:     UPLOAD_FOLDER,
+		Select:   true,
+		Print:    print,
+	}
+	err := ur.AddFile(filename, r)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.UploadFile(ur)
+	return err
+}
+
+func (d *Driver) Start(location string, path string) error {
+	location, err := storage(location)
+	if err != nil {
+		return err
+	}
+	return d.client.SelectFile(location, path, true)
+}
+
+/*
+storage checks the location is one OctoPrint has, local being its uploads folder and sdcard the printer's SD card
+*/
+func storage(location string) (string, error) {
+	switch location {
+	case "":
+		return LOCATION_LOCAL, nil
+	case LOCATION_LOCAL, LOCATION_SDCARD:
+		return location, nil
+	}
+	return "", fmt.Errorf("%w: %v", printer.ErrUnknownStorage, location)
+}
+
+func (d *Driver) Pause() error {
+	return d.client.PauseJob()
+}
+
+func (d *Driver) Resume() error {
+	return d.client.ResumeJob()
+}
+
+func (d *Driver) Cancel() error {
+	return d.client.CancelJob()
+}
+
+func (d *Driver) ListFiles() ([]printer.File, error) {
+	resp, err := d.client.ListFiles("", true)
+	if err != nil {
+		return nil, err
+	}
+	return flattenFiles(resp.Files, []printer.File{}), nil
+}
+
+func (d *Driver) DeleteFile(location string, path string) error {
+	location, err := storage(location)
+	if err != nil {
+		return err
+	}
+	return d.client.DeleteFile(location, path)
+}
+
+func flattenFiles(files []File, list []printer.File) []printer.File {
+	for _, f := range files {
+		if f.Type == "folder" {
+			list = flattenFiles(f.Children, list)
+			continue
+		}
+		name := f.Name
+		if f.Display != "" {
+			name = f.Display
+		}
+		list = append(list, printer.File{
+			Name:     filepath.Base(name),
+			Path:     f.Path,
+			Location: f.Origin,
+			Size:     f.Size,
+			Date:     time.Unix(f.Date, 0),
+		})
+	}
+	return list
+}
+
+func (d *Driver) SendGCode(commands ...string) error {
+	return d.client.IssueCommands(commands...)
+}
@@ -0,0 +1,133 @@
+package octoprint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+func TestDriver_Status(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		code     int
+		want     printer.State
+		wantJob  bool
+		wantTemp float64
+	}{
+		{"Idle", `{"temperature":{"tool0":{"actual":24.5,"target":0}},"state":{"text":"Operational","flags":{"operational":true,"ready":true}}}`, http.StatusOK, printer.STATE_IDLE, false, 24.5},
+		{"Printing", `{"temperature":{"tool0":{"actual":215,"target":215}},"state":{"text":"Printing","flags":{"operational":true,"printing":true}}}`, http.StatusOK, printer.STATE_PRINTING, true, 215},
+		{"Paused", `{"temperature":{"tool0":{"actual":170,"target":0}},"state":{"text":"Paused","flags":{"operational":true,"paused":true}}}`, http.StatusOK, printer.STATE_PAUSED, true, 170},
+		{"Not Connected", `Printer is not operational`, http.StatusConflict, printer.STATE_OFFLINE, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/printer":
+					w.WriteHeader(tt.code)
+					w.Write([]byte(tt.state))
+				case "/api/job":
+					w.Write([]byte(`{"job":{"file":{"path":"ymir/test.gcode"},"estimatedPrintTime":100},"progress":{"completion":12.5},"state":"Printing"}`))
+				}
+			}))
+			defer srv.Close()
+
+			d, err := printer.NewDriver(types.Printer{URL: srv.URL, APIType: types.API_TYPE_OCTOPRINT})
+			assert.NoError(t, err)
+			status, err := d.Status()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, status.State)
+			assert.Equal(t, tt.wantTemp, status.Temperatures["tool0"].Actual)
+			if tt.wantJob {
+				assert.Equal(t, "ymir/test.gcode", status.Job.File)
+				assert.Equal(t, 12.5, status.Job.Completion)
+			} else {
+				assert.Nil(t, status.Job)
+			}
+		})
+	}
+}
+
+func TestDriver_Info(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   string
+		wantModel string
+	}{
+		{"Profile", "prusa_mk3s", "Original Prusa i3 MK3S"},
+		{"Default Profile", DEFAULT_PROFILE, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Api-Key") != "ABC123" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				switch r.URL.Path {
+				case "/api/version":
+					w.Write([]byte(`{"api":"0.1","server":"1.9.3","text":"OctoPrint 1.9.3"}`))
+				case "/api/connection":
+					w.Write([]byte(`{"current":{"state":"Operational","printerProfile":"` + tt.profile + `"}}`))
+				case "/api/printerprofiles/prusa_mk3s":
+					w.Write([]byte(`{"id":"prusa_mk3s","name":"MK3S","model":"Original Prusa i3 MK3S"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			d, _ := printer.NewDriver(types.Printer{URL: srv.URL, APIKey: "ABC123", APIType: types.API_TYPE_OCTOPRINT})
+			info, err := d.Info()
+			assert.NoError(t, err)
+			assert.Equal(t, printer.Info{Server: "OctoPrint", ServerVersion: "1.9.3", Model: tt.wantModel}, info)
+
+			d, _ = printer.NewDriver(types.Printer{URL: srv.URL, APIKey: "wrong", APIType: types.API_TYPE_OCTOPRINT})
+			_, err = d.Info()
+			var statusErr printer.StatusError
+			assert.ErrorAs(t, err, &statusErr, "a bad api key should be reported")
+		})
+	}
+}
+
+func TestDriver_Files(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		path     string
+		want     string
+		wantErr  error
+	}{
+		{"Uploads", "", "ymir/benchy.gcode", "/api/files/local/ymir/benchy.gcode", nil},
+		{"SD Card", LOCATION_SDCARD, "CUBE.GCO", "/api/files/sdcard/CUBE.GCO", nil},
+		{"Unknown Storage", "usb", "CUBE.GCO", "", printer.ErrUnknownStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []string{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			d, _ := printer.NewDriver(types.Printer{URL: srv.URL, APIType: types.API_TYPE_OCTOPRINT})
+			startErr := d.Start(tt.location, tt.path)
+			deleteErr := d.DeleteFile(tt.location, tt.path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, startErr, tt.wantErr)
+				assert.ErrorIs(t, deleteErr, tt.wantErr)
+				assert.Empty(t, requests)
+				return
+			}
+			assert.NoError(t, startErr)
+			assert.NoError(t, deleteErr)
+			assert.Equal(t, []string{http.MethodPost + " " + tt.want, http.MethodDelete + " " + tt.want}, requests)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package octoprint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	_FILES_URI = "/api/files"
+)
+
+type UploadFileRequest struct {
+	// Location is the target location to which to upload the file. Currently
+	// only `local` and `sdcard` are supported here, with local referring to
+	// OctoPrint’s `uploads` folder and `sdcard` referring to the printer’s
+	// SD card. If an upload targets the SD card, it will also be stored
+	// locally first.
+	Location string
+	// Path is the folder inside Location to upload the file to. Optional.
+	Path string
+	// Select whether to select the file directly after upload (true) or not
+	// (false). Optional, defaults to false. Ignored when creating a folder.
+	Select bool
+	//Print whether to start printing the file directly after upload (true) or
+	// not (false). If set, select is implicitely true as well. Optional,
+	// defaults to false. Ignored when creating a folder.
+	Print bool
+	b     *bytes.Buffer
+	w     *multipart.Writer
+}
+
+// AddFile adds a new file to be uploaded from a given reader.
+func (req *UploadFileRequest) AddFile(filename string, r io.Reader) error {
+	w, err := req.writer().CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (req *UploadFileRequest) writer() *multipart.Writer {
+	if req.w == nil {
+		req.b = bytes.NewBuffer(nil)
+		req.w = multipart.NewWriter(req.b)
+	}
+
+	return req.w
+}
+
+func (req *UploadFileRequest) addFieldsAndClose() error {
+	if req.Path != "" {
+		err := req.writer().WriteField("path", req.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := req.writer().WriteField("select", fmt.Sprintf("%t", req.Select))
+	if err != nil {
+		return err
+	}
+
+	err = req.writer().WriteField("print", fmt.Sprintf("%t", req.Print))
+	if err != nil {
+		return err
+	}
+
+	return req.writer().Close()
+}
+
+/*
+UploadFile uploads the file added to the request with AddFile.
+Uploads are not bound by the client timeout since large G-code files can take a while.
+*/
+func (c *Client) UploadFile(ur *UploadFileRequest) (resp UploadResponse, err error) {
+	if ur.Location == "" {
+		ur.Location = LOCATION_LOCAL
+	}
+	if err = ur.addFieldsAndClose(); err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, fmt.Sprintf("%s%s/%s", c.Endpoint, _FILES_URI, ur.Location), ur.b)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", ur.w.FormDataContentType())
+	err = c.do(req, &resp, http.StatusCreated)
+	return
+}
+
+/*
+ListFiles lists the files and folders of a location. Use an empty location for all locations.
+*/
+func (c *Client) ListFiles(location string, recursive bool) (files FilesResponse, err error) {
+	uri := _FILES_URI
+	if location != "" {
+		uri = fmt.Sprintf("%s/%s", uri, location)
+	}
+	err = c.doJSONRequest(http.MethodGet, fmt.Sprintf("%s?recursive=%t", uri, recursive), nil, &files)
+	return
+}
+
+/*
+GetFile retrieves a single file or folder (with its children) from a location
+*/
+func (c *Client) GetFile(location string, path string, recursive bool) (file File, err error) {
+	err = c.doJSONRequest(http.MethodGet, fmt.Sprintf("%s?recursive=%t", fileURI(location, path), recursive), nil, &file)
+	return
+}
+
+func (c *Client) DeleteFile(location string, path string) error {
+	return c.doJSONRequest(http.MethodDelete, fileURI(location, path), nil, nil, http.StatusNoContent)
+}
+
+/*
+SelectFile selects a file for printing and optionally starts the print
+*/
+func (c *Client) SelectFile(location string, path string, print bool) error {
+	return c.doJSONRequest(http.MethodPost, fileURI(location, path), FileCommand{Command: "select", Print: print}, nil, http.StatusNoContent)
+}
+
+func fileURI(location string, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return fmt.Sprintf("%s/%s/%s", _FILES_URI, location, strings.Join(parts, "/"))
+}
@@ -0,0 +1,49 @@
+package octoprint
+
+import (
+	"net/http"
+)
+
+const (
+	_JOB_URI = "/api/job"
+
+	JOB_START   = "start"
+	JOB_CANCEL  = "cancel"
+	JOB_RESTART = "restart"
+	JOB_PAUSE   = "pause"
+
+	PAUSE_ACTION_PAUSE  = "pause"
+	PAUSE_ACTION_RESUME = "resume"
+	PAUSE_ACTION_TOGGLE = "toggle"
+)
+
+/*
+GetJob retrieves information about the current job (if there is one)
+*/
+func (c *Client) GetJob() (job JobResponse, err error) {
+	err = c.doJSONRequest(http.MethodGet, _JOB_URI, nil, &job)
+	return
+}
+
+/*
+IssueJobCommand starts, cancels, restarts, pauses or resumes the current job
+*/
+func (c *Client) IssueJobCommand(cmd JobCommand) error {
+	return c.doJSONRequest(http.MethodPost, _JOB_URI, cmd, nil, http.StatusNoContent)
+}
+
+func (c *Client) StartJob() error {
+	return c.IssueJobCommand(JobCommand{Command: JOB_START})
+}
+
+func (c *Client) CancelJob() error {
+	return c.IssueJobCommand(JobCommand{Command: JOB_CANCEL})
+}
+
+func (c *Client) PauseJob() error {
+	return c.IssueJobCommand(JobCommand{Command: JOB_PAUSE, Action: PAUSE_ACTION_PAUSE})
+}
+
+func (c *Client) ResumeJob() error {
+	return c.IssueJobCommand(JobCommand{Command: JOB_PAUSE, Action: PAUSE_ACTION_RESUME})
+}
@@ -0,0 +1,48 @@
+package octoprint
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const (
+	_PRINTER_URI          = "/api/printer"
+	_COMMAND_URI          = "/api/printer/command"
+	_VERSION_URI          = "/api/version"
+	_PRINTER_PROFILES_URI = "/api/printerprofiles"
+
+	// DEFAULT_PROFILE is the printer profile OctoPrint ships with, which says nothing about the printer
+	DEFAULT_PROFILE = "_default"
+)
+
+/*
+GetPrinterState retrieves the current temperatures, sd and printer state.
+OctoPrint answers with 409 if the printer is not operational.
+*/
+func (c *Client) GetPrinterState() (state PrinterState, err error) {
+	err = c.doJSONRequest(http.MethodGet, _PRINTER_URI+"?exclude=history", nil, &state)
+	return
+}
+
+/*
+IssueCommands sends one or more raw G-code commands to the printer
+*/
+func (c *Client) IssueCommands(commands ...string) error {
+	return c.doJSONRequest(http.MethodPost, _COMMAND_URI, CommandRequest{Commands: commands}, nil, http.StatusNoContent)
+}
+
+/*
+GetVersion retrieves the OctoPrint server and API version. It needs a valid API key.
+*/
+func (c *Client) GetVersion() (version VersionResponse, err error) {
+	err = c.doJSONRequest(http.MethodGet, _VERSION_URI, nil, &version)
+	return
+}
+
+/*
+GetPrinterProfile retrieves the printer profile with {id}
+*/
+func (c *Client) GetPrinterProfile(id string) (profile PrinterProfile, err error) {
+	err = c.doJSONRequest(http.MethodGet, _PRINTER_PROFILES_URI+"/"+url.PathEscape(id), nil, &profile)
+	return
+}
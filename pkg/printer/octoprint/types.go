@@ -0,0 +1,197 @@
+package octoprint
+
+/*
+Data model for the parts of the OctoPrint API that Ymir uses.
+https://docs.octoprint.org/en/master/api/datamodel.html
+*/
+
+const (
+	LOCATION_LOCAL  = "local"
+	LOCATION_SDCARD = "sdcard"
+)
+
+// JobResponse is returned by GET /api/job
+type JobResponse struct {
+	Job      JobInformation      `json:"job"`
+	Progress ProgressInformation `json:"progress"`
+	State    string              `json:"state"`
+	Error    string              `json:"error,omitempty"`
+}
+
+type JobInformation struct {
+	File               JobFile                   `json:"file"`
+	EstimatedPrintTime float64                   `json:"estimatedPrintTime"`
+	AveragePrintTime   float64                   `json:"averagePrintTime"`
+	LastPrintTime      float64                   `json:"lastPrintTime"`
+	Filament           map[string]FilamentLength `json:"filament"`
+	User               string                    `json:"user"`
+}
+
+type JobFile struct {
+	Name    string `json:"name"`
+	Display string `json:"display"`
+	Path    string `json:"path"`
+	Origin  string `json:"origin"`
+	Size    int64  `json:"size"`
+	Date    int64  `json:"date"`
+}
+
+type FilamentLength struct {
+	Length float64 `json:"length"`
+	Volume float64 `json:"volume"`
+}
+
+type ProgressInformation struct {
+	Completion          float64 `json:"completion"`
+	FilePos             int64   `json:"filepos"`
+	PrintTime           float64 `json:"printTime"`
+	PrintTimeLeft       float64 `json:"printTimeLeft"`
+	PrintTimeLeftOrigin string  `json:"printTimeLeftOrigin"`
+}
+
+// JobCommand is the body of POST /api/job
+type JobCommand struct {
+	Command string `json:"command"`
+	Action  string `json:"action,omitempty"`
+}
+
+// PrinterState is returned by GET /api/printer
+type PrinterState struct {
+	Temperature map[string]TemperatureData `json:"temperature"`
+	SD          SDState                    `json:"sd"`
+	State       StateInformation           `json:"state"`
+}
+
+type TemperatureData struct {
+	Actual float64  `json:"actual"`
+	Target float64  `json:"target"`
+	Offset *float64 `json:"offset,omitempty"`
+}
+
+type SDState struct {
+	Ready bool `json:"ready"`
+}
+
+type StateInformation struct {
+	Text  string     `json:"text"`
+	Error string     `json:"error,omitempty"`
+	Flags StateFlags `json:"flags"`
+}
+
+type StateFlags struct {
+	Operational   bool `json:"operational"`
+	Paused        bool `json:"paused"`
+	Printing      bool `json:"printing"`
+	Pausing       bool `json:"pausing"`
+	Cancelling    bool `json:"cancelling"`
+	SDReady       bool `json:"sdReady"`
+	Error         bool `json:"error"`
+	Ready         bool `json:"ready"`
+	ClosedOrError bool `json:"closedOrError"`
+	Finishing     bool `json:"finishing"`
+	Resuming      bool `json:"resuming"`
+}
+
+// CommandRequest is the body of POST /api/printer/command
+type CommandRequest struct {
+	Commands []string `json:"commands"`
+}
+
+// ConnectionResponse is returned by GET /api/connection
+type ConnectionResponse struct {
+	Current ConnectionState   `json:"current"`
+	Options ConnectionOptions `json:"options"`
+}
+
+type ConnectionState struct {
+	State          string `json:"state"`
+	Port           string `json:"port"`
+	BaudRate       int    `json:"baudrate"`
+	PrinterProfile string `json:"printerProfile"`
+}
+
+type ConnectionOptions struct {
+	Ports                    []string         `json:"ports"`
+	BaudRates                []int            `json:"baudrates"`
+	PrinterProfiles          []PrinterProfile `json:"printerProfiles"`
+	PortPreference           string           `json:"portPreference"`
+	BaudRatePreference       int              `json:"baudratePreference"`
+	PrinterProfilePreference string           `json:"printerProfilePreference"`
+	Autoconnect              bool             `json:"autoconnect"`
+}
+
+type PrinterProfile struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Model string `json:"model,omitempty"`
+}
+
+// VersionResponse is returned by GET /api/version
+type VersionResponse struct {
+	API    string `json:"api"`
+	Server string `json:"server"`
+	Text   string `json:"text"`
+}
+
+// ConnectionRequest is the body of POST /api/connection
+type ConnectionRequest struct {
+	Command        string `json:"command"`
+	Port           string `json:"port,omitempty"`
+	BaudRate       int    `json:"baudrate,omitempty"`
+	PrinterProfile string `json:"printerProfile,omitempty"`
+	Save           bool   `json:"save,omitempty"`
+	Autoconnect    bool   `json:"autoconnect,omitempty"`
+}
+
+// FilesResponse is returned by GET /api/files and GET /api/files/{location}
+type FilesResponse struct {
+	Files []File `json:"files"`
+	Free  int64  `json:"free,omitempty"`
+	Total int64  `json:"total,omitempty"`
+}
+
+// File is a file or folder on the OctoPrint server. Folders carry their Children.
+type File struct {
+	Name          string         `json:"name"`
+	Display       string         `json:"display"`
+	Path          string         `json:"path"`
+	Type          string         `json:"type"`
+	TypePath      []string       `json:"typePath"`
+	Hash          string         `json:"hash,omitempty"`
+	Size          int64          `json:"size,omitempty"`
+	Date          int64          `json:"date,omitempty"`
+	Origin        string         `json:"origin"`
+	Refs          FileRefs       `json:"refs"`
+	GCodeAnalysis *GCodeAnalysis `json:"gcodeAnalysis,omitempty"`
+	Children      []File         `json:"children,omitempty"`
+}
+
+type FileRefs struct {
+	Resource string `json:"resource"`
+	Download string `json:"download,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+type GCodeAnalysis struct {
+	EstimatedPrintTime float64                   `json:"estimatedPrintTime"`
+	Filament           map[string]FilamentLength `json:"filament"`
+	Dimensions         Dimensions                `json:"dimensions"`
+}
+
+type Dimensions struct {
+	Width  float64 `json:"width"`
+	Depth  float64 `json:"depth"`
+	Height float64 `json:"height"`
+}
+
+// FileCommand is the body of POST /api/files/{location}/{path}
+type FileCommand struct {
+	Command string `json:"command"`
+	Print   bool   `json:"print,omitempty"`
+}
+
+// UploadResponse is returned by POST /api/files/{location}
+type UploadResponse struct {
+	Files map[string]File `json:"files"`
+	Done  bool            `json:"done"`
+}
@@ -0,0 +1,312 @@
+/*
+Package poller queries every printer in the PrinterStore in the background and caches
+the latest status so clients don't have to talk to each printer themselves.
+*/
+package poller
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/events"
+	"ymir/pkg/printer"
+)
+
+const (
+	DEFAULT_INTERVAL = 10 * time.Second
+)
+
+/*
+PrinterLister is the part of the PrinterStore the poller needs
+*/
+type PrinterLister interface {
+	List() (printers map[string]types.Printer, err error)
+}
+
+/*
+PrinterStatus is the cached status of one printer. LastSeen is the last time the printer answered.
+*/
+type PrinterStatus struct {
+	Id          string `json:"id"`
+	PrinterName string `json:"printerName"`
+	printer.Status
+	LastSeen time.Time `json:"lastSeen"`
+	Updated  time.Time `json:"updated"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type Poller struct {
+	store    PrinterLister
+	interval time.Duration
+
+	lock   sync.RWMutex
+	cache  map[string]PrinterStatus
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	active bool
+
+	// connecting holds the printers being connected, so a slow connect isn't started again every poll
+	connectLock sync.Mutex
+	connecting  map[string]bool
+}
+
+var (
+	defaultLock = &sync.RWMutex{}
+	instance    *Poller
+)
+
+func NewPoller(store PrinterLister, interval time.Duration) *Poller {
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+	return &Poller{
+		store:      store,
+		interval:   interval,
+		cache:      map[string]PrinterStatus{},
+		connecting: map[string]bool{},
+	}
+}
+
+/*
+SetDefault makes p the poller returned by Default. The server sets it when it starts the poller.
+*/
+func SetDefault(p *Poller) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	instance = p
+}
+
+/*
+Default returns the poller the server started, or nil if there is none
+*/
+func Default() *Poller {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return instance
+}
+
+/*
+Start connects the AutoConnect printers and then polls every printer each interval until Stop is called
+*/
+func (p *Poller) Start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.active {
+		return
+	}
+	p.active = true
+	p.stop = make(chan struct{})
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.connectAll()
+		p.Poll()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.Poll()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	log.Infof("printer status poller started, polling every %v", p.interval)
+}
+
+func (p *Poller) Stop() {
+	p.lock.Lock()
+	if !p.active {
+		p.lock.Unlock()
+		return
+	}
+	p.active = false
+	close(p.stop)
+	p.lock.Unlock()
+	p.wg.Wait()
+	log.Info("printer status poller stopped")
+}
+
+/*
+connectAll connects every printer marked AutoConnect
+*/
+func (p *Poller) connectAll() {
+	printers, err := p.store.List()
+	if err != nil {
+		log.Errorf("poller could not list printers: %v", err)
+		return
+	}
+	wg := sync.WaitGroup{}
+	for _, pr := range printers {
+		if !pr.AutoConnect {
+			continue
+		}
+		wg.Add(1)
+		go func(pr types.Printer) {
+			defer wg.Done()
+			p.connect(pr)
+		}(pr)
+	}
+	wg.Wait()
+}
+
+/*
+connect connects the printer unless it is being connected already
+*/
+func (p *Poller) connect(pr types.Printer) {
+	p.connectLock.Lock()
+	if p.connecting[pr.Id] {
+		p.connectLock.Unlock()
+		return
+	}
+	p.connecting[pr.Id] = true
+	p.connectLock.Unlock()
+	defer func() {
+		p.connectLock.Lock()
+		delete(p.connecting, pr.Id)
+		p.connectLock.Unlock()
+	}()
+
+	d, err := printer.NewDriver(pr)
+	if err == nil {
+		err = d.Connect()
+	}
+	if err != nil {
+		log.Warnf("could not auto connect printer %v: %v", pr.PrinterName, err)
+		return
+	}
+	log.Infof("auto connected printer %v", pr.PrinterName)
+}
+
+/*
+Poll queries every printer once and updates the cache. Printers that have been deleted are dropped.
+*/
+func (p *Poller) Poll() {
+	printers, err := p.store.List()
+	if err != nil {
+		log.Errorf("poller could not list printers: %v", err)
+		return
+	}
+
+	results := make(chan PrinterStatus, len(printers))
+	wg := sync.WaitGroup{}
+	for _, pr := range printers {
+		wg.Add(1)
+		go func(pr types.Printer) {
+			defer wg.Done()
+			results <- p.poll(pr)
+		}(pr)
+	}
+	wg.Wait()
+	close(results)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cache := make(map[string]PrinterStatus, len(printers))
+	for s := range results {
+		cache[s.Id] = s
+	}
+	p.cache = cache
+}
+
+func (p *Poller) poll(pr types.Printer) PrinterStatus {
+	now := time.Now()
+	p.lock.RLock()
+	prev, ok := p.cache[pr.Id]
+	p.lock.RUnlock()
+	if !ok {
+		prev = PrinterStatus{Id: pr.Id}
+	}
+	s := prev
+	s.PrinterName = pr.PrinterName
+	s.Updated = now
+
+	status, err := status(pr)
+	if err != nil {
+		log.Debugf("poller could not get status of printer %v: %v", pr.PrinterName, err)
+		s.Status = printer.Status{
+			State:        printer.STATE_OFFLINE,
+			Text:         "Unreachable",
+			Temperatures: map[string]printer.Temperature{},
+		}
+		s.Error = err.Error()
+	} else {
+		s.Status = status
+		s.Error = ""
+		s.LastSeen = now
+		if status.State == printer.STATE_OFFLINE && pr.AutoConnect {
+			// the host is up but not connected to the printer, e.g. the printer was switched on after Ymir started
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				p.connect(pr)
+			}()
+		}
+	}
+	publishChanges(prev, s)
+	return s
+}
+
+/*
+publishChanges publishes state changes and job progress between two polls. Progress goes first so
+subscribers know how far a job got when the state change that ends it arrives.
+*/
+func publishChanges(prev PrinterStatus, s PrinterStatus) {
+	if s.Job != nil && (prev.Job == nil || *prev.Job != *s.Job) {
+		events.Publish(events.Event{
+			Type:      events.JOB_PROGRESS,
+			PrinterId: s.Id,
+			Time:      s.Updated,
+			Data:      s.Job,
+		})
+	}
+	if prev.State != s.State {
+		events.Publish(events.Event{
+			Type:      events.PRINTER_STATE,
+			PrinterId: s.Id,
+			Time:      s.Updated,
+			Data: events.StateChange{
+				PrinterName: s.PrinterName,
+				Previous:    string(prev.State),
+				State:       string(s.State),
+				Text:        s.Text,
+			},
+		})
+	}
+}
+
+func status(pr types.Printer) (printer.Status, error) {
+	d, err := printer.NewDriver(pr)
+	if err != nil {
+		return printer.Status{}, err
+	}
+	return d.Status()
+}
+
+/*
+Status returns the cached status of the printer with id
+*/
+func (p *Poller) Status(id string) (PrinterStatus, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	s, ok := p.cache[id]
+	return s, ok
+}
+
+/*
+Statuses returns the cached status of every printer keyed by printer id
+*/
+func (p *Poller) Statuses() map[string]PrinterStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	statuses := make(map[string]PrinterStatus, len(p.cache))
+	for k, v := range p.cache {
+		statuses[k] = v
+	}
+	return statuses
+}
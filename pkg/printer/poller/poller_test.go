@@ -0,0 +1,226 @@
+package poller
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+	bus "ymir/pkg/events"
+	"ymir/pkg/printer"
+)
+
+const (
+	FAKE_API_TYPE = "PollerFake"
+)
+
+var (
+	fakeLock      = &sync.Mutex{}
+	fakeStates    = map[string]printer.State{}
+	fakeConnected = map[string]int{}
+	// fakeConnecting keeps Connect waiting until it is closed
+	fakeConnecting chan struct{}
+)
+
+/*
+fakeDriver reports whatever state the test put in fakeStates for the printer's URL
+*/
+type fakeDriver struct {
+	url string
+}
+
+func (f *fakeDriver) Connect() error {
+	fakeLock.Lock()
+	defer fakeLock.Unlock()
+	fakeConnected[f.url]++
+	connecting := fakeConnecting
+	fakeLock.Unlock()
+	if connecting != nil {
+		<-connecting
+	}
+	fakeLock.Lock()
+	return nil
+}
+
+func (f *fakeDriver) Status() (printer.Status, error) {
+	fakeLock.Lock()
+	defer fakeLock.Unlock()
+	state, ok := fakeStates[f.url]
+	if !ok {
+		return printer.Status{}, errors.New("connection refused")
+	}
+	return printer.Status{State: state, Text: string(state), Temperatures: map[string]printer.Temperature{"bed": {Actual: 60, Target: 60}}}, nil
+}
+
+func (f *fakeDriver) Upload(string, io.Reader, bool) error { return nil }
+func (f *fakeDriver) Start(string, string) error           { return nil }
+func (f *fakeDriver) DeleteFile(string, string) error      { return nil }
+func (f *fakeDriver) Pause() error                         { return nil }
+func (f *fakeDriver) Resume() error                        { return nil }
+func (f *fakeDriver) Cancel() error                        { return nil }
+func (f *fakeDriver) ListFiles() ([]printer.File, error)   { return nil, nil }
+func (f *fakeDriver) SendGCode(...string) error            { return nil }
+func (f *fakeDriver) Info() (printer.Info, error)          { return printer.Info{}, nil }
+
+func init() {
+	printer.Register(FAKE_API_TYPE, func(p types.Printer) (printer.PrinterDriver, error) {
+		return &fakeDriver{url: p.URL}, nil
+	})
+}
+
+type fakeStore struct {
+	printers map[string]types.Printer
+}
+
+func (s *fakeStore) List() (map[string]types.Printer, error) {
+	return s.printers, nil
+}
+
+type PollerTestSuite struct {
+	suite.Suite
+	store  *fakeStore
+	poller *Poller
+}
+
+func (suite *PollerTestSuite) SetupTest() {
+	fakeLock.Lock()
+	fakeStates = map[string]printer.State{"up": printer.STATE_IDLE, "unplugged": printer.STATE_OFFLINE}
+	fakeConnected = map[string]int{}
+	fakeConnecting = nil
+	fakeLock.Unlock()
+
+	suite.store = &fakeStore{printers: map[string]types.Printer{
+		"1": {Id: "1", PrinterName: "mk3s", URL: "up", APIType: FAKE_API_TYPE, AutoConnect: true},
+		"2": {Id: "2", PrinterName: "ender", URL: "down", APIType: FAKE_API_TYPE},
+		"3": {Id: "3", PrinterName: "voron", URL: "unplugged", APIType: FAKE_API_TYPE, AutoConnect: true},
+	}}
+	suite.poller = NewPoller(suite.store, time.Hour)
+}
+
+func (suite *PollerTestSuite) connected(url string) int {
+	fakeLock.Lock()
+	defer fakeLock.Unlock()
+	return fakeConnected[url]
+}
+
+func (suite *PollerTestSuite) TestPoll() {
+	suite.poller.Poll()
+	statuses := suite.poller.Statuses()
+	assert.Len(suite.T(), statuses, 3)
+
+	up := statuses["1"]
+	assert.Equal(suite.T(), "mk3s", up.PrinterName)
+	assert.Equal(suite.T(), printer.STATE_IDLE, up.State)
+	assert.Equal(suite.T(), 60.0, up.Temperatures["bed"].Actual)
+	assert.False(suite.T(), up.LastSeen.IsZero())
+	assert.Empty(suite.T(), up.Error)
+
+	down := statuses["2"]
+	assert.Equal(suite.T(), printer.STATE_OFFLINE, down.State)
+	assert.True(suite.T(), down.LastSeen.IsZero(), "a printer that never answered has not been seen")
+	assert.Equal(suite.T(), "connection refused", down.Error)
+}
+
+func (suite *PollerTestSuite) TestPoll_KeepsLastSeen() {
+	suite.poller.Poll()
+	seen := suite.poller.Statuses()["1"].LastSeen
+
+	fakeLock.Lock()
+	delete(fakeStates, "up")
+	fakeLock.Unlock()
+	suite.poller.Poll()
+
+	s, ok := suite.poller.Status("1")
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), printer.STATE_OFFLINE, s.State)
+	assert.Equal(suite.T(), seen, s.LastSeen)
+	assert.True(suite.T(), s.Updated.After(seen))
+}
+
+func (suite *PollerTestSuite) TestPoll_DropsDeletedPrinters() {
+	suite.poller.Poll()
+	delete(suite.store.printers, "2")
+	suite.poller.Poll()
+	_, ok := suite.poller.Status("2")
+	assert.False(suite.T(), ok)
+}
+
+func (suite *PollerTestSuite) TestStart_AutoConnect() {
+	suite.poller.Start()
+	defer suite.poller.Stop()
+	assert.Eventually(suite.T(), func() bool {
+		_, ok := suite.poller.Status("1")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(suite.T(), 1, suite.connected("up"))
+	assert.Equal(suite.T(), 0, suite.connected("down"), "printers without AutoConnect are left alone")
+	assert.Eventually(suite.T(), func() bool {
+		return suite.connected("unplugged") >= 2
+	}, time.Second, 10*time.Millisecond, "offline AutoConnect printers are connected again when polled")
+}
+
+func (suite *PollerTestSuite) TestPoll_OneConnectAtATime() {
+	fakeLock.Lock()
+	fakeConnecting = make(chan struct{})
+	fakeLock.Unlock()
+	for i := 0; i < 3; i++ {
+		suite.poller.Poll()
+	}
+	assert.Eventually(suite.T(), func() bool {
+		return suite.connected("unplugged") == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(suite.T(), 1, suite.connected("unplugged"), "the printer is still being connected")
+
+	fakeLock.Lock()
+	close(fakeConnecting)
+	fakeLock.Unlock()
+	suite.poller.wg.Wait()
+	suite.poller.Poll()
+	suite.poller.wg.Wait()
+	assert.Equal(suite.T(), 2, suite.connected("unplugged"), "a connect that is done can be tried again")
+}
+
+func (suite *PollerTestSuite) TestPoll_PublishesStateChanges() {
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	defer unsubscribe()
+	suite.poller.Poll()
+	suite.poller.Poll()
+
+	changes := map[string]bus.StateChange{}
+	for len(events) > 0 {
+		e := <-events
+		if e.Type == bus.PRINTER_STATE {
+			changes[e.PrinterId] = e.Data.(bus.StateChange)
+		}
+	}
+	assert.Len(suite.T(), changes, 3, "only the first poll changes state")
+	assert.Equal(suite.T(), bus.StateChange{PrinterName: "mk3s", State: "idle", Text: "idle"}, changes["1"])
+
+	fakeLock.Lock()
+	fakeStates["up"] = printer.STATE_PRINTING
+	fakeLock.Unlock()
+	suite.poller.Poll()
+	e := <-events
+	assert.Equal(suite.T(), "1", e.PrinterId)
+	assert.Equal(suite.T(), "idle", e.Data.(bus.StateChange).Previous)
+	assert.Equal(suite.T(), "printing", e.Data.(bus.StateChange).State)
+}
+
+func TestDefault(t *testing.T) {
+	assert.Nil(t, Default())
+	p := NewPoller(&fakeStore{}, 0)
+	assert.Equal(t, DEFAULT_INTERVAL, p.interval)
+	SetDefault(p)
+	assert.Equal(t, p, Default())
+	SetDefault(nil)
+}
+
+func TestPollerTestSuite(t *testing.T) {
+	suite.Run(t, new(PollerTestSuite))
+}
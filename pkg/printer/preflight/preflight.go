@@ -0,0 +1,207 @@
+/*
+Package preflight checks a print file against the capability profile of the printer it is about to be sent to.
+*/
+package preflight
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ymir/pkg/api/printer/types"
+	spooltypes "ymir/pkg/api/spool/types"
+	"ymir/pkg/gcode"
+	"ymir/pkg/printer/profiles"
+)
+
+const (
+	SEVERITY_WARNING = "warning"
+	SEVERITY_ERROR   = "error"
+
+	CHECK_PROFILE      = "profile"
+	CHECK_PRINTER_TYPE = "printerType"
+	CHECK_NOZZLE       = "nozzle"
+	CHECK_MATERIAL     = "material"
+	CHECK_BUILD_VOLUME = "buildVolume"
+	CHECK_FORMAT       = "format"
+	CHECK_FILAMENT     = "filament"
+
+	// TOLERANCE is how far in mm moves may stray outside the build volume, for purge lines printed off the bed edge
+	TOLERANCE = 5.0
+)
+
+/*
+Issue is one problem found with a print file. Errors block the print unless overridden, warnings don't.
+*/
+type Issue struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type Report struct {
+	File       string  `json:"file"`
+	Issues     []Issue `json:"issues"`
+	Overridden bool    `json:"overridden,omitempty"`
+}
+
+/*
+Blocking is true when the report has errors
+*/
+func (r Report) Blocking() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SEVERITY_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) add(check string, severity string, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Check: check, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+/*
+Error is returned when a print is refused over a blocking Report
+*/
+type Error struct {
+	Report Report
+}
+
+func (e *Error) Error() string {
+	msgs := []string{}
+	for _, i := range e.Report.Issues {
+		if i.Severity == SEVERITY_ERROR {
+			msgs = append(msgs, i.Message)
+		}
+	}
+	return fmt.Sprintf("%v is not compatible with the printer: %v", filepath.Base(e.Report.File), strings.Join(msgs, "; "))
+}
+
+/*
+Check compares the file's slicer metadata and extents with the printer's profile
+*/
+func Check(file string, meta gcode.GCodeMetaData, ext gcode.Extents, profile *types.Profile) Report {
+	r := Report{File: file, Issues: []Issue{}}
+	if profile == nil {
+		r.add(CHECK_PROFILE, SEVERITY_WARNING, "the printer has no profile, so the file could not be checked")
+		return r
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".bgcode") && !profile.BinaryGCode {
+		r.add(CHECK_FORMAT, SEVERITY_ERROR, "the %v can't print binary G-code", profile.Model)
+	}
+
+	if meta.PrinterType != "" {
+		if len(profile.SlicerModels) == 0 {
+			r.add(CHECK_PRINTER_TYPE, SEVERITY_WARNING, "sliced for %v, which can't be checked against the %v profile",
+				meta.PrinterType, profile.Model)
+		} else if !profiles.SlicedFor(*profile, meta.PrinterType) {
+			r.add(CHECK_PRINTER_TYPE, SEVERITY_ERROR, "sliced for %v, not the %v", meta.PrinterType, profile.Model)
+		}
+	}
+
+	for _, d := range splitList(meta.NozzleDiameter) {
+		nozzle, err := strconv.ParseFloat(d, 64)
+		if err != nil {
+			continue
+		}
+		if !hasNozzle(profile.Nozzles, nozzle) {
+			r.add(CHECK_NOZZLE, SEVERITY_ERROR, "sliced for a %vmm nozzle, the %v takes %v", nozzle, profile.Model,
+				joinFloats(profile.Nozzles, "mm, ")+"mm")
+		}
+	}
+
+	for _, m := range splitList(meta.Material) {
+		if !hasMaterial(profile.Materials, m) {
+			r.add(CHECK_MATERIAL, SEVERITY_WARNING, "%v is not a material the %v is known to print", m, profile.Model)
+		}
+	}
+
+	if ext.Moves > 0 {
+		v := profile.BuildVolume
+		for _, axis := range []struct {
+			name     string
+			min, max float64
+			size     float64
+		}{
+			{"X", ext.MinX, ext.MaxX, v.X},
+			{"Y", ext.MinY, ext.MaxY, v.Y},
+			{"Z", ext.MinZ, ext.MaxZ, v.Z},
+		} {
+			if axis.size <= 0 {
+				continue
+			}
+			if axis.max > axis.size+TOLERANCE || axis.min < -TOLERANCE {
+				r.add(CHECK_BUILD_VOLUME, SEVERITY_ERROR, "prints from %v=%.1f to %v=%.1f, the %v's build volume is %.0fmm",
+					axis.name, axis.min, axis.name, axis.max, profile.Model, axis.size)
+			}
+		}
+	}
+	return r
+}
+
+/*
+CheckSpool warns when the spool loaded in the printer holds less filament than the file uses or is another material.
+Neither blocks the print, the remaining weight is an estimate and spools get swapped mid print.
+*/
+func CheckSpool(r *Report, meta gcode.GCodeMetaData, spool spooltypes.Spool) {
+	needed := 0.0
+	for _, g := range strings.Split(meta.FilamentUsedG, ",") {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(g), 64); err == nil {
+			needed += v
+		}
+	}
+	if needed > spool.RemainingWeightG {
+		r.add(CHECK_FILAMENT, SEVERITY_WARNING, "the print uses %.1fg of filament, the %v spool has %.1fg left",
+			needed, spool.Material, spool.RemainingWeightG)
+	}
+	materials := splitList(meta.Material)
+	if len(materials) > 0 && spool.Material != "" && !hasMaterial(materials, spool.Material) {
+		r.add(CHECK_FILAMENT, SEVERITY_WARNING, "sliced for %v, the printer has a %v spool loaded",
+			strings.Join(materials, ", "), spool.Material)
+	}
+}
+
+/*
+splitList splits multi extruder values such as "PLA;PETG" or "0.4,0.4", dropping repeats
+*/
+func splitList(value string) []string {
+	values := []string{}
+	seen := map[string]bool{}
+	for _, v := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if v = strings.TrimSpace(v); v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func hasNozzle(nozzles []float64, nozzle float64) bool {
+	for _, n := range nozzles {
+		if math.Abs(n-nozzle) < 0.001 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMaterial(materials []string, material string) bool {
+	for _, m := range materials {
+		if strings.EqualFold(m, material) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinFloats(values []float64, sep string) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(s, sep)
+}
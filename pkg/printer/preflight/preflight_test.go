@@ -0,0 +1,66 @@
+package preflight
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	spooltypes "ymir/pkg/api/spool/types"
+	"ymir/pkg/gcode"
+	"ymir/pkg/printer/profiles"
+)
+
+func checks(r Report) map[string]string {
+	found := map[string]string{}
+	for _, i := range r.Issues {
+		found[i.Check] = i.Severity
+	}
+	return found
+}
+
+func TestCheck(t *testing.T) {
+	mk3, _ := profiles.Get("prusa-mk3s")
+	fits := gcode.Extents{MinX: 0, MaxX: 200, MinY: -3, MaxY: 200, MinZ: 0.2, MaxZ: 50, Moves: 100}
+
+	r := Check("benchy.gcode", gcode.GCodeMetaData{PrinterType: "MK3S", NozzleDiameter: "0.4", Material: "PLA"}, fits, &mk3)
+	assert.Empty(t, r.Issues)
+	assert.False(t, r.Blocking())
+
+	r = Check("benchy_mk4.gcode", gcode.GCodeMetaData{PrinterType: "MK4IS", NozzleDiameter: "0.3", Material: "PLA;WOOD"},
+		gcode.Extents{MaxX: 251, MaxY: 220, MaxZ: 10, Moves: 1}, &mk3)
+	assert.True(t, r.Blocking())
+	assert.Equal(t, map[string]string{
+		CHECK_PRINTER_TYPE: SEVERITY_ERROR,
+		CHECK_NOZZLE:       SEVERITY_ERROR,
+		CHECK_MATERIAL:     SEVERITY_WARNING,
+		CHECK_BUILD_VOLUME: SEVERITY_ERROR,
+	}, checks(r))
+	assert.Len(t, r.Issues, 4, "only Y is outside the bed")
+
+	r = Check("benchy.bgcode", gcode.GCodeMetaData{}, gcode.Extents{}, &mk3)
+	assert.Equal(t, map[string]string{CHECK_FORMAT: SEVERITY_ERROR}, checks(r))
+
+	r = Check("benchy.gcode", gcode.GCodeMetaData{PrinterType: "MK4"}, fits, nil)
+	assert.Equal(t, map[string]string{CHECK_PROFILE: SEVERITY_WARNING}, checks(r))
+	assert.False(t, r.Blocking())
+
+	var err error = &Error{Report: Check("benchy_mk4.gcode", gcode.GCodeMetaData{PrinterType: "MK4"}, fits, &mk3)}
+	var preflightErr *Error
+	assert.True(t, errors.As(err, &preflightErr))
+	assert.Contains(t, err.Error(), "sliced for MK4")
+}
+
+func TestCheckSpool(t *testing.T) {
+	spool := spooltypes.Spool{Material: "PLA", RemainingWeightG: 20}
+
+	r := Report{}
+	CheckSpool(&r, gcode.GCodeMetaData{Material: "PLA", FilamentUsedG: "12.5"}, spool)
+	assert.Empty(t, r.Issues)
+
+	r = Report{}
+	CheckSpool(&r, gcode.GCodeMetaData{Material: "PETG", FilamentUsedG: "12.5, 12.5"}, spool)
+	assert.Len(t, r.Issues, 2)
+	assert.Equal(t, map[string]string{CHECK_FILAMENT: SEVERITY_WARNING}, checks(r))
+	assert.Contains(t, r.Issues[0].Message, "25.0g")
+	assert.False(t, r.Blocking())
+}
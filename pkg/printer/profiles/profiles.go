@@ -0,0 +1,124 @@
+/*
+Package profiles is the bundled catalog of printer capability profiles new printers are seeded from.
+*/
+package profiles
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode"
+
+	"ymir/pkg/api/printer/types"
+)
+
+//go:embed catalog.json
+var catalogJSON []byte
+
+var (
+	once    sync.Once
+	catalog []types.Profile
+)
+
+func load() []types.Profile {
+	once.Do(func() {
+		if err := json.Unmarshal(catalogJSON, &catalog); err != nil {
+			panic("bad printer profile catalog: " + err.Error())
+		}
+	})
+	return catalog
+}
+
+/*
+Catalog returns a copy of every profile in the catalog
+*/
+func Catalog() []types.Profile {
+	profiles := make([]types.Profile, 0, len(load()))
+	for _, p := range load() {
+		profiles = append(profiles, clone(p))
+	}
+	return profiles
+}
+
+/*
+Get returns a copy of the catalog profile with id
+*/
+func Get(id string) (types.Profile, bool) {
+	for _, p := range load() {
+		if p.Id == id {
+			return clone(p), true
+		}
+	}
+	return types.Profile{}, false
+}
+
+/*
+Match finds the catalog profile for a printer's make and model. Names are compared ignoring case, spaces and
+punctuation so "Original Prusa i3 MK3S" and "MK3S" both find the MK3S+.
+*/
+func Match(t types.PrinterType) (types.Profile, bool) {
+	model := normalize(t.Model)
+	if model == "" {
+		return types.Profile{}, false
+	}
+	makeModel := normalize(t.Make + t.Model)
+	for _, p := range load() {
+		for _, name := range append([]string{p.Model, p.Make + p.Model}, p.Aliases...) {
+			n := normalize(name)
+			if n == model || n == makeModel || normalize(p.Make+name) == makeModel {
+				return clone(p), true
+			}
+		}
+	}
+	return types.Profile{}, false
+}
+
+/*
+MatchSlicerModel finds the catalog profile whose slicers write name as the printer model into G-code
+*/
+func MatchSlicerModel(name string) (types.Profile, bool) {
+	n := normalize(name)
+	if n == "" {
+		return types.Profile{}, false
+	}
+	for _, p := range load() {
+		if SlicedFor(p, n) {
+			return clone(p), true
+		}
+	}
+	return types.Profile{}, false
+}
+
+/*
+SlicedFor is true when name is one of the printer models slicers write into G-code for profile
+*/
+func SlicedFor(profile types.Profile, name string) bool {
+	n := normalize(name)
+	for _, s := range profile.SlicerModels {
+		if normalize(s) == n {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '+':
+			return -1
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.':
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func clone(p types.Profile) types.Profile {
+	p.Nozzles = append([]float64(nil), p.Nozzles...)
+	p.Materials = append([]string(nil), p.Materials...)
+	p.SlicerModels = append([]string(nil), p.SlicerModels...)
+	p.Aliases = append([]string(nil), p.Aliases...)
+	return p
+}
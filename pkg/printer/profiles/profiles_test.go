@@ -0,0 +1,58 @@
+package profiles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ymir/pkg/api/printer/types"
+)
+
+func TestCatalog(t *testing.T) {
+	ids := map[string]bool{}
+	for _, p := range Catalog() {
+		assert.False(t, ids[p.Id], "duplicate profile %v", p.Id)
+		ids[p.Id] = true
+		assert.NotEmpty(t, p.Make, p.Id)
+		assert.NotEmpty(t, p.Model, p.Id)
+		assert.Positive(t, p.BuildVolume.X*p.BuildVolume.Y*p.BuildVolume.Z, p.Id)
+		assert.NotEmpty(t, p.Nozzles, p.Id)
+		assert.NotEmpty(t, p.Materials, p.Id)
+		assert.Positive(t, p.MaxHotendTemp, p.Id)
+		assert.Contains(t, []string{types.GCODE_FLAVOR_MARLIN, types.GCODE_FLAVOR_MARLIN2, types.GCODE_FLAVOR_KLIPPER,
+			types.GCODE_FLAVOR_REPRAP}, p.GCodeFlavor, p.Id)
+	}
+
+	p, _ := Get("prusa-mk4")
+	p.Nozzles[0] = 1
+	p, _ = Get("prusa-mk4")
+	assert.Equal(t, 0.25, p.Nozzles[0], "profiles are copies")
+}
+
+func TestMatch(t *testing.T) {
+	for _, tt := range []struct {
+		make, model string
+		id          string
+	}{
+		{"Prusa", "MK3S+", "prusa-mk3s"},
+		{"", "Original Prusa i3 MK3S", "prusa-mk3s"},
+		{"Prusa", "MK4", "prusa-mk4"},
+		{"Prusa", "mk4s", "prusa-mk4s"},
+		{"Creality", "Ender 3", "creality-ender3"},
+		{"Creality", "Ender-3 V2", "creality-ender3-v2"},
+		{"", "Ender-3 V2", "creality-ender3-v2"},
+		{"Voron", "2.4", "voron-2.4-350"},
+		{"Voron", "2.4 300", "voron-2.4-300"},
+		{"Prusa", "", ""},
+		{"Acme", "Printomatic", ""},
+	} {
+		p, ok := Match(types.PrinterType{Make: tt.make, Model: tt.model})
+		assert.Equal(t, tt.id != "", ok, tt.model)
+		assert.Equal(t, tt.id, p.Id, tt.model)
+	}
+
+	p, ok := MatchSlicerModel("MK4IS")
+	assert.True(t, ok)
+	assert.Equal(t, "prusa-mk4", p.Id)
+	_, ok = MatchSlicerModel("")
+	assert.False(t, ok)
+}
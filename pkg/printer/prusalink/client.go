@@ -0,0 +1,231 @@
+package prusalink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_API_KEY_HEADER  = "X-Api-Key"
+	_DEFAULT_TIMEOUT = 10 * time.Second
+
+	// DEFAULT_USERNAME is the user PrusaLink creates on the printer
+	DEFAULT_USERNAME = "maker"
+	STORAGE_USB      = "usb"
+)
+
+/*
+Client is a small client for the PrusaLink v1 API on Prusa MK4, XL and MINI printers.
+Requests are authenticated with HTTP digest auth, or with an X-Api-Key on older firmware.
+https://github.com/prusa3d/Prusa-Link-Web/blob/master/spec/openapi.yaml
+*/
+type Client struct {
+	Endpoint string
+	APIKey   string
+	Timeout  time.Duration
+	c        *http.Client
+}
+
+/*
+APIError is returned when PrusaLink answers with a status code we did not expect
+*/
+type APIError struct {
+	StatusCode int    `json:"statusCode"`
+	Status     string `json:"status"`
+	Body       string `json:"body,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("prusalink: %v: %v", e.Status, strings.TrimSpace(e.Body))
+	}
+	return fmt.Sprintf("prusalink: %v", e.Status)
+}
+
+/*
+HTTPStatus satisfies printer.StatusError
+*/
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+/*
+NewClient returns a client using digest auth with username and password.
+If no password is given the apiKey is sent as X-Api-Key instead.
+*/
+func NewClient(endpoint string, username string, password string, apiKey string) *Client {
+	var transport http.RoundTripper = &http.Transport{
+		DisableKeepAlives: true,
+	}
+	if password != "" {
+		if username == "" {
+			username = DEFAULT_USERNAME
+		}
+		transport = &digestTransport{
+			Username:  username,
+			Password:  password,
+			Transport: transport,
+		}
+	}
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		APIKey:   apiKey,
+		Timeout:  _DEFAULT_TIMEOUT,
+		c: &http.Client{
+			Transport: transport,
+		},
+	}
+}
+
+func (c *Client) call(method string, uri string, out interface{}, expected ...int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+uri, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(req, out, expected...)
+	return err
+}
+
+/*
+do sends the request and decodes the response into out (if not nil). The status code is returned
+since PrusaLink uses 204 to say there is no job.
+*/
+func (c *Client) do(req *http.Request, out interface{}, expected ...int) (int, error) {
+	if c.APIKey != "" {
+		req.Header.Set(_API_KEY_HEADER, c.APIKey)
+	}
+	req.Header.Set("Accept", "application/json")
+	log.Debugf("prusalink request: %v %v", req.Method, req.URL)
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if !isExpected(resp.StatusCode, expected) {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return resp.StatusCode, &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+		}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
+
+func isExpected(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code == http.StatusOK
+	}
+	for _, e := range expected {
+		if code == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) Version() (version Version, err error) {
+	err = c.call(http.MethodGet, "/api/version", &version)
+	return
+}
+
+func (c *Client) Status() (status StatusResponse, err error) {
+	err = c.call(http.MethodGet, "/api/v1/status", &status)
+	return
+}
+
+/*
+Job returns the current job. ok is false when the printer has no job.
+*/
+func (c *Client) Job() (job JobResponse, ok bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint+"/api/v1/job", nil)
+	if err != nil {
+		return
+	}
+	code, err := c.do(req, &job, http.StatusOK, http.StatusNoContent)
+	return job, code == http.StatusOK, err
+}
+
+func (c *Client) PauseJob(id int) error {
+	return c.call(http.MethodPut, fmt.Sprintf("/api/v1/job/%d/pause", id), nil, http.StatusNoContent)
+}
+
+func (c *Client) ResumeJob(id int) error {
+	return c.call(http.MethodPut, fmt.Sprintf("/api/v1/job/%d/resume", id), nil, http.StatusNoContent)
+}
+
+func (c *Client) StopJob(id int) error {
+	return c.call(http.MethodDelete, fmt.Sprintf("/api/v1/job/%d", id), nil, http.StatusNoContent)
+}
+
+/*
+UploadFile PUTs a file to storage/path. The whole file is buffered so the request
+can be replayed when the digest challenge comes back. Uploads are not bound by the client timeout.
+*/
+func (c *Client) UploadFile(storage string, path string, r io.Reader, printAfterUpload bool) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, c.Endpoint+filesURI(storage, path), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Overwrite", "?1")
+	if printAfterUpload {
+		req.Header.Set("Print-After-Upload", "?1")
+	} else {
+		req.Header.Set("Print-After-Upload", "?0")
+	}
+	_, err = c.do(req, nil, http.StatusCreated, http.StatusNoContent)
+	return err
+}
+
+/*
+StartPrint starts printing a file that is already on the printer's storage
+*/
+func (c *Client) StartPrint(storage string, path string) error {
+	return c.call(http.MethodPost, filesURI(storage, path), nil, http.StatusNoContent)
+}
+
+/*
+ListFiles lists a folder on the printer's storage
+*/
+func (c *Client) ListFiles(storage string, path string) (folder FileInfo, err error) {
+	err = c.call(http.MethodGet, filesURI(storage, path), &folder)
+	return
+}
+
+func (c *Client) DeleteFile(storage string, path string) error {
+	return c.call(http.MethodDelete, filesURI(storage, path), nil, http.StatusNoContent)
+}
+
+func filesURI(storage string, path string) string {
+	parts := []string{url.PathEscape(storage)}
+	for _, p := range strings.Split(strings.Trim(path, "/"), "/") {
+		if p != "" {
+			parts = append(parts, url.PathEscape(p))
+		}
+	}
+	return "/api/v1/files/" + strings.Join(parts, "/")
+}
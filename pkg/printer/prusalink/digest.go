@@ -0,0 +1,133 @@
+package prusalink
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+/*
+digestTransport answers HTTP digest challenges (RFC 7616, MD5 only) as PrusaLink requires.
+The last challenge is remembered so follow up requests only take one round trip.
+*/
+type digestTransport struct {
+	Username  string
+	Password  string
+	Transport http.RoundTripper
+
+	lock      sync.Mutex
+	challenge map[string]string
+	nc        int
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	if t.challenge != nil {
+		req.Header.Set("Authorization", t.authorize(req))
+	}
+	t.lock.Unlock()
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	header := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(header), "digest ") {
+		return resp, nil
+	}
+
+	// Retry once with the new challenge. The body has to be rewound for that.
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	t.lock.Lock()
+	t.challenge = parseChallenge(header[len("digest "):])
+	t.nc = 0
+	retry.Header.Set("Authorization", t.authorize(retry))
+	t.lock.Unlock()
+	return t.Transport.RoundTrip(retry)
+}
+
+/*
+authorize builds the Authorization header for req. Must be called with the lock held.
+*/
+func (t *digestTransport) authorize(req *http.Request) string {
+	t.nc++
+	realm, nonce := t.challenge["realm"], t.challenge["nonce"]
+	uri := req.URL.RequestURI()
+	ha1 := md5Hex(fmt.Sprintf("%s:%s:%s", t.Username, realm, t.Password))
+	ha2 := md5Hex(fmt.Sprintf("%s:%s", req.Method, uri))
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, t.Username, realm, nonce, uri)
+	if qop := t.challenge["qop"]; qop != "" {
+		nc := fmt.Sprintf("%08x", t.nc)
+		cnonce := newCNonce()
+		response := md5Hex(fmt.Sprintf("%s:%s:%s:%s:auth:%s", ha1, nonce, nc, cnonce, ha2))
+		header += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, response)
+	} else {
+		header += fmt.Sprintf(`, response="%s"`, md5Hex(fmt.Sprintf("%s:%s:%s", ha1, nonce, ha2)))
+	}
+	if opaque := t.challenge["opaque"]; opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	if algorithm := t.challenge["algorithm"]; algorithm != "" {
+		header += fmt.Sprintf(`, algorithm=%s`, algorithm)
+	}
+	return header
+}
+
+/*
+parseChallenge splits `realm="x", nonce="y", qop="auth"` into a map
+*/
+func parseChallenge(s string) map[string]string {
+	challenge := map[string]string{}
+	for _, part := range splitParams(s) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		challenge[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return challenge
+}
+
+/*
+splitParams splits on commas that are not inside quotes
+*/
+func splitParams(s string) (parts []string) {
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func md5Hex(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+func newCNonce() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%x", buf)
+}
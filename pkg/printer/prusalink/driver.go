@@ -0,0 +1,226 @@
+package prusalink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+var (
+	ErrUnsupportedFile = errors.New("prusalink only accepts .gcode and .bgcode files")
+	ErrNoJob           = errors.New("prusalink: printer has no job")
+)
+
+func init() {
+	printer.Register(types.API_TYPE_PRUSALINK, NewDriver)
+}
+
+/*
+Driver adapts the PrusaLink Client to the printer.PrinterDriver interface
+*/
+type Driver struct {
+	client *Client
+}
+
+func NewDriver(p types.Printer) (printer.PrinterDriver, error) {
+	apiKey := ""
+	if p.Password == "" {
+		apiKey = p.APIKey
+	}
+	return &Driver{
+		client: NewClient(p.URL, p.Username, p.Password, apiKey),
+	}, nil
+}
+
+/*
+Connect checks that PrusaLink answers and accepts our credentials. PrusaLink is always connected to its printer.
+*/
+func (d *Driver) Connect() error {
+	_, err := d.client.Version()
+	return err
+}
+
+/*
+Info reports the PrusaLink and firmware versions. PrusaLink only runs on Prusa printers, some of which report their model.
+*/
+func (d *Driver) Info() (info printer.Info, err error) {
+	version, err := d.client.Version()
+	if err != nil {
+		return
+	}
+	info = printer.Info{
+		Server:          "PrusaLink",
+		ServerVersion:   version.Server,
+		Firmware:        "Prusa-Firmware",
+		FirmwareVersion: version.Firmware,
+		Make:            "Prusa",
+	}
+	if model, ok := strings.CutPrefix(version.Original, "PrusaLink "); ok {
+		info.Model = model
+	}
+	return info, nil
+}
+
+func (d *Driver) Status() (status printer.Status, err error) {
+	resp, err := d.client.Status()
+	if err != nil {
+		return
+	}
+
+	status = printer.Status{
+		State: stateFromPrusaLink(resp.Printer.State),
+		Text:  resp.Printer.State,
+		Temperatures: map[string]printer.Temperature{
+			"tool0": {Actual: resp.Printer.TempNozzle, Target: resp.Printer.TargetNozzle},
+			"bed":   {Actual: resp.Printer.TempBed, Target: resp.Printer.TargetBed},
+		},
+	}
+
+	if resp.Job != nil && (status.State == printer.STATE_PRINTING || status.State == printer.STATE_PAUSED) {
+		job, ok, err := d.client.Job()
+		if err != nil {
+			return status, err
+		}
+		status.Job = &printer.Job{
+			Completion:         resp.Job.Progress,
+			PrintTime:          resp.Job.TimePrinting,
+			PrintTimeLeft:      resp.Job.TimeRemaining,
+			EstimatedPrintTime: resp.Job.TimePrinting + resp.Job.TimeRemaining,
+		}
+		if ok {
+			status.Job.File = job.File.Path
+		}
+	}
+	return status, nil
+}
+
+func stateFromPrusaLink(state string) printer.State {
+	switch strings.ToUpper(state) {
+	case STATE_IDLE, STATE_READY, STATE_FINISHED, STATE_STOPPED:
+		return printer.STATE_IDLE
+	case STATE_BUSY:
+		return printer.STATE_BUSY
+	case STATE_PRINTING:
+		return printer.STATE_PRINTING
+	case STATE_PAUSED:
+		return printer.STATE_PAUSED
+	case STATE_ERROR, STATE_ATTENTION:
+		return printer.STATE_ERROR
+	default:
+		return printer.STATE_OFFLINE
+	}
+}
+
+func (d *Driver) Upload(filename string, r io.Reader, print bool) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gcode", ".bgcode":
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedFile, filename)
+	}
+	return d.client.UploadFile(STORAGE_USB, filename, r, print)
+}
+
+func (d *Driver) Start(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.StartPrint(STORAGE_USB, strings.TrimPrefix(path, "/"+STORAGE_USB))
+}
+
+/*
+storage checks the location is the USB drive, where Upload puts files
+*/
+func storage(location string) error {
+	if location != "" && location != STORAGE_USB {
+		return fmt.Errorf("%w: %v", printer.ErrUnknownStorage, location)
+	}
+	return nil
+}
+
+/*
+jobId returns the id of the current job since PrusaLink addresses job commands by id
+*/
+func (d *Driver) jobId() (int, error) {
+	job, ok, err := d.client.Job()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrNoJob
+	}
+	return job.Id, nil
+}
+
+func (d *Driver) Pause() error {
+	id, err := d.jobId()
+	if err != nil {
+		return err
+	}
+	return d.client.PauseJob(id)
+}
+
+func (d *Driver) Resume() error {
+	id, err := d.jobId()
+	if err != nil {
+		return err
+	}
+	return d.client.ResumeJob(id)
+}
+
+func (d *Driver) Cancel() error {
+	id, err := d.jobId()
+	if err != nil {
+		return err
+	}
+	return d.client.StopJob(id)
+}
+
+func (d *Driver) ListFiles() ([]printer.File, error) {
+	folder, err := d.client.ListFiles(STORAGE_USB, "")
+	if err != nil {
+		return nil, err
+	}
+	return flattenFiles(folder.Children, "", []printer.File{}), nil
+}
+
+func (d *Driver) DeleteFile(location string, path string) error {
+	if err := storage(location); err != nil {
+		return err
+	}
+	return d.client.DeleteFile(STORAGE_USB, strings.TrimPrefix(path, "/"+STORAGE_USB))
+}
+
+func flattenFiles(files []FileInfo, dir string, list []printer.File) []printer.File {
+	for _, f := range files {
+		path := strings.TrimLeft(dir+"/"+f.Name, "/")
+		if f.Type == "FOLDER" {
+			list = flattenFiles(f.Children, path, list)
+			continue
+		}
+		name := f.DisplayName
+		if name == "" {
+			name = f.Name
+		}
+		list = append(list, printer.File{
+			Name:     name,
+			Path:     path,
+			Location: STORAGE_USB,
+			Size:     f.Size,
+			Date:     time.Unix(f.Timestamp, 0),
+		})
+	}
+	return list
+}
+
+/*
+SendGCode is not supported. PrusaLink has no endpoint for raw G-code.
+*/
+func (d *Driver) SendGCode(commands ...string) error {
+	return printer.ErrNotSupported
+}
@@ -0,0 +1,196 @@
+package prusalink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"ymir/pkg/api/printer/types"
+	"ymir/pkg/printer"
+)
+
+const (
+	TEST_PASSWORD = "secret"
+	TEST_REALM    = "Printer API"
+	TEST_NONCE    = "dcd98b7102dd2f0e"
+)
+
+/*
+prusaLinkStandIn emulates PrusaLink including its digest auth
+*/
+type prusaLinkStandIn struct {
+	state      string
+	uploads    map[string]string
+	printAfter string
+	started    string
+	lastJobCmd string
+	challenges int
+}
+
+func (p *prusaLinkStandIn) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Digest ") {
+		return false
+	}
+	c := parseChallenge(header[len("Digest "):])
+	ha1 := md5Hex(fmt.Sprintf("%s:%s:%s", DEFAULT_USERNAME, TEST_REALM, TEST_PASSWORD))
+	ha2 := md5Hex(fmt.Sprintf("%s:%s", r.Method, c["uri"]))
+	want := md5Hex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, c["nonce"], c["nc"], c["cnonce"], c["qop"], ha2))
+	return c["username"] == DEFAULT_USERNAME && c["response"] == want
+}
+
+func (p *prusaLinkStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(r) {
+		p.challenges++
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", nonce="%s", qop="auth"`, TEST_REALM, TEST_NONCE))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/api/version":
+		json.NewEncoder(w).Encode(Version{API: "2.0.0", Server: "2.1.2", Firmware: "5.1.0", Original: "PrusaLink MK4"})
+	case r.URL.Path == "/api/v1/status":
+		resp := StatusResponse{Printer: StatusPrinter{State: p.state, TempNozzle: 215, TargetNozzle: 215, TempBed: 60, TargetBed: 60}}
+		if p.state == STATE_PRINTING {
+			resp.Job = &StatusJob{Id: 7, Progress: 30, TimeRemaining: 700, TimePrinting: 300}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case r.URL.Path == "/api/v1/job":
+		if p.state != STATE_PRINTING {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(JobResponse{Id: 7, State: p.state, File: FileInfo{Path: "/usb/TEST~1.BGC", DisplayName: "test.bgcode"}})
+	case strings.HasPrefix(r.URL.Path, "/api/v1/job/7"):
+		p.lastJobCmd = r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/api/v1/files/usb" || r.URL.Path == "/api/v1/files/usb/":
+		json.NewEncoder(w).Encode(FileInfo{Type: "FOLDER", Children: []FileInfo{
+			{Name: "TEST~1.BGC", DisplayName: "test.bgcode", Type: "PRINT_FILE", Size: 2048},
+			{Name: "YMIR", Type: "FOLDER", Children: []FileInfo{{Name: "A.GCO", DisplayName: "a.gcode", Type: "PRINT_FILE"}}},
+		}})
+	case strings.HasPrefix(r.URL.Path, "/api/v1/files/usb/") && r.Method == http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		p.uploads[strings.TrimPrefix(r.URL.Path, "/api/v1/files/usb/")] = string(b)
+		p.printAfter = r.Header.Get("Print-After-Upload")
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/files/usb/") && r.Method == http.MethodPost:
+		p.started = strings.TrimPrefix(r.URL.Path, "/api/v1/files/usb/")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type PrusaLinkDriverTestSuite struct {
+	suite.Suite
+	standIn *prusaLinkStandIn
+	server  *httptest.Server
+	driver  printer.PrinterDriver
+}
+
+func (suite *PrusaLinkDriverTestSuite) SetupTest() {
+	suite.standIn = &prusaLinkStandIn{state: STATE_IDLE, uploads: map[string]string{}}
+	suite.server = httptest.NewServer(suite.standIn)
+	d, err := printer.NewDriver(types.Printer{
+		URL:      suite.server.URL,
+		APIType:  types.API_TYPE_PRUSALINK,
+		Password: TEST_PASSWORD,
+	})
+	assert.NoError(suite.T(), err)
+	suite.driver = d
+}
+
+func (suite *PrusaLinkDriverTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestConnect_DigestAuth() {
+	assert.NoError(suite.T(), suite.driver.Connect())
+	assert.NoError(suite.T(), suite.driver.Connect())
+	assert.Equal(suite.T(), 1, suite.standIn.challenges, "challenge should be reused for the second request")
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestConnect_BadPassword() {
+	d, _ := NewDriver(types.Printer{URL: suite.server.URL, Password: "wrong"})
+	err := d.Connect()
+	var statusErr printer.StatusError
+	assert.ErrorAs(suite.T(), err, &statusErr)
+	assert.Equal(suite.T(), http.StatusUnauthorized, statusErr.HTTPStatus())
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestInfo() {
+	info, err := suite.driver.Info()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.Info{Server: "PrusaLink", ServerVersion: "2.1.2", Firmware: "Prusa-Firmware", FirmwareVersion: "5.1.0", Make: "Prusa", Model: "MK4"}, info)
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestStatus() {
+	status, err := suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_IDLE, status.State)
+	assert.Equal(suite.T(), 215.0, status.Temperatures["tool0"].Actual)
+	assert.Nil(suite.T(), status.Job)
+
+	suite.standIn.state = STATE_PRINTING
+	status, err = suite.driver.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), printer.STATE_PRINTING, status.State)
+	assert.Equal(suite.T(), 30.0, status.Job.Completion)
+	assert.Equal(suite.T(), 1000.0, status.Job.EstimatedPrintTime)
+	assert.Equal(suite.T(), "/usb/TEST~1.BGC", status.Job.File)
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestUpload() {
+	err := suite.driver.Upload("test.bgcode", strings.NewReader("GCDE"), true)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "GCDE", suite.standIn.uploads["test.bgcode"], "body should be replayed after the challenge")
+	assert.Equal(suite.T(), "?1", suite.standIn.printAfter)
+
+	err = suite.driver.Upload("test.3mf", strings.NewReader(""), false)
+	assert.ErrorIs(suite.T(), err, ErrUnsupportedFile)
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestJobControl() {
+	assert.ErrorIs(suite.T(), suite.driver.Pause(), ErrNoJob)
+
+	suite.standIn.state = STATE_PRINTING
+	assert.NoError(suite.T(), suite.driver.Pause())
+	assert.Equal(suite.T(), "PUT /api/v1/job/7/pause", suite.standIn.lastJobCmd)
+	assert.NoError(suite.T(), suite.driver.Resume())
+	assert.Equal(suite.T(), "PUT /api/v1/job/7/resume", suite.standIn.lastJobCmd)
+	assert.NoError(suite.T(), suite.driver.Cancel())
+	assert.Equal(suite.T(), "DELETE /api/v1/job/7", suite.standIn.lastJobCmd)
+
+	assert.NoError(suite.T(), suite.driver.Start("", "/usb/TEST~1.BGC"))
+	assert.Equal(suite.T(), "TEST~1.BGC", suite.standIn.started)
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestListFiles() {
+	files, err := suite.driver.ListFiles()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), files, 2)
+	assert.Equal(suite.T(), "YMIR/A.GCO", files[1].Path)
+	assert.Equal(suite.T(), "a.gcode", files[1].Name)
+}
+
+func (suite *PrusaLinkDriverTestSuite) TestSendGCode() {
+	assert.ErrorIs(suite.T(), suite.driver.SendGCode("G28"), printer.ErrNotSupported)
+}
+
+func TestParseChallenge(t *testing.T) {
+	c := parseChallenge(`realm="Printer API", nonce="abc", qop="auth,auth-int", opaque="x,y"`)
+	assert.Equal(t, "Printer API", c["realm"])
+	assert.Equal(t, "auth,auth-int", c["qop"])
+	assert.Equal(t, "x,y", c["opaque"])
+}
+
+func TestPrusaLinkDriverTestSuite(t *testing.T) {
+	suite.Run(t, new(PrusaLinkDriverTestSuite))
+}
@@ -0,0 +1,77 @@
+package prusalink
+
+/*
+Data model for the parts of the PrusaLink v1 API that Ymir uses
+*/
+
+const (
+	STATE_IDLE      = "IDLE"
+	STATE_BUSY      = "BUSY"
+	STATE_PRINTING  = "PRINTING"
+	STATE_PAUSED    = "PAUSED"
+	STATE_FINISHED  = "FINISHED"
+	STATE_STOPPED   = "STOPPED"
+	STATE_ERROR     = "ERROR"
+	STATE_ATTENTION = "ATTENTION"
+	STATE_READY     = "READY"
+)
+
+// Version is returned by GET /api/version
+type Version struct {
+	API      string `json:"api"`
+	Server   string `json:"server"`
+	Text     string `json:"text"`
+	Firmware string `json:"firmware"`
+	Hostname string `json:"hostname"`
+	// Original is e.g. "PrusaLink I3MK3S" on printers that report their model
+	Original string `json:"original,omitempty"`
+}
+
+// StatusResponse is returned by GET /api/v1/status
+type StatusResponse struct {
+	Job     *StatusJob    `json:"job,omitempty"`
+	Storage *Storage      `json:"storage,omitempty"`
+	Printer StatusPrinter `json:"printer"`
+}
+
+type StatusJob struct {
+	Id            int     `json:"id"`
+	Progress      float64 `json:"progress"`
+	TimeRemaining float64 `json:"time_remaining"`
+	TimePrinting  float64 `json:"time_printing"`
+}
+
+type Storage struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type StatusPrinter struct {
+	State        string  `json:"state"`
+	TempNozzle   float64 `json:"temp_nozzle"`
+	TargetNozzle float64 `json:"target_nozzle"`
+	TempBed      float64 `json:"temp_bed"`
+	TargetBed    float64 `json:"target_bed"`
+}
+
+// JobResponse is returned by GET /api/v1/job
+type JobResponse struct {
+	Id            int      `json:"id"`
+	State         string   `json:"state"`
+	Progress      float64  `json:"progress"`
+	TimeRemaining float64  `json:"time_remaining"`
+	TimePrinting  float64  `json:"time_printing"`
+	File          FileInfo `json:"file"`
+}
+
+// FileInfo is a file or folder on the printer's storage
+type FileInfo struct {
+	Name        string     `json:"name"`
+	DisplayName string     `json:"display_name"`
+	Path        string     `json:"path"`
+	Type        string     `json:"type"`
+	Size        int64      `json:"size"`
+	Timestamp   int64      `json:"m_timestamp"`
+	Children    []FileInfo `json:"children,omitempty"`
+}
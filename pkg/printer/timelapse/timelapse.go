@@ -0,0 +1,326 @@
+/*
+Package timelapse captures webcam snapshots of prints started through Ymir and files them with the printed model.
+*/
+package timelapse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	jobtypes "ymir/pkg/api/job/types"
+	modeltypes "ymir/pkg/api/model/types"
+	"ymir/pkg/api/printer/types"
+	bus "ymir/pkg/events"
+	"ymir/pkg/printer"
+	"ymir/pkg/printer/webcam"
+	"ymir/pkg/utils"
+)
+
+const (
+	// TIMELAPSE_DIR is where frames are saved under the model's directory, one folder per job
+	TIMELAPSE_DIR = "timelapse"
+)
+
+/*
+PrinterInspector is the part of the PrinterStore the timelapse needs
+*/
+type PrinterInspector interface {
+	Inspect(id string) (types.Printer, error)
+}
+
+/*
+JobLister is the part of the JobStore the timelapse needs
+*/
+type JobLister interface {
+	ListByPrinter(printerId string) (map[string]jobtypes.Job, error)
+}
+
+/*
+ModelStore is the part of the ModelStore the timelapse needs
+*/
+type ModelStore interface {
+	Inspect(id string) (modeltypes.Model, error)
+	Update(model modeltypes.Model) error
+}
+
+/*
+Snapshotter fetches a frame from a webcam
+*/
+type Snapshotter func(url string) (image []byte, contentType string, err error)
+
+/*
+Timelapse starts capturing when a printer with a snapshot url starts printing a job recorded for one of our models.
+Frames are taken every interval, skipped while paused, and the last one is added to the model's images when the job ends.
+*/
+type Timelapse struct {
+	printers PrinterInspector
+	jobs     JobLister
+	models   ModelStore
+	interval time.Duration
+	snapshot Snapshotter
+
+	lock        sync.Mutex
+	sessions    map[string]*session
+	unsubscribe func()
+	done        chan struct{}
+	// finishing counts the sessions being wrapped up in the background
+	finishing sync.WaitGroup
+}
+
+type session struct {
+	job       jobtypes.Job
+	url       string
+	dir       string
+	lock      sync.Mutex
+	paused    bool
+	frames    int
+	lastFrame string
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+func NewTimelapse(printers PrinterInspector, jobs JobLister, models ModelStore, interval time.Duration) *Timelapse {
+	return &Timelapse{
+		printers: printers,
+		jobs:     jobs,
+		models:   models,
+		interval: interval,
+		snapshot: webcam.Snapshot,
+		sessions: map[string]*session{},
+	}
+}
+
+/*
+Start subscribes to printer events until Stop is called
+*/
+func (t *Timelapse) Start() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.unsubscribe != nil || t.interval <= 0 {
+		return
+	}
+	events, unsubscribe := bus.Subscribe(bus.DEFAULT_BUFFER)
+	t.unsubscribe = unsubscribe
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		for e := range events {
+			t.handle(e)
+		}
+	}()
+}
+
+/*
+Stop ends event handling and any running captures, keeping the frames taken so far, and waits for the
+captures that were already finishing
+*/
+func (t *Timelapse) Stop() {
+	t.lock.Lock()
+	if t.unsubscribe == nil {
+		t.lock.Unlock()
+		return
+	}
+	t.unsubscribe()
+	t.unsubscribe = nil
+	done := t.done
+	t.lock.Unlock()
+	<-done
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for id, s := range t.sessions {
+		s.end()
+		delete(t.sessions, id)
+	}
+	t.finishing.Wait()
+}
+
+func (t *Timelapse) handle(e bus.Event) {
+	if e.Type != bus.PRINTER_STATE {
+		return
+	}
+	change, ok := e.Data.(bus.StateChange)
+	if !ok {
+		return
+	}
+	switch printer.State(change.State) {
+	case printer.STATE_PRINTING:
+		t.begin(e.PrinterId)
+	case printer.STATE_PAUSED:
+		t.pause(e.PrinterId, true)
+	case printer.STATE_IDLE, printer.STATE_ERROR:
+		t.finish(e.PrinterId)
+	}
+	// busy comes and goes during a print, e.g. while heating, and offline may only be the network
+}
+
+/*
+begin starts capturing for the printer's active job, or resumes a paused capture
+*/
+func (t *Timelapse) begin(printerId string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if s, ok := t.sessions[printerId]; ok {
+		s.setPaused(false)
+		return
+	}
+	p, err := t.printers.Inspect(printerId)
+	if err != nil || p.SnapshotURL == "" {
+		return
+	}
+	job, ok := t.activeJob(printerId)
+	if !ok || job.ModelId == "" {
+		return
+	}
+	model, err := t.models.Inspect(job.ModelId)
+	if err != nil || model.BasePath == "" {
+		log.Errorf("no directory for the timelapse of job %v: %v", job.Id, err)
+		return
+	}
+	s := &session{
+		job:     job,
+		url:     p.SnapshotURL,
+		dir:     filepath.Join(model.BasePath, TIMELAPSE_DIR, job.Id),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := utils.MakeDirIfNotExists(s.dir); err != nil {
+		return
+	}
+	t.sessions[printerId] = s
+	log.Infof("capturing a timelapse of %v on %v every %v", filepath.Base(job.File), p.PrinterName, t.interval)
+	go t.capture(s)
+}
+
+func (t *Timelapse) activeJob(printerId string) (jobtypes.Job, bool) {
+	jobs, err := t.jobs.ListByPrinter(printerId)
+	if err != nil {
+		log.Errorf("could not list jobs for printer %v: %v", printerId, err)
+		return jobtypes.Job{}, false
+	}
+	var active jobtypes.Job
+	for _, j := range jobs {
+		if j.Active() && j.StartTime.After(active.StartTime) {
+			active = j
+		}
+	}
+	return active, active.Id != ""
+}
+
+func (t *Timelapse) pause(printerId string, paused bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if s, ok := t.sessions[printerId]; ok {
+		s.setPaused(paused)
+	}
+}
+
+/*
+finish stops capturing on the printer. Waiting for a frame in progress and updating the model is left to
+a goroutine, the event loop has other subscribers' events to get on with.
+*/
+func (t *Timelapse) finish(printerId string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	s, ok := t.sessions[printerId]
+	if !ok {
+		return
+	}
+	delete(t.sessions, printerId)
+	t.finishing.Add(1)
+	go func() {
+		defer t.finishing.Done()
+		t.wrapUp(s)
+	}()
+}
+
+/*
+wrapUp ends the capture and adds its last frame to the model's images
+*/
+func (t *Timelapse) wrapUp(s *session) {
+	s.end()
+	if s.lastFrame == "" {
+		return
+	}
+	if err := t.attach(s); err != nil {
+		log.Errorf("could not add the timelapse of job %v to model %v: %v", s.job.Id, s.job.ModelId, err)
+	}
+}
+
+/*
+attach adds the final frame to the model's images, as a path relative to the model's directory like its other files
+*/
+func (t *Timelapse) attach(s *session) error {
+	model, err := t.models.Inspect(s.job.ModelId)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(model.BasePath, s.lastFrame)
+	if err != nil {
+		return err
+	}
+	model.Images = append(model.Images, modeltypes.FileType{Path: rel})
+	log.Infof("added %v frames of job %v to model %v", s.frames, s.job.Id, model.DisplayName)
+	return t.models.Update(model)
+}
+
+func (t *Timelapse) capture(s *session) {
+	defer close(s.stopped)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		if !s.isPaused() {
+			t.frame(s)
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Timelapse) frame(s *session) {
+	image, contentType, err := t.snapshot(s.url)
+	if err != nil {
+		log.Debugf("no timelapse frame for job %v: %v", s.job.Id, err)
+		return
+	}
+	ext := ".jpg"
+	if contentType == "image/png" {
+		ext = ".png"
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	path := filepath.Join(s.dir, fmt.Sprintf("frame_%05d%v", s.frames+1, ext))
+	if err := os.WriteFile(path, image, 0664); err != nil {
+		log.Errorf("could not save timelapse frame: %v", err)
+		return
+	}
+	s.frames++
+	s.lastFrame = path
+}
+
+func (s *session) setPaused(paused bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.paused = paused
+}
+
+func (s *session) isPaused() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paused
+}
+
+/*
+end stops the capture and waits for a frame in progress
+*/
+func (s *session) end() {
+	close(s.stop)
+	<-s.stopped
+}
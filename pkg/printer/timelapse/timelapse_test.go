@@ -0,0 +1,169 @@
+package timelapse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	jobtypes "ymir/pkg/api/job/types"
+	modeltypes "ymir/pkg/api/model/types"
+	"ymir/pkg/api/printer/types"
+	bus "ymir/pkg/events"
+)
+
+type fakePrinters map[string]types.Printer
+
+func (f fakePrinters) Inspect(id string) (types.Printer, error) {
+	return f[id], nil
+}
+
+type fakeJobs map[string]jobtypes.Job
+
+func (f fakeJobs) ListByPrinter(printerId string) (map[string]jobtypes.Job, error) {
+	jobs := map[string]jobtypes.Job{}
+	for id, j := range f {
+		if j.PrinterId == printerId {
+			jobs[id] = j
+		}
+	}
+	return jobs, nil
+}
+
+type memModels struct {
+	lock   sync.Mutex
+	models map[string]modeltypes.Model
+}
+
+func (m *memModels) Inspect(id string) (modeltypes.Model, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	model, ok := m.models[id]
+	if !ok {
+		return model, errors.New("not found")
+	}
+	return model, nil
+}
+
+func (m *memModels) Update(model modeltypes.Model) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.models[model.Id] = model
+	return nil
+}
+
+type TimelapseTestSuite struct {
+	suite.Suite
+	dir       string
+	models    *memModels
+	timelapse *Timelapse
+	lock      sync.Mutex
+	snapshots int
+}
+
+func (suite *TimelapseTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	suite.models = &memModels{models: map[string]modeltypes.Model{
+		"m1": {Id: "m1", DisplayName: "benchy", BasePath: suite.dir, Images: []modeltypes.FileType{{Path: "benchy.png"}}},
+	}}
+	suite.snapshots = 0
+	suite.timelapse = NewTimelapse(
+		fakePrinters{
+			"p1": {Id: "p1", PrinterName: "mk3s", SnapshotURL: "http://octopi.local/webcam/?action=snapshot"},
+			"p2": {Id: "p2", PrinterName: "no webcam"},
+		},
+		fakeJobs{
+			"j1": {Id: "j1", PrinterId: "p1", ModelId: "m1", File: "benchy.gcode", StartTime: time.Now(), State: jobtypes.JOB_STATE_PRINTING},
+			"j2": {Id: "j2", PrinterId: "p2", ModelId: "m1", StartTime: time.Now(), State: jobtypes.JOB_STATE_PRINTING},
+		},
+		suite.models,
+		10*time.Millisecond,
+	)
+	suite.timelapse.snapshot = func(url string) ([]byte, string, error) {
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+		suite.snapshots++
+		return []byte{0xff, 0xd8, 0xff, 0xd9}, "image/jpeg", nil
+	}
+}
+
+func (suite *TimelapseTestSuite) state(printerId string, state string) {
+	suite.timelapse.handle(bus.Event{Type: bus.PRINTER_STATE, PrinterId: printerId, Data: bus.StateChange{State: state}})
+}
+
+func (suite *TimelapseTestSuite) taken() int {
+	suite.lock.Lock()
+	defer suite.lock.Unlock()
+	return suite.snapshots
+}
+
+func (suite *TimelapseTestSuite) TestCapture() {
+	suite.state("p1", "printing")
+	assert.Eventually(suite.T(), func() bool { return suite.taken() >= 3 }, time.Second, 5*time.Millisecond)
+
+	suite.state("p1", "paused")
+	time.Sleep(20 * time.Millisecond)
+	paused := suite.taken()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(suite.T(), paused, suite.taken(), "no frames while paused")
+
+	suite.state("p1", "printing")
+	suite.state("p1", "idle")
+	suite.timelapse.finishing.Wait()
+	frames, _ := filepath.Glob(filepath.Join(suite.dir, TIMELAPSE_DIR, "j1", "frame_*.jpg"))
+	assert.Len(suite.T(), frames, suite.taken())
+
+	model, _ := suite.models.Inspect("m1")
+	assert.Len(suite.T(), model.Images, 2)
+	last := model.Images[1].Path
+	assert.Equal(suite.T(), filepath.Join(TIMELAPSE_DIR, "j1", filepath.Base(frames[len(frames)-1])), last)
+	_, err := os.Stat(filepath.Join(suite.dir, last))
+	assert.NoError(suite.T(), err)
+
+	suite.state("p1", "idle")
+	suite.timelapse.finishing.Wait()
+	model, _ = suite.models.Inspect("m1")
+	assert.Len(suite.T(), model.Images, 2, "the job only ends once")
+}
+
+func (suite *TimelapseTestSuite) TestFinish_DoesNotBlock() {
+	release := make(chan struct{})
+	suite.timelapse.snapshot = func(url string) ([]byte, string, error) {
+		<-release
+		return []byte{0xff, 0xd8, 0xff, 0xd9}, "image/jpeg", nil
+	}
+	suite.state("p1", "printing")
+	time.Sleep(20 * time.Millisecond)
+
+	handled := make(chan struct{})
+	go func() {
+		suite.state("p1", "idle")
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		suite.T().Error("handling idle waited for the frame in progress")
+	}
+	close(release)
+	suite.timelapse.finishing.Wait()
+	model, _ := suite.models.Inspect("m1")
+	assert.Len(suite.T(), model.Images, 2, "the frame in progress is still attached")
+}
+
+func (suite *TimelapseTestSuite) TestNoWebcam() {
+	suite.state("p2", "printing")
+	suite.state("unknown", "printing")
+	time.Sleep(20 * time.Millisecond)
+	suite.state("p2", "idle")
+	assert.Zero(suite.T(), suite.taken())
+	assert.Empty(suite.T(), suite.timelapse.sessions)
+}
+
+func TestTimelapseTestSuite(t *testing.T) {
+	suite.Run(t, new(TimelapseTestSuite))
+}
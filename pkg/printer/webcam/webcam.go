@@ -0,0 +1,100 @@
+/*
+Package webcam fetches still images from printer webcams, e.g. mjpg-streamer's ?action=snapshot or a Klipper
+crowsnest snapshot url.
+*/
+package webcam
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	TIMEOUT = 10 * time.Second
+	// MAX_SNAPSHOT_SIZE guards against a stream url configured as the snapshot url
+	MAX_SNAPSHOT_SIZE = 20 << 20
+	// MAX_REDIRECTS is how many redirects are followed, webcams behind a proxy may need one or two
+	MAX_REDIRECTS = 3
+)
+
+var (
+	ErrInvalidURL = errors.New("webcam url must be http or https")
+
+	client = &http.Client{Timeout: TIMEOUT, CheckRedirect: checkRedirect}
+)
+
+/*
+checkRedirect keeps redirects to http and https and stops after MAX_REDIRECTS
+*/
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MAX_REDIRECTS {
+		return fmt.Errorf("webcam redirected more than %v times", MAX_REDIRECTS)
+	}
+	return checkScheme(req.URL)
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, u.Redacted())
+	}
+	return nil
+}
+
+/*
+StatusError is returned when the webcam answers with anything but 200
+*/
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webcam returned %v", e.Status)
+}
+
+/*
+HTTPStatus passes client errors through and reports server errors as a bad gateway
+*/
+func (e *StatusError) HTTPStatus() int {
+	if e.StatusCode >= 500 {
+		return http.StatusBadGateway
+	}
+	return e.StatusCode
+}
+
+/*
+Snapshot fetches one frame from the http or https url and returns it with its content type
+*/
+func Snapshot(snapshotURL string) (image []byte, contentType string, err error) {
+	u, err := url.Parse(snapshotURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, "", err
+	}
+	res, err := client.Get(u.String())
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, "", &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	image, err = io.ReadAll(io.LimitReader(res.Body, MAX_SNAPSHOT_SIZE+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(image) > MAX_SNAPSHOT_SIZE {
+		return nil, "", fmt.Errorf("webcam snapshot is larger than %v bytes, is %v a stream?", MAX_SNAPSHOT_SIZE, snapshotURL)
+	}
+	contentType = res.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(image)
+	}
+	return image, contentType, nil
+}
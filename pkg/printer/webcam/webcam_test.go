@@ -0,0 +1,66 @@
+package webcam
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("action") {
+		case "snapshot":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte{0xff, 0xd8, 0xff, 0xd9})
+		default:
+			http.Error(w, "no such action", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	image, contentType, err := Snapshot(server.URL + "/?action=snapshot")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+	assert.Len(t, image, 4)
+
+	_, _, err = Snapshot(server.URL + "/?action=stream")
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusBadGateway, statusErr.HTTPStatus())
+}
+
+func TestSnapshot_URLs(t *testing.T) {
+	redirects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			redirects++
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/snapshot", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte{0xff, 0xd8, 0xff, 0xd9})
+		}
+	}))
+	defer server.Close()
+
+	_, _, err := Snapshot(server.URL + "/moved")
+	assert.NoError(t, err, "a redirect is followed")
+
+	_, _, err = Snapshot(server.URL + "/loop")
+	assert.Error(t, err)
+	assert.Equal(t, MAX_REDIRECTS, redirects)
+
+	_, _, err = Snapshot(server.URL + "/file")
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	for _, u := range []string{"file:///etc/passwd", "gopher://localhost:70", "/snapshot", "::"} {
+		_, _, err = Snapshot(u)
+		assert.ErrorIs(t, err, ErrInvalidURL, u)
+	}
+}
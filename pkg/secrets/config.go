@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	db "ymir/pkg/db/boltdatastore"
+)
+
+const (
+	_SECRETS  = "secrets"
+	_KEY_FILE = "secret.key"
+)
+
+type SecretsConfig struct {
+	// Key is a passphrase the encryption key is derived from. When it is empty KeyFile is used instead
+	Key string `toml:"key"`
+	// KeyFile holds the passphrase and is created with a random one if it does not exist.
+	// It defaults to secret.key next to the DB file, so the key stays with the secrets it opens.
+	KeyFile string `toml:"keyFile"`
+}
+
+func NewSecretsConfig() *SecretsConfig {
+	c := &SecretsConfig{}
+
+	h := viper.Sub(_SECRETS)
+	if h != nil {
+		err := h.Unmarshal(c)
+		if err != nil {
+			log.Error(_SECRETS, " config error: ", err.Error())
+		}
+	}
+	if c.KeyFile == "" {
+		c.KeyFile = filepath.Join(filepath.Dir(db.NewBoltDBDataStoreConfig().DBFile), _KEY_FILE)
+		// the key file used to default to the working directory
+		if _, err := os.Stat(c.KeyFile); errors.Is(err, os.ErrNotExist) {
+			if _, err := os.Stat(_KEY_FILE); err == nil {
+				log.Warnf("using the secrets key file %v in the working directory, move it to %v", _KEY_FILE, c.KeyFile)
+				c.KeyFile = _KEY_FILE
+			}
+		}
+	}
+	return c
+}
+
+func (c *SecretsConfig) StringJSON() string {
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func (c *SecretsConfig) StringToml() (config string) {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
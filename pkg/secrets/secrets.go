@@ -0,0 +1,176 @@
+/*
+Package secrets encrypts credentials such as printer API keys before they are written to the datastore.
+Values are sealed with AES-256-GCM under a key derived from the configured passphrase or key file.
+*/
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/scrypt"
+	"ymir/pkg/utils"
+)
+
+const (
+	// ENCRYPTED_PREFIX marks a stored value as encrypted. Anything without it is legacy plain text.
+	ENCRYPTED_PREFIX = "enc:v1:"
+	// MASK replaces all but the last few characters of a secret in API responses
+	MASK          = "********"
+	_SALT         = "ymir secrets"
+	_VISIBLE_TAIL = 4
+)
+
+var (
+	ErrMalformed = errors.New("malformed encrypted secret")
+)
+
+var (
+	lock          = &sync.Mutex{}
+	defaultCipher *Cipher
+)
+
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+/*
+NewCipher derives the encryption key from config.Key, or from the contents of config.KeyFile which is
+generated the first time
+*/
+func NewCipher(config *SecretsConfig) (*Cipher, error) {
+	passphrase := config.Key
+	if passphrase == "" {
+		var err error
+		passphrase, err = readOrCreateKeyFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	key, err := scrypt.Key([]byte(passphrase), []byte(_SALT), 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+/*
+Default returns the Cipher for the server's secrets config, creating it on first use
+*/
+func Default() (*Cipher, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if defaultCipher == nil {
+		c, err := NewCipher(NewSecretsConfig())
+		if err != nil {
+			return nil, err
+		}
+		defaultCipher = c
+	}
+	return defaultCipher, nil
+}
+
+func readOrCreateKeyFile(keyFile string) (string, error) {
+	b, err := os.ReadFile(keyFile)
+	if err == nil {
+		passphrase := strings.TrimSpace(string(b))
+		if passphrase == "" {
+			return "", fmt.Errorf("key file %v is empty", keyFile)
+		}
+		return passphrase, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if dir := filepath.Dir(keyFile); dir != "." {
+		if err := utils.MakeDirIfNotExists(dir); err != nil {
+			return "", err
+		}
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	passphrase := hex.EncodeToString(buf)
+	if err := os.WriteFile(keyFile, []byte(passphrase+"\n"), 0600); err != nil {
+		return "", err
+	}
+	log.Infof("created secrets key file %v", keyFile)
+	return passphrase, nil
+}
+
+/*
+Encrypt seals plain. Empty and already encrypted values are returned unchanged.
+*/
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	if plain == "" || IsEncrypted(plain) {
+		return plain, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return ENCRYPTED_PREFIX + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+/*
+Decrypt opens a value sealed by Encrypt. Values without ENCRYPTED_PREFIX are returned as they are.
+*/
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ENCRYPTED_PREFIX))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt secret, has the key changed?: %w", err)
+	}
+	return string(plain), nil
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ENCRYPTED_PREFIX)
+}
+
+/*
+Mask hides secret for display, keeping the last few characters of long secrets so they can be told apart
+*/
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) < 2*_VISIBLE_TAIL {
+		return MASK
+	}
+	return MASK + secret[len(secret)-_VISIBLE_TAIL:]
+}
+
+/*
+IsMasked is true when value is the output of Mask rather than a real secret
+*/
+func IsMasked(value string) bool {
+	return strings.HasPrefix(value, MASK)
+}
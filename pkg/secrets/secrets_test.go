@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(&SecretsConfig{Key: "correct horse battery staple"})
+	assert.NoError(t, err)
+
+	sealed, err := c.Encrypt("ABC123")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "ABC123")
+
+	again, _ := c.Encrypt(sealed)
+	assert.Equal(t, sealed, again, "encrypted values are not encrypted twice")
+
+	plain, err := c.Decrypt(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC123", plain)
+
+	plain, err = c.Decrypt("legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", plain, "plain text passes through")
+
+	other, _ := NewCipher(&SecretsConfig{Key: "another passphrase"})
+	_, err = other.Decrypt(sealed)
+	assert.Error(t, err)
+	_, err = c.Decrypt(ENCRYPTED_PREFIX + "!!")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestCipher_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys", "secret.key")
+	c, err := NewCipher(&SecretsConfig{KeyFile: keyFile})
+	assert.NoError(t, err)
+	info, err := os.Stat(keyFile)
+	assert.NoError(t, err, "the key file is created")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	sealed, _ := c.Encrypt("ABC123")
+	reloaded, err := NewCipher(&SecretsConfig{KeyFile: keyFile})
+	assert.NoError(t, err)
+	plain, err := reloaded.Decrypt(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC123", plain)
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "", Mask(""))
+	assert.Equal(t, MASK, Mask("short"))
+	assert.Equal(t, MASK+"9ABC", Mask("0123456789ABC"))
+	assert.True(t, IsMasked(Mask("0123456789ABC")))
+	assert.False(t, IsMasked("0123456789ABC"))
+}
+
+func TestNewSecretsConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("datastore.dbFile", "/var/lib/ymir/ymir.db")
+	assert.Equal(t, "/var/lib/ymir/secret.key", NewSecretsConfig().KeyFile, "next to the DB file")
+	viper.Set("secrets.keyFile", "/etc/ymir/secret.key")
+	assert.Equal(t, "/etc/ymir/secret.key", NewSecretsConfig().KeyFile)
+}
@@ -5,33 +5,57 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"time"
 
 	"ymir/front"
 	"ymir/pkg"
 	"ymir/pkg/api"
 	"ymir/pkg/api/admin"
+	"ymir/pkg/api/events"
+	"ymir/pkg/api/job"
+	jobstore "ymir/pkg/api/job/store"
 	"ymir/pkg/api/model"
+	modelstore "ymir/pkg/api/model/store"
 	"ymir/pkg/api/printer"
+	"ymir/pkg/api/printer/store"
+	"ymir/pkg/api/spool"
+	spoolstore "ymir/pkg/api/spool/store"
 	"ymir/pkg/logger/httplogger"
+	"ymir/pkg/notify"
+	"ymir/pkg/printer/dispatcher"
+	_ "ymir/pkg/printer/drivers"
+	"ymir/pkg/printer/metrics"
+	"ymir/pkg/printer/poller"
+	"ymir/pkg/printer/timelapse"
 
 	chiprometheus "github.com/766b/chi-prometheus"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
 	_API_VERSION = "/v1"
+	// _SHUTDOWN_TIMEOUT is how long requests still running get to finish when the server stops
+	_SHUTDOWN_TIMEOUT = 10 * time.Second
 )
 
 type Server struct {
 	Config     *ServerConfig
 	Router     *chi.Mux
 	Handlers   []api.HandlerIFace
+	Events     events.EventsHandler
 	tokenAuth  *jwtauth.JWTAuth
 	HttpLogger *log.Logger
+	Poller     *poller.Poller
+	Recorder   *job.Recorder
+	Dispatcher *dispatcher.Dispatcher
+	Timelapse  *timelapse.Timelapse
+	Notifier   *notify.Notifier
+	Usage      *spool.Usage
 }
 
 func NewServer() (*Server, error) {
@@ -69,12 +93,35 @@ func NewServer() (*Server, error) {
 	s.Handlers = append(s.Handlers, model.NewModelHandler())
 	s.Handlers = append(s.Handlers, printer.NewPrinterHandler())
 	s.Handlers = append(s.Handlers, admin.NewAdminHandler())
+	s.Events = events.NewEventsHandler().(events.EventsHandler)
+	s.Handlers = append(s.Handlers, s.Events)
+	s.Handlers = append(s.Handlers, job.NewJobHandler())
+	s.Handlers = append(s.Handlers, spool.NewSpoolHandler())
 
 	//Append the base and static handlers Last
 	s.Handlers = append(s.Handlers, api.NewBaseHandler(s.HttpLogger, s.Router))
 	s.Router.Mount(_API_VERSION, s.registerRoutes()) // base
 	s.Router.Mount("/", front.HandleSPA())           //Svelte Static Route Handler
 
+	//Record how jobs end and start queued prints, then start polling printers in the background
+	s.Poller = poller.NewPoller(store.NewPrinterDataStore(), printer.NewPrintersConfig().PollEvery())
+	poller.SetDefault(s.Poller)
+	s.Recorder = job.NewRecorder(jobstore.NewJobDataStore())
+	s.Recorder.Start()
+	if ms, ok := model.NewModelService().(model.ModelService); ok {
+		s.Dispatcher = dispatcher.NewDispatcher(store.NewPrinterDataStore(), store.NewQueueDataStore(), s.Poller, ms.UploadFileToPrinter)
+		dispatcher.SetDefault(s.Dispatcher)
+		s.Dispatcher.Start()
+	}
+	s.Timelapse = timelapse.NewTimelapse(store.NewPrinterDataStore(), jobstore.NewJobDataStore(), modelstore.NewModelDataStore(), printer.NewPrintersConfig().TimelapseEvery())
+	s.Timelapse.Start()
+	s.Notifier = notify.NewNotifier(notify.NewNotificationsConfig(), store.NewPrinterDataStore())
+	s.Notifier.Start()
+	s.Usage = spool.NewUsage(spoolstore.NewSpoolDataStore(), store.NewPrinterDataStore())
+	s.Usage.Start()
+	s.Poller.Start()
+	prometheus.MustRegister(metrics.NewCollector(store.NewPrinterDataStore(), jobstore.NewJobDataStore(), s.Poller))
+
 	return s, nil
 }
 
@@ -83,11 +130,23 @@ func (s *Server) ServeAPI(ctx context.Context) {
 		Addr:    fmt.Sprintf("%s:%v", s.Config.Hostname, s.Config.Port),
 		Handler: s.Router,
 	}
+	// event streams only end when the client goes away, Shutdown would wait for them forever
+	srv.RegisterOnShutdown(s.Events.Close)
 
 	done := make(chan struct{})
 	go func() {
 		<-ctx.Done()
-		if err := srv.Shutdown(context.Background()); err != nil {
+		s.Poller.Stop()
+		s.Recorder.Stop()
+		s.Timelapse.Stop()
+		s.Notifier.Stop()
+		s.Usage.Stop()
+		if s.Dispatcher != nil {
+			s.Dispatcher.Stop()
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), _SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
 			log.Error(err)
 		}
 		log.Info("Server Shutting down")